@@ -0,0 +1,42 @@
+package govrrp
+
+import "time"
+
+// OnAdvertDelay 设置 心跳定时器触发间隔超出允许延迟时的回调函数。
+// actual 为相邻两次定时器触发的实际间隔，expected 为配置的心跳间隔。
+// 主机负载过高时定时器可能合并或错过触发，导致心跳延迟并引起对端误判主节点下线，通过该回调及时发现调度问题。
+// 回调函数在状态机协程中同步调用，请勿在其中执行耗时操作。
+func (r *VirtualRouter) OnAdvertDelay(handler func(actual, expected time.Duration)) *VirtualRouter {
+	r.advertDelayHandler = handler
+	return r
+}
+
+// SetAdvertDelayTolerance 设置 心跳定时器触发允许的延迟，默认值为心跳间隔的一半
+func (r *VirtualRouter) SetAdvertDelayTolerance(tolerance time.Duration) *VirtualRouter {
+	r.advertDelayTolerance = tolerance
+	return r
+}
+
+// observeAdvertTick 记录心跳定时器的触发时间，触发间隔超出允许延迟时计数并回调
+func (r *VirtualRouter) observeAdvertTick() {
+	now := r.now()
+	last := r.lastAdvertTick
+	r.lastAdvertTick = now
+	if last.IsZero() {
+		return
+	}
+	expected := centisToDuration(r.advertisementInterval)
+	tolerance := r.advertDelayTolerance
+	if tolerance <= 0 {
+		tolerance = expected / 2
+	}
+	actual := now.Sub(last)
+	if actual <= expected+tolerance {
+		return
+	}
+	r.stats.delayedAdverts.Add(1)
+	r.logger().Printf("WARN VRID [%d] advertisement delayed, interval %v exceeds expected %v", r.vrID, actual, expected)
+	if r.advertDelayHandler != nil {
+		r.advertDelayHandler(actual, expected)
+	}
+}
@@ -0,0 +1,59 @@
+package govrrp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_OnAdvertDelay(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	now := time.Unix(1000, 0)
+	vr.now = func() time.Time { return now }
+	vr.SetAdvInterval(time.Second)
+
+	type delay struct{ actual, expected time.Duration }
+	var got []delay
+	vr.OnAdvertDelay(func(actual, expected time.Duration) {
+		got = append(got, delay{actual, expected})
+	})
+
+	vr.makeAdvertTicker()
+	defer vr.stopAdvertTicker()
+
+	// 按时触发及允许范围内的延迟不计数
+	for _, step := range []time.Duration{time.Second, 1400 * time.Millisecond, 1500 * time.Millisecond} {
+		now = now.Add(step)
+		vr.observeAdvertTick()
+	}
+	if len(got) != 0 || vr.GetStatistics().DelayedAdverts != 0 {
+		t.Fatalf("on-time ticks reported as delayed: %v", got)
+	}
+
+	// 定时器错过一次触发
+	now = now.Add(2 * time.Second)
+	vr.observeAdvertTick()
+	if len(got) != 1 || got[0] != (delay{2 * time.Second, time.Second}) {
+		t.Fatalf("OnAdvertDelay calls = %v, want [{2s 1s}]", got)
+	}
+	if n := vr.GetStatistics().DelayedAdverts; n != 1 {
+		t.Errorf("DelayedAdverts = %d, want 1", n)
+	}
+
+	// 自定义允许延迟
+	vr.SetAdvertDelayTolerance(100 * time.Millisecond)
+	now = now.Add(1200 * time.Millisecond)
+	vr.observeAdvertTick()
+	if n := vr.GetStatistics().DelayedAdverts; n != 2 || len(got) != 2 {
+		t.Errorf("DelayedAdverts = %d, calls = %d, want 2 with tolerance 100ms", n, len(got))
+	}
+
+	// 重新创建定时器后从新的起点计算间隔
+	now = now.Add(time.Hour)
+	vr.stopAdvertTicker()
+	vr.makeAdvertTicker()
+	now = now.Add(time.Second)
+	vr.observeAdvertTick()
+	if len(got) != 2 {
+		t.Errorf("restarted ticker reported as delayed: %v", got[len(got)-1])
+	}
+}
@@ -0,0 +1,258 @@
+// Package cmd 常用操作的命令行子命令，便于构建自定义程序时复用参数解析与信号处理：
+// run（运行虚拟路由器）、discover（发现网段中的虚拟路由器）、status（查询指定虚拟路由器的主节点）以及虚拟路由器运行状态的输出。
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Trisia/govrrp"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// dial 创建监听VRRP消息的连接，便于测试替换
+var dial = func(nif string, family byte) (govrrp.VRRPMsgConnection, error) {
+	ift, err := net.InterfaceByName(nif)
+	if err != nil {
+		return nil, err
+	}
+	if family == govrrp.IPv4 {
+		return govrrp.NewIPv4VRRPMsgConn(ift, net.IPv4zero, govrrp.VRRPMultiAddrIPv4)
+	}
+	return govrrp.NewIPv6VRRPMsgCon(ift, net.IPv6unspecified, govrrp.VRRPMultiAddrIPv6)
+}
+
+// Main 根据第一个参数执行对应的子命令并返回进程退出码，用于 main 函数：os.Exit(cmd.Main(os.Args[1:]))
+func Main(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: <command> [flags]\n\ncommands:\n  run       运行虚拟路由器\n  discover  发现网段中的虚拟路由器\n  status    查询指定虚拟路由器的主节点")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "run":
+		err = Run(args[1:], os.Stdout)
+	case "discover":
+		err = Discover(args[1:], os.Stdout)
+	case "status":
+		err = Status(args[1:], os.Stdout)
+	default:
+		usage()
+		return 2
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// parseFamily 解析协议类型参数
+func parseFamily(typ int) (byte, error) {
+	if family := byte(typ); family == govrrp.IPv4 || family == govrrp.IPv6 {
+		return family, nil
+	}
+	return 0, fmt.Errorf("invalid IP family %d, expect 4 or 6", typ)
+}
+
+// Run 根据命令行参数运行虚拟路由器，收到 SIGINT 或 SIGTERM 信号后停止。
+// 参数：-id 虚拟路由ID，-p 优先级，-i 网口名称，-t 协议类型，-vip 虚拟IP地址（逗号分隔，必填），
+// -itl 心跳间隔，-pp 抢占模式，-status 周期性输出运行状态的时间间隔（0 表示不输出）。
+func Run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	var (
+		vrid     = fs.Int("id", 240, "虚拟路由ID (1~255)")
+		priority = fs.Int("p", 100, "虚拟路由器优先级(1~255)，255表示主机拥有者")
+		nif      = fs.String("i", "", "网卡名称")
+		typ      = fs.Int("t", 4, "虚拟路由器类型(4:IPv4 6:IPv6)")
+		vips     = fs.String("vip", "", "虚拟IP地址，多个地址以逗号分隔")
+		interval = fs.Duration("itl", time.Second, "心跳间隔")
+		preempt  = fs.Bool("pp", true, "抢占模式")
+		status   = fs.Duration("status", 0, "周期性输出运行状态的时间间隔，0 表示不输出")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *nif == "" {
+		return errors.New("-i interface name is required")
+	}
+	if strings.TrimSpace(*vips) == "" {
+		return errors.New("-vip virtual IP address is required")
+	}
+	if *vrid < 1 || *vrid > 255 || *priority < 1 || *priority > 255 {
+		return fmt.Errorf("invalid VRID %d or priority %d", *vrid, *priority)
+	}
+	family, err := parseFamily(*typ)
+	if err != nil {
+		return err
+	}
+	var addrs []net.IP
+	for _, s := range strings.Split(*vips, ",") {
+		addr := net.ParseIP(strings.TrimSpace(s))
+		if addr == nil {
+			return fmt.Errorf("invalid virtual IP address %q", s)
+		}
+		addrs = append(addrs, addr)
+	}
+
+	vr, err := govrrp.NewVirtualRouter(byte(*vrid), *nif, *priority == 255, family)
+	if err != nil {
+		return err
+	}
+	vr.SetPreemptMode(*preempt)
+	vr.SetAdvInterval(*interval)
+	vr.SetPriorityAndMasterAdvInterval(byte(*priority), *interval)
+	for _, addr := range addrs {
+		vr.AddIPvXAddr(addr)
+	}
+	changed := func(r *govrrp.VirtualRouter) {
+		fmt.Fprintf(out, "VRID [%d] enter %s state\n", r.VRID(), govrrp.StateName(r.GetState()))
+	}
+	vr.AddEventListener(govrrp.Init2Master, changed)
+	vr.AddEventListener(govrrp.Init2Backup, changed)
+	vr.AddEventListener(govrrp.Backup2Master, changed)
+	vr.AddEventListener(govrrp.Master2Backup, changed)
+	vr.AddEventListener(govrrp.Master2Init, changed)
+	vr.AddEventListener(govrrp.Backup2Init, changed)
+
+	done := make(chan error, 1)
+	go func() { done <- vr.Start() }()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	var tick <-chan time.Time
+	if *status > 0 {
+		ticker := time.NewTicker(*status)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+			WriteStatus(out, vr)
+		case <-sig:
+			vr.Stop()
+			return <-done
+		case err = <-done:
+			return err
+		}
+	}
+}
+
+// Discover 根据命令行参数监听VRRP心跳消息，输出发现的虚拟路由器。
+// 参数：-i 网口名称，-t 协议类型，-d 监听时长。
+func Discover(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	var (
+		nif      = fs.String("i", "", "网卡名称")
+		typ      = fs.Int("t", 4, "协议类型(4:IPv4 6:IPv6)")
+		duration = fs.Duration("d", 3*time.Second, "监听时长")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *nif == "" {
+		return errors.New("-i interface name is required")
+	}
+	family, err := parseFamily(*typ)
+	if err != nil {
+		return err
+	}
+	conn, err := dial(*nif, family)
+	if err != nil {
+		return err
+	}
+	routers, err := govrrp.Discover(conn, *duration)
+	WriteDiscovered(out, routers)
+	return err
+}
+
+// Status 根据命令行参数监听VRRP心跳消息，输出指定虚拟路由器当前的主节点，可用于健康检查。
+// 监听时长内没有主节点发送心跳消息，或存在多个主节点时返回错误。
+// 参数：-i 网口名称，-t 协议类型，-id 虚拟路由ID，-d 监听时长。
+func Status(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	var (
+		nif      = fs.String("i", "", "网卡名称")
+		typ      = fs.Int("t", 4, "协议类型(4:IPv4 6:IPv6)")
+		vrid     = fs.Int("id", 240, "虚拟路由ID (1~255)")
+		duration = fs.Duration("d", 3*time.Second, "监听时长")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *nif == "" {
+		return errors.New("-i interface name is required")
+	}
+	if *vrid < 1 || *vrid > 255 {
+		return fmt.Errorf("invalid VRID %d", *vrid)
+	}
+	family, err := parseFamily(*typ)
+	if err != nil {
+		return err
+	}
+	conn, err := dial(*nif, family)
+	if err != nil {
+		return err
+	}
+	routers, err := govrrp.Discover(conn, *duration)
+	if err != nil {
+		return err
+	}
+	// 优先级为 0 的心跳消息表示主节点正在让渡，不视为主节点
+	var masters []govrrp.DiscoveredRouter
+	for _, r := range routers {
+		if r.VRID == byte(*vrid) && r.Priority > 0 {
+			masters = append(masters, r)
+		}
+	}
+	WriteDiscovered(out, masters)
+	switch len(masters) {
+	case 0:
+		return fmt.Errorf("no master advertising VRID %d within %v", *vrid, *duration)
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("VRID %d advertised by %d masters", *vrid, len(masters))
+	}
+}
+
+// WriteDiscovered 以表格形式输出发现的虚拟路由器
+func WriteDiscovered(out io.Writer, routers []govrrp.DiscoveredRouter) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VRID\tADDRESS\tPRIORITY\tINTERVAL\tVERSION\tVIPS")
+	for _, r := range routers {
+		vips := make([]string, 0, len(r.VIPs))
+		for _, vip := range r.VIPs {
+			vips = append(vips, vip.String())
+		}
+		fmt.Fprintf(w, "%d\t%v\t%d\t%v\tv%d\t%s\n", r.VRID, r.Addr, r.Priority, r.AdvInterval, r.Version, strings.Join(vips, ","))
+	}
+	_ = w.Flush()
+}
+
+// WriteStatus 以表格形式输出虚拟路由器的运行状态
+func WriteStatus(out io.Writer, routers ...*govrrp.VirtualRouter) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ROUTER\tSTATE\tPRIORITY\tINTERVAL\tMASTER INTERVAL\tELECTION\tRECEIVED\tSEND ERRORS\tPEERS")
+	for _, r := range routers {
+		stats := r.GetStatistics()
+		fmt.Fprintf(w, "%s\t%s\t%d\t%v\t%v\t%s\t%d\t%d\t%d\n", r.Identity(), govrrp.StateName(r.GetState()), r.GetPriority(),
+			r.GetAdvInterval(), r.GetMasterAdvInterval(), r.LastElectionReason(), stats.Received, stats.SendErrors, len(r.Peers()))
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Trisia/govrrp"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memConn 基于内存的 VRRPMsgConnection，依次返回投递的报文，关闭后返回错误
+type memConn struct {
+	in   chan *govrrp.VRRPPacket
+	done chan struct{}
+	once sync.Once
+}
+
+func (c *memConn) WriteMessage(*govrrp.VRRPPacket) error { return nil }
+
+func (c *memConn) ReadMessage() (*govrrp.VRRPPacket, error) {
+	select {
+	case pkt := <-c.in:
+		return pkt, nil
+	default:
+	}
+	select {
+	case pkt := <-c.in:
+		return pkt, nil
+	case <-c.done:
+		return nil, errors.New("memConn: use of closed connection")
+	}
+}
+
+func (c *memConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+func (c *memConn) ConnectionInfo() govrrp.ConnectionInfo {
+	return govrrp.ConnectionInfo{InterfaceName: "mem0", InterfaceIndex: 1}
+}
+
+func advertisement(VRID, priority byte, src string) *govrrp.VRRPPacket {
+	var pkt govrrp.VRRPPacket
+	pkt.SetVersion(govrrp.VRRPv3)
+	pkt.SetType()
+	pkt.SetVirtualRouterID(VRID)
+	pkt.SetPriority(priority)
+	pkt.SetAdvertisementInterval(100)
+	pkt.AddIPvXAddr(govrrp.IPv4, net.IPv4(192, 168, 0, 200+VRID))
+	pkt.Pshdr = &govrrp.PseudoHeader{Saddr: net.ParseIP(src).To4(), Protocol: govrrp.VRRPIPProtocolNumber}
+	return &pkt
+}
+
+func TestDiscover(t *testing.T) {
+	conn := &memConn{in: make(chan *govrrp.VRRPPacket, 8), done: make(chan struct{})}
+	conn.in <- advertisement(42, 150, "192.168.0.20")
+	conn.in <- advertisement(7, 100, "192.168.0.30")
+
+	var dialed string
+	defer func(orig func(string, byte) (govrrp.VRRPMsgConnection, error)) { dial = orig }(dial)
+	dial = func(nif string, family byte) (govrrp.VRRPMsgConnection, error) {
+		dialed = nif
+		if family != govrrp.IPv4 {
+			t.Errorf("dial family %d, want IPv4", family)
+		}
+		return conn, nil
+	}
+
+	var out bytes.Buffer
+	if err := Discover([]string{"-i", "mem0", "-d", "30ms"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if dialed != "mem0" {
+		t.Errorf("dialed interface %q, want mem0", dialed)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expect a header and 2 routers, got:\n%s", out.String())
+	}
+	for i, want := range []string{"7 ", "42 "} {
+		if !strings.HasPrefix(lines[i+1], want) {
+			t.Errorf("line %d = %q, want VRID %s", i+1, lines[i+1], want)
+		}
+	}
+	if !strings.Contains(lines[2], "192.168.0.20") || !strings.Contains(lines[2], "192.168.0.242") {
+		t.Errorf("line %q should contain the source and the virtual IP address", lines[2])
+	}
+
+	if err := Discover([]string{"-t", "4"}, &out); err == nil {
+		t.Error("discover without interface should fail")
+	}
+}
+
+func TestRun_RequiresVIP(t *testing.T) {
+	for _, args := range [][]string{{"-i", "mem0"}, {"-i", "mem0", "-vip", " "}} {
+		err := Run(args, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), "-vip") {
+			t.Errorf("run %v: got %v, want -vip required", args, err)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	defer func(orig func(string, byte) (govrrp.VRRPMsgConnection, error)) { dial = orig }(dial)
+	var conn *memConn
+	dial = func(string, byte) (govrrp.VRRPMsgConnection, error) { return conn, nil }
+	status := func(pkts ...*govrrp.VRRPPacket) (string, error) {
+		conn = &memConn{in: make(chan *govrrp.VRRPPacket, 8), done: make(chan struct{})}
+		for _, pkt := range pkts {
+			conn.in <- pkt
+		}
+		var out bytes.Buffer
+		err := Status([]string{"-i", "mem0", "-id", "42", "-d", "30ms"}, &out)
+		return out.String(), err
+	}
+
+	out, err := status(advertisement(42, 150, "192.168.0.20"), advertisement(7, 100, "192.168.0.30"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "42 ") || !strings.Contains(lines[1], "192.168.0.20") {
+		t.Errorf("expect the master of VRID 42 only, got:\n%s", out)
+	}
+
+	// 仅有让渡主节点的心跳消息或没有心跳消息时视为没有主节点
+	if _, err = status(advertisement(42, 0, "192.168.0.20")); err == nil || !strings.Contains(err.Error(), "no master") {
+		t.Errorf("got %v, want no master", err)
+	}
+	if _, err = status(advertisement(42, 150, "192.168.0.20"), advertisement(42, 100, "192.168.0.21")); err == nil {
+		t.Error("multiple masters should be reported")
+	}
+
+	if err = Status([]string{"-i", "mem0", "-id", "0"}, &bytes.Buffer{}); err == nil {
+		t.Error("invalid VRID should be rejected")
+	}
+}
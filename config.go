@@ -0,0 +1,159 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// ErrRestartRequired 配置变更无法在运行中的虚拟路由器上直接生效，需要重新创建虚拟路由器
+var ErrRestartRequired = errors.New("config change requires restart")
+
+// Config 虚拟路由器配置
+type Config struct {
+	VRID        byte          // 虚拟路由ID
+	Interface   string        // 工作网口名称
+	IPvX        byte          // IP协议类型(IPv4 或 IPv6)
+	Priority    byte          // 优先级，255 表示地址拥有者，为 0 时使用默认值 100
+	AdvInterval time.Duration // VRRP消息发送间隔，为 0 时使用默认值
+	Preempt     bool          // 抢占模式
+	VIPs        []net.IP      // 虚拟IP地址
+}
+
+// priority 获取 配置的优先级，未配置时使用默认值
+func (c Config) priority() byte {
+	if c.Priority == 0 {
+		return 100
+	}
+	return c.Priority
+}
+
+// advInterval 获取 配置的VRRP消息发送间隔，未配置时使用默认值
+func (c Config) advInterval() time.Duration {
+	if c.AdvInterval == 0 {
+		return defaultAdvertisementInterval
+	}
+	return c.AdvInterval
+}
+
+// Equal 判断 两个配置是否等价，未配置的优先级与VRRP消息发送间隔按默认值比较，虚拟IP地址不区分顺序
+func (c Config) Equal(other Config) bool {
+	return len(c.Diff(other)) == 0
+}
+
+// Diff 比较两个配置，返回可读的字段变更列表，每一项以字段名开头，如 "Priority: 100 -> 200"。
+// 其中 VRID、Interface、IPvX 的变更需要重新创建虚拟路由器，其余字段见 Reload。
+func (c Config) Diff(other Config) []string {
+	var diff []string
+	if c.VRID != other.VRID {
+		diff = append(diff, fmt.Sprintf("VRID: %d -> %d", c.VRID, other.VRID))
+	}
+	if c.Interface != other.Interface {
+		diff = append(diff, fmt.Sprintf("Interface: %q -> %q", c.Interface, other.Interface))
+	}
+	if c.IPvX != other.IPvX {
+		diff = append(diff, fmt.Sprintf("IPvX: %d -> %d", c.IPvX, other.IPvX))
+	}
+	if c.priority() != other.priority() {
+		diff = append(diff, fmt.Sprintf("Priority: %d -> %d", c.priority(), other.priority()))
+	}
+	if c.advInterval() != other.advInterval() {
+		diff = append(diff, fmt.Sprintf("AdvInterval: %v -> %v", c.advInterval(), other.advInterval()))
+	}
+	if c.Preempt != other.Preempt {
+		diff = append(diff, fmt.Sprintf("Preempt: %v -> %v", c.Preempt, other.Preempt))
+	}
+	if added, removed := diffVIPs(c.VIPs, other.VIPs); len(added) > 0 || len(removed) > 0 {
+		diff = append(diff, fmt.Sprintf("VIPs: added %v, removed %v", added, removed))
+	}
+	return diff
+}
+
+// diffVIPs 比较两组虚拟IP地址，返回新增与移除的地址，均按地址排序
+func diffVIPs(from, to []net.IP) (added, removed []netip.Addr) {
+	set := func(ips []net.IP) map[netip.Addr]bool {
+		m := make(map[netip.Addr]bool, len(ips))
+		for _, ip := range ips {
+			if addr, ok := netip.AddrFromSlice(ip); ok {
+				m[addr.Unmap()] = true
+			}
+		}
+		return m
+	}
+	old, cur := set(from), set(to)
+	for addr := range cur {
+		if !old[addr] {
+			added = append(added, addr)
+		}
+	}
+	for addr := range old {
+		if !cur[addr] {
+			removed = append(removed, addr)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].Less(added[j]) })
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Less(removed[j]) })
+	return added, removed
+}
+
+// NewVirtualRouterFromConfig 根据配置创建虚拟路由器
+func NewVirtualRouterFromConfig(cfg Config) (*VirtualRouter, error) {
+	ift, err := net.InterfaceByName(cfg.Interface)
+	if err != nil {
+		return nil, err
+	}
+	preferred, err := interfacePreferIP(ift, cfg.IPvX)
+	if err != nil {
+		return nil, err
+	}
+	vr, err := NewVirtualRouterSpec(cfg.VRID, ift, preferred, cfg.priority())
+	if err != nil {
+		return nil, err
+	}
+	vr.autoSource = true
+	vr.applyConfig(cfg)
+	return vr, nil
+}
+
+// applyConfig 将配置中的参数应用到虚拟路由器
+func (r *VirtualRouter) applyConfig(cfg Config) {
+	r.SetPreemptMode(cfg.Preempt)
+	r.SetAdvInterval(cfg.advInterval())
+	r.SetPriorityAndMasterAdvInterval(cfg.priority(), cfg.advInterval())
+	for _, vip := range cfg.VIPs {
+		r.AddIPvXAddr(vip)
+	}
+}
+
+// Reload 在运行中的虚拟路由器上应用新的配置
+// 目前仅虚拟IP地址的变更可直接生效，其余参数变更时返回 ErrRestartRequired，需要重新创建虚拟路由器。
+func (r *VirtualRouter) Reload(cfg Config) error {
+	if cfg.VRID != r.vrID || cfg.Interface != r.ift.Name || cfg.IPvX != r.ipvX {
+		return fmt.Errorf("%w: identity of VRID [%d] changed", ErrRestartRequired, r.vrID)
+	}
+	if cfg.priority() != r.priority || cfg.advInterval() != r.GetAdvInterval() || cfg.Preempt != r.preempt {
+		return fmt.Errorf("%w: parameters of VRID [%d] changed", ErrRestartRequired, r.vrID)
+	}
+
+	wanted := make(map[netip.Addr]net.IP, len(cfg.VIPs))
+	for _, vip := range cfg.VIPs {
+		if key, ok := netip.AddrFromSlice(vip); ok {
+			wanted[key.Unmap()] = vip
+		}
+	}
+	for _, vip := range r.GetVIPs() {
+		key, _ := netip.AddrFromSlice(vip)
+		if _, ok := wanted[key.Unmap()]; ok {
+			delete(wanted, key.Unmap())
+			continue
+		}
+		r.RemoveIPvXAddr(vip)
+	}
+	for _, vip := range wanted {
+		r.AddIPvXAddr(vip)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package govrrp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfig_Diff(t *testing.T) {
+	base := Config{
+		VRID:      10,
+		Interface: "eth0",
+		IPvX:      IPv4,
+		Preempt:   true,
+		VIPs:      []net.IP{net.ParseIP("192.168.0.100"), net.ParseIP("192.168.0.101")},
+	}
+	same := base
+	same.Priority = 100
+	same.AdvInterval = defaultAdvertisementInterval
+	same.VIPs = []net.IP{net.ParseIP("192.168.0.101"), net.ParseIP("192.168.0.100").To4()}
+	if !base.Equal(same) {
+		t.Errorf("expect equal configs, got diff %v", base.Diff(same))
+	}
+
+	changed := base
+	changed.AdvInterval = 2 * time.Second
+	changed.VIPs = []net.IP{net.ParseIP("192.168.0.100"), net.ParseIP("192.168.0.102")}
+	want := []string{
+		"AdvInterval: 1s -> 2s",
+		"VIPs: added [192.168.0.102], removed [192.168.0.101]",
+	}
+	if diff := base.Diff(changed); !reflect.DeepEqual(diff, want) {
+		t.Errorf("expect diff %q, got %q", want, diff)
+	}
+	if base.Equal(changed) {
+		t.Error("expect configs not equal")
+	}
+}
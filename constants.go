@@ -31,17 +31,53 @@ const (
 	IPv6 byte = 6
 )
 
+// State 虚拟路由器状态机状态 INIT | MASTER | BACKUP
+type State = uint32
+
 const (
 	INIT   uint32 = 0
 	MASTER uint32 = 1
 	BACKUP uint32 = 2
 )
 
+// StateName 获取 状态机状态的名称
+func StateName(s State) string {
+	switch s {
+	case INIT:
+		return "INIT"
+	case MASTER:
+		return "MASTER"
+	case BACKUP:
+		return "BACKUP"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// VRRPTypeAdvertisement VRRP报文类型 ADVERTISEMENT （RFC5798 5.2.2），也是唯一定义的报文类型
+const VRRPTypeAdvertisement byte = 1
+
+// VRRPv2 认证类型 （RFC 3768 5.3.6）
+const (
+	VRRPv2AuthNone       byte = 0 // 无认证
+	VRRPv2AuthSimpleText byte = 1 // 简单文本密码
+	VRRPv2AuthIPAH       byte = 2 // IP 认证头部
+)
+
+// VRRPv2AuthDataLen VRRPv2 认证数据长度 （RFC 3768 5.3.10）
+const VRRPv2AuthDataLen = 8
+
 const (
 	VRRPMultiTTL         = 255
 	VRRPIPProtocolNumber = 112 // IANA为VRRP分配的IPv4协议号为 112（十进制）。
 )
 
+// VRRP 协议报文监听网络，用于 net.ListenIP，协议号与 VRRPIPProtocolNumber 一致
+const (
+	VRRPListenNetworkIPv4 = "ip4:112" // IPv4 网络
+	VRRPListenNetworkIPv6 = "ip6:112" // IPv6 网络
+)
+
 // VRRPMultiAddrIPv4 VRRP协议多播IPv4地址 （RFC5798 5.1.1.2）
 // IANA为VRRP分配的IPv4多播地址为： 224.0.0.18
 var VRRPMultiAddrIPv4 = net.IPv4(224, 0, 0, 18)
@@ -58,6 +94,10 @@ type EVENT byte
 const (
 	SHUTDOWN EVENT = 0
 	START    EVENT = 1
+	PAUSE    EVENT = 2
+	RESUME   EVENT = 3
+	LINKDOWN EVENT = 4 // 工作网口链路断开，见 SetLinkMonitorInterval
+	LINKUP   EVENT = 5 // 工作网口链路恢复
 )
 
 func (e EVENT) String() string {
@@ -66,6 +106,14 @@ func (e EVENT) String() string {
 		return "START"
 	case SHUTDOWN:
 		return "SHUTDOWN"
+	case PAUSE:
+		return "PAUSE"
+	case RESUME:
+		return "RESUME"
+	case LINKDOWN:
+		return "LINKDOWN"
+	case LINKUP:
+		return "LINKUP"
 	default:
 		return "unknown event"
 	}
@@ -73,6 +121,7 @@ func (e EVENT) String() string {
 
 const PACKET_QUEUE_SIZE = 512
 const EVENT_CHANNEL_SIZE = 1
+const ERROR_CHANNEL_SIZE = 16
 
 // transition 状态切换类型
 type transition int
@@ -96,6 +145,30 @@ func (t transition) String() string {
 	}
 }
 
+// From 状态切换的源状态
+func (t transition) From() State {
+	switch t {
+	case Master2Backup, Master2Init:
+		return MASTER
+	case Backup2Master, Backup2Init:
+		return BACKUP
+	default:
+		return INIT
+	}
+}
+
+// To 状态切换的目标状态
+func (t transition) To() State {
+	switch t {
+	case Backup2Master, Init2Master:
+		return MASTER
+	case Master2Backup, Init2Backup:
+		return BACKUP
+	default:
+		return INIT
+	}
+}
+
 const (
 	Master2Backup transition = iota
 	Backup2Master
@@ -0,0 +1,34 @@
+package govrrp
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTransition_FromTo(t *testing.T) {
+	cases := []struct {
+		t        transition
+		from, to State
+	}{
+		{Master2Backup, MASTER, BACKUP},
+		{Backup2Master, BACKUP, MASTER},
+		{Init2Master, INIT, MASTER},
+		{Init2Backup, INIT, BACKUP},
+		{Master2Init, MASTER, INIT},
+		{Backup2Init, BACKUP, INIT},
+	}
+	for _, c := range cases {
+		if c.t.From() != c.from || c.t.To() != c.to {
+			t.Errorf("%s: expect %d -> %d, got %d -> %d", c.t, c.from, c.to, c.t.From(), c.t.To())
+		}
+	}
+}
+
+func TestVRRPListenNetwork(t *testing.T) {
+	if expect := fmt.Sprintf("ip4:%d", VRRPIPProtocolNumber); VRRPListenNetworkIPv4 != expect {
+		t.Errorf("expect %s, got %s", expect, VRRPListenNetworkIPv4)
+	}
+	if expect := fmt.Sprintf("ip6:%d", VRRPIPProtocolNumber); VRRPListenNetworkIPv6 != expect {
+		t.Errorf("expect %s, got %s", expect, VRRPListenNetworkIPv6)
+	}
+}
@@ -0,0 +1,31 @@
+package govrrp
+
+import "net"
+
+// masterInfo 最近一次接受的主节点心跳消息
+type masterInfo struct {
+	addr     net.IP
+	priority byte
+}
+
+// GetMasterAddr 获取 当前主节点的源地址，取自 BACKUP 状态下最近一次接受的主节点心跳消息。
+// 本节点为主节点或尚未收到主节点心跳消息时返回 nil，此时可通过 GetState 判断本节点是否为主节点。
+func (r *VirtualRouter) GetMasterAddr() net.IP {
+	if info := r.currentMaster.Load(); info != nil {
+		return info.addr
+	}
+	return nil
+}
+
+// GetMasterPriority 获取 当前主节点通告的优先级，本节点为主节点或尚未收到主节点心跳消息时返回 0
+func (r *VirtualRouter) GetMasterPriority() byte {
+	if info := r.currentMaster.Load(); info != nil {
+		return info.priority
+	}
+	return 0
+}
+
+// observeMaster 记录接受的主节点心跳消息
+func (r *VirtualRouter) observeMaster(packet *VRRPPacket) {
+	r.currentMaster.Store(&masterInfo{addr: packet.Pshdr.Saddr, priority: packet.GetPriority()})
+}
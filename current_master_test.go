@@ -0,0 +1,51 @@
+package govrrp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_GetMasterAddr(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	if vr.GetMasterAddr() != nil || vr.GetMasterPriority() != 0 {
+		t.Fatal("master should be unknown before start")
+	}
+	startRouter(t, vr)
+
+	// waitMaster 等待记录的主节点变为 addr 与 priority
+	waitMaster := func(addr string, priority byte) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if vr.GetMasterAddr().Equal(net.ParseIP(addr)) && vr.GetMasterPriority() == priority {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("expect master %s priority %d, got %v priority %d", addr, priority, vr.GetMasterAddr(), vr.GetMasterPriority())
+	}
+
+	vr.packetQueue <- newAdvertisement(240, 200, "192.168.0.20")
+	waitMaster("192.168.0.20", 200)
+	vr.packetQueue <- newAdvertisement(240, 150, "192.168.0.30")
+	waitMaster("192.168.0.30", 150)
+	if vr.GetState() != BACKUP {
+		t.Fatalf("expect BACKUP, got %s", StateName(vr.GetState()))
+	}
+
+	// 主节点下线后本节点成为主节点，记录的主节点被清空
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	if vr.GetMasterAddr() != nil || vr.GetMasterPriority() != 0 {
+		t.Errorf("master should be reset in MASTER state, got %v priority %d", vr.GetMasterAddr(), vr.GetMasterPriority())
+	}
+
+	// 被抢占后记录抢占的主节点
+	vr.packetQueue <- newAdvertisement(240, 200, "192.168.0.20")
+	waitMaster("192.168.0.20", 200)
+	if !waitState(vr, BACKUP, time.Second) {
+		t.Fatal("router should yield to the higher priority master")
+	}
+}
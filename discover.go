@@ -0,0 +1,80 @@
+package govrrp
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DiscoveredRouter 监听心跳消息发现的虚拟路由器
+type DiscoveredRouter struct {
+	VRID        byte          // 虚拟路由ID
+	Addr        net.IP        // 发送心跳消息的源地址
+	Priority    byte          // 心跳消息中的优先级
+	AdvInterval time.Duration // 心跳消息中的心跳间隔
+	Version     VRRPVersion   // VRRP协议版本
+	VIPs        []netip.Addr  // 心跳消息中的虚拟IP地址
+	LastSeen    time.Time     // 最近一次收到心跳消息的时间
+}
+
+// Discover 在 duration 时间内监听连接上的VRRP心跳消息，返回发现的虚拟路由器，按 VRID 与源地址排序。
+// 同一 VRID 与源地址仅保留最近一次的心跳消息，格式错误的报文被忽略。
+// 监听结束后关闭 conn；连接在此之前出错时返回已发现的虚拟路由器与该错误。
+func Discover(conn VRRPMsgConnection, duration time.Duration) ([]DiscoveredRouter, error) {
+	var expired atomic.Bool
+	timer := time.AfterFunc(duration, func() {
+		expired.Store(true)
+		_ = conn.Close()
+	})
+	defer timer.Stop()
+
+	type key struct {
+		vrid byte
+		addr string
+	}
+	found := make(map[key]DiscoveredRouter)
+	collect := func() []DiscoveredRouter {
+		routers := make([]DiscoveredRouter, 0, len(found))
+		for _, router := range found {
+			routers = append(routers, router)
+		}
+		sort.Slice(routers, func(i, j int) bool {
+			if routers[i].VRID != routers[j].VRID {
+				return routers[i].VRID < routers[j].VRID
+			}
+			return bytes.Compare(routers[i].Addr, routers[j].Addr) < 0
+		})
+		return routers
+	}
+	for {
+		packet, err := conn.ReadMessage()
+		if err != nil {
+			if expired.Load() {
+				// 监听时长到期，连接已关闭
+				return collect(), nil
+			}
+			if _, ok := err.(NetErr); !ok {
+				// 格式错误的报文，继续监听
+				continue
+			}
+			_ = conn.Close()
+			return collect(), err
+		}
+		var src net.IP
+		if packet.Pshdr != nil {
+			src = packet.Pshdr.Saddr
+		}
+		found[key{packet.GetVirtualRouterID(), src.String()}] = DiscoveredRouter{
+			VRID:        packet.GetVirtualRouterID(),
+			Addr:        src,
+			Priority:    packet.GetPriority(),
+			AdvInterval: centisToDuration(packet.GetAdvertisementInterval()),
+			Version:     VRRPVersion(packet.GetVersion()),
+			VIPs:        packet.GetIPAddrs(),
+			LastSeen:    time.Now(),
+		}
+	}
+}
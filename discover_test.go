@@ -0,0 +1,66 @@
+package govrrp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDiscover(t *testing.T) {
+	network := &memNetwork{}
+	conn := network.dial(net.ParseIP("192.168.0.10").To4())
+
+	first := newAdvertisement(20, 100, "192.168.0.30")
+	first.AddIPvXAddr(IPv4, net.IPv4(192, 168, 0, 200))
+	conn.deliver(first, nil)
+	conn.deliver(newAdvertisement(10, 150, "192.168.0.20"), nil)
+	conn.deliver(nil, errors.New("malformed"))
+	conn.deliver(newAdvertisement(20, 200, "192.168.0.30"), nil)
+	conn.deliver(newAdvertisement(20, 50, "192.168.0.21"), nil)
+
+	start := time.Now()
+	routers, err := Discover(conn, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Discover returned after %v, before the listen duration", elapsed)
+	}
+	want := []struct {
+		vrid     byte
+		addr     string
+		priority byte
+	}{
+		{10, "192.168.0.20", 150},
+		{20, "192.168.0.21", 50},
+		{20, "192.168.0.30", 200},
+	}
+	if len(routers) != len(want) {
+		t.Fatalf("discovered %d routers, want %d: %+v", len(routers), len(want), routers)
+	}
+	for i, w := range want {
+		got := routers[i]
+		if got.VRID != w.vrid || got.Addr.String() != w.addr || got.Priority != w.priority {
+			t.Errorf("router %d = VRID %d %v priority %d, want VRID %d %s priority %d",
+				i, got.VRID, got.Addr, got.Priority, w.vrid, w.addr, w.priority)
+		}
+		if got.AdvInterval != testInterval || got.Version != VRRPv3 {
+			t.Errorf("router %d interval %v version %v", i, got.AdvInterval, got.Version)
+		}
+	}
+
+	// 连接已关闭
+	if _, err = conn.ReadMessage(); err == nil {
+		t.Error("Discover should close the connection")
+	}
+
+	// 监听期间连接出错
+	broken := network.dial(net.ParseIP("192.168.0.11").To4())
+	broken.deliver(newAdvertisement(30, 100, "192.168.0.40"), nil)
+	broken.deliver(nil, NetErr{errors.New("socket closed")})
+	routers, err = Discover(broken, time.Second)
+	if err == nil || len(routers) != 1 {
+		t.Errorf("Discover on broken connection = %d routers, %v; want 1 router and an error", len(routers), err)
+	}
+}
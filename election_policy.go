@@ -0,0 +1,44 @@
+package govrrp
+
+// ElectionPolicy 选举策略，在状态机的决策点被调用，用于实现 RFC 之外的选举语义（如基于法定数量或权重的选举）。
+// 策略函数在状态机协程中同步调用，请勿在其中执行耗时操作。
+type ElectionPolicy interface {
+	// ShouldYield 收到对端心跳消息时是否让渡于对端：
+	// MASTER 状态下返回 true 时切换至 BACKUP 状态；BACKUP 状态下返回 true 时视为来自主节点，重置主节点下线倒计时。
+	// 地址拥有者（优先级 255）的 MASTER 状态以及优先级为 0 的心跳消息不经过该决策。
+	ShouldYield(r *VirtualRouter, packet *VRRPPacket) bool
+	// ShouldBecomeMaster 主节点下线倒计时到期时是否成为主节点，返回 false 时保持 BACKUP 状态并重新开始倒计时。
+	// 地址拥有者启动或恢复时直接成为主节点，不经过该决策。
+	ShouldBecomeMaster(r *VirtualRouter) bool
+}
+
+// RFCElectionPolicy RFC 5798 定义的选举策略，为默认的选举策略：
+// 对端优先级更高，或优先级相同且对端源IP地址更大时让渡；主节点下线倒计时到期时成为主节点。
+type RFCElectionPolicy struct{}
+
+// ShouldYield 对端优先级更高，或优先级相同且对端源IP地址更大时让渡
+func (RFCElectionPolicy) ShouldYield(r *VirtualRouter, packet *VRRPPacket) bool {
+	priority := r.effectivePriority()
+	return packet.GetPriority() > priority ||
+		(packet.GetPriority() == priority && largerThan(packet.Pshdr.Saddr, r.preferredSourceIP))
+}
+
+// ShouldBecomeMaster 主节点下线倒计时到期时总是成为主节点
+func (RFCElectionPolicy) ShouldBecomeMaster(*VirtualRouter) bool {
+	return true
+}
+
+// SetElectionPolicy 设置 选举策略，为 nil 时使用默认的 RFCElectionPolicy。
+// 抢占模式（SetPreemptMode）、相同优先级抢占、抢占延迟以及最短主节点保持时间等设置在选举策略之外继续生效。需在 Start 前调用。
+func (r *VirtualRouter) SetElectionPolicy(policy ElectionPolicy) *VirtualRouter {
+	r.electionPolicy = policy
+	return r
+}
+
+// election 获取 当前使用的选举策略
+func (r *VirtualRouter) election() ElectionPolicy {
+	if r.electionPolicy == nil {
+		return RFCElectionPolicy{}
+	}
+	return r.electionPolicy
+}
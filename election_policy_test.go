@@ -0,0 +1,110 @@
+package govrrp
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// quorumPolicy 至少观察到 quorum 个对端后才允许成为主节点的选举策略
+type quorumPolicy struct {
+	RFCElectionPolicy
+	quorum   int
+	rejected atomic.Int32
+}
+
+func (p *quorumPolicy) ShouldBecomeMaster(r *VirtualRouter) bool {
+	if len(r.Peers()) < p.quorum {
+		p.rejected.Add(1)
+		return false
+	}
+	return true
+}
+
+func TestVirtualRouter_SetElectionPolicy(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	policy := &quorumPolicy{quorum: 1}
+	vr.SetElectionPolicy(policy)
+	startRouter(t, vr)
+
+	// 未达到法定数量时，主节点下线倒计时到期也不会成为主节点
+	if waitState(vr, MASTER, 10*testInterval) {
+		t.Fatal("router promoted to MASTER without quorum")
+	}
+	if policy.rejected.Load() == 0 {
+		t.Fatal("election policy was not consulted when the master down timer expired")
+	}
+
+	// 观察到对端（优先级较低，不会被视为主节点）后达到法定数量
+	conn.deliver(newAdvertisement(240, 50, "192.168.0.20"), nil)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatalf("router should become MASTER once quorum is met, state %d", vr.GetState())
+	}
+	if reason := vr.LastElectionReason(); reason != ElectionReasonPriority {
+		t.Errorf("LastElectionReason = %q, want %q", reason, ElectionReasonPriority)
+	}
+}
+
+func TestRFCElectionPolicy_ShouldYield(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	policy := RFCElectionPolicy{}
+	cases := []struct {
+		priority byte
+		src      string
+		want     bool
+	}{
+		{200, "192.168.0.1", true},
+		{100, "192.168.0.20", true},
+		{100, "192.168.0.5", false},
+		{50, "192.168.0.20", false},
+	}
+	for _, tc := range cases {
+		if got := policy.ShouldYield(vr, newAdvertisement(240, tc.priority, tc.src)); got != tc.want {
+			t.Errorf("ShouldYield(priority %d from %s) = %v, want %v", tc.priority, tc.src, got, tc.want)
+		}
+	}
+	if !policy.ShouldBecomeMaster(vr) {
+		t.Error("RFC policy should always allow promotion")
+	}
+}
+
+func TestLargerThan(t *testing.T) {
+	for _, c := range []struct {
+		ip1, ip2 net.IP
+		expect   bool
+	}{
+		{net.ParseIP("192.168.0.20"), net.ParseIP("192.168.0.10").To4(), true},
+		{net.ParseIP("192.168.0.20").To4(), net.ParseIP("192.168.0.10"), true},
+		{net.ParseIP("192.168.0.10").To4(), net.ParseIP("192.168.0.20"), false},
+		{net.ParseIP("192.168.0.10"), net.ParseIP("192.168.0.10").To4(), false},
+		{net.ParseIP("fe80::20"), net.ParseIP("fe80::10"), true},
+		{net.ParseIP("fe80::20"), net.ParseIP("192.168.0.10").To4(), false},
+		{nil, net.ParseIP("192.168.0.10"), false},
+	} {
+		if got := largerThan(c.ip1, c.ip2); got != c.expect {
+			t.Errorf("largerThan(%v[%d], %v[%d]) expect %v, got %v", c.ip1, len(c.ip1), c.ip2, len(c.ip2), c.expect, got)
+		}
+	}
+}
+
+func TestVirtualRouter_EqualPriorityMixedAddrLength(t *testing.T) {
+	network := &memNetwork{}
+	low, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	high, highConn := newTestRouter(t, network, 240, "192.168.0.20", 100)
+	// 对端收到的源地址为 16 字节形式，本端保存的源地址为 4 字节形式
+	highConn.src = net.ParseIP("192.168.0.20").To16()
+
+	startRouter(t, low)
+	if !waitState(low, MASTER, time.Second) {
+		t.Fatal("the first router should become master")
+	}
+	startRouter(t, high)
+	if !waitState(low, BACKUP, time.Second) {
+		t.Fatalf("the router with lower IP should yield, got %s", StateName(low.GetState()))
+	}
+	time.Sleep(5 * testInterval)
+	if high.GetState() != MASTER || low.GetState() != BACKUP {
+		t.Errorf("expect high=MASTER low=BACKUP, got high=%s low=%s", StateName(high.GetState()), StateName(low.GetState()))
+	}
+}
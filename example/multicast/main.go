@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/hex"
+	"github.com/Trisia/govrrp"
 	"golang.org/x/net/ipv4"
 	"log"
 	"net"
@@ -20,7 +21,7 @@ func main() {
 	multiAddr := &net.IPAddr{IP: net.IPv4(224, 0, 0, 18)}
 
 	itf, _ := net.InterfaceByName(name)
-	conn, err := net.ListenIP("ip4:112", localAddr)
+	conn, err := net.ListenIP(govrrp.VRRPListenNetworkIPv4, localAddr)
 	if err != nil {
 		log.Printf("网口 [%s] 无法监听组播消息， %v\n", itf.Name, err)
 		return
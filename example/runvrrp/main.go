@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"github.com/Trisia/govrrp"
 	"log"
@@ -19,6 +20,8 @@ var (
 	VIP      string // 虚拟IP地址
 	Mill     int    // 发送间隔毫秒数
 	preempt  bool   // 抢占模式
+	accept   bool   // 接受模式
+	Conf     string // 配置文件路径
 )
 
 func init() {
@@ -29,11 +32,86 @@ func init() {
 	flag.StringVar(&VIP, "vip", "", "虚拟IP地址")
 	flag.IntVar(&Mill, "itl", 800, "发送间隔毫秒数")
 	flag.BoolVar(&preempt, "pp", false, "抢占模式")
+	flag.BoolVar(&accept, "accept", false, "接受模式，主路由器是否接收目的地址为虚拟IP地址的数据包")
+	flag.StringVar(&Conf, "c", "", "配置文件路径(JSON)，指定后忽略其余参数，收到 SIGHUP 信号时重新加载")
+}
+
+// routerConf 配置文件中的虚拟路由器配置
+type routerConf struct {
+	VRID     byte     `json:"vrid"`
+	Priority byte     `json:"priority"`
+	Nif      string   `json:"nif"`
+	Typ      byte     `json:"type"`
+	VIPs     []string `json:"vips"`
+	Mill     int      `json:"itl"`
+	Preempt  bool     `json:"preempt"`
+}
+
+// loadConf 加载配置文件
+func loadConf(path string) ([]govrrp.Config, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var confs []routerConf
+	if err = json.Unmarshal(bin, &confs); err != nil {
+		return nil, err
+	}
+	cfgs := make([]govrrp.Config, 0, len(confs))
+	for _, c := range confs {
+		cfg := govrrp.Config{
+			VRID:        c.VRID,
+			Interface:   c.Nif,
+			IPvX:        c.Typ,
+			Priority:    c.Priority,
+			AdvInterval: time.Millisecond * time.Duration(c.Mill),
+			Preempt:     c.Preempt,
+		}
+		for _, vip := range c.VIPs {
+			cfg.VIPs = append(cfg.VIPs, net.ParseIP(vip))
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
+}
+
+// runWithConf 根据配置文件运行虚拟路由器，收到 SIGHUP 信号时重新加载配置
+func runWithConf(path string) {
+	reloader := govrrp.NewReloader(nil)
+	cfgs, err := loadConf(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = reloader.Apply(cfgs); err != nil {
+		log.Println(err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM)
+	for s := range sig {
+		if s != syscall.SIGHUP {
+			break
+		}
+		log.Println("reload config", path)
+		cfgs, err = loadConf(path)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if err = reloader.Apply(cfgs); err != nil {
+			log.Println(err)
+		}
+	}
+	reloader.StopAll()
 }
 
 func main() {
 
 	flag.Parse()
+	if Conf != "" {
+		runWithConf(Conf)
+		return
+	}
 	if Nif == "" {
 		log.Fatal("-i 网卡名称不能为空")
 	}
@@ -44,22 +122,23 @@ func main() {
 	if VIP == "" || addr == nil {
 		log.Fatal("-vip 虚拟IP地址错误")
 	}
-	log.Printf("VRID: %d, Priority: %d, NIC: %s, VIP: %s, Mill: %d, preempt: %v\n", VRID, Priority, Nif, VIP, Mill, preempt)
+	log.Printf("VRID: %d, Priority: %d, NIC: %s, VIP: %s, Mill: %d, preempt: %v, accept: %v\n", VRID, Priority, Nif, VIP, Mill, preempt, accept)
 	vr, err := govrrp.NewVirtualRouter(byte(VRID), Nif, Priority == 255, byte(Typ))
 	if err != nil {
 		log.Fatal(err)
 	}
 	vr.SetPreemptMode(preempt)
+	vr.SetAcceptMode(accept)
 	vr.SetAdvInterval(time.Millisecond * time.Duration(Mill))
 	vr.SetPriorityAndMasterAdvInterval(byte(Priority), time.Millisecond*time.Duration(Mill))
 	vr.AddIPvXAddr(addr)
 
 	vr.AddEventListener(govrrp.Init2Master, func(ctx *govrrp.VirtualRouter) {
-		log.Printf("VRID [%d] init to master\n", ctx.VRID())
+		log.Printf("VRID [%d] init to master, accept mode: %v\n", ctx.VRID(), ctx.GetAcceptMode())
 
 	})
 	vr.AddEventListener(govrrp.Backup2Master, func(ctx *govrrp.VirtualRouter) {
-		log.Printf("VRID [%d] backup to master\n", vr.VRID())
+		log.Printf("VRID [%d] backup to master, accept mode: %v\n", ctx.VRID(), ctx.GetAcceptMode())
 
 	})
 	vr.AddEventListener(govrrp.Master2Init, func(ctx *govrrp.VirtualRouter) {
@@ -0,0 +1,54 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrInstanceLocked 同一主机上已有其他进程运行相同网口、协议类型与虚拟路由ID的虚拟路由器
+var ErrInstanceLocked = errors.New("virtual router instance is locked by another process")
+
+// SetInstanceLock 设置 实例锁文件所在目录，为空表示不使用实例锁（默认不使用）。
+//
+// 开启后 Start 时将在该目录下以 Identity（工作网口名称、协议类型 与 虚拟路由ID）为键获取建议锁，
+// 若同一主机上已有其他进程持有该锁则 Start 立即返回 ErrInstanceLocked，
+// 避免两个进程以相同的虚拟路由ID在同一网口上互相争抢。锁在虚拟路由器停止后释放。
+func (r *VirtualRouter) SetInstanceLock(dir string) *VirtualRouter {
+	r.lockDir = dir
+	return r
+}
+
+// instanceLockPath 实例锁文件路径
+func (r *VirtualRouter) instanceLockPath() string {
+	return filepath.Join(r.lockDir, "govrrp-"+r.Identity()+".lock")
+}
+
+// acquireInstanceLock 获取实例锁，未开启实例锁时直接返回
+func (r *VirtualRouter) acquireInstanceLock() error {
+	if r.lockDir == "" || r.lockFile != nil {
+		return nil
+	}
+	path := r.instanceLockPath()
+	f, err := lockFile(path)
+	if err != nil {
+		return fmt.Errorf("VRID [%d] acquire instance lock %s: %w", r.vrID, path, err)
+	}
+	r.lockFile = f
+	return nil
+}
+
+// releaseInstanceLock 释放实例锁
+func (r *VirtualRouter) releaseInstanceLock() {
+	if r.lockFile != nil {
+		_ = unlockFile(r.lockFile)
+		r.lockFile = nil
+	}
+}
+
+// unlockFile 释放文件锁并关闭文件
+func unlockFile(f *os.File) error {
+	// 关闭文件描述符即释放建议锁
+	return f.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package govrrp
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile 当前平台不支持文件建议锁
+func lockFile(string) (*os.File, error) {
+	return nil, errors.New("instance lock is not supported on this platform")
+}
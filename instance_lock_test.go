@@ -0,0 +1,56 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_SetInstanceLock(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	first.SetInstanceLock(dir)
+	startRouter(t, first)
+	if !waitState(first, BACKUP, time.Second) {
+		t.Fatal("the first router should start")
+	}
+
+	second, _ := newTestRouter(t, nil, 240, "192.168.0.11", 100)
+	second.SetInstanceLock(dir)
+	if err := second.Start(); !errors.Is(err, ErrInstanceLocked) {
+		t.Fatalf("expect ErrInstanceLocked, got %v", err)
+	}
+
+	// 其他虚拟路由ID不受影响
+	other, _ := newTestRouter(t, nil, 241, "192.168.0.11", 100)
+	other.SetInstanceLock(dir)
+	if err := other.acquireInstanceLock(); err != nil {
+		t.Fatal(err)
+	}
+	other.releaseInstanceLock()
+
+	first.Stop()
+	time.Sleep(50 * time.Millisecond)
+	if err := second.acquireInstanceLock(); err != nil {
+		t.Fatalf("the lock should be released after stop, got %v", err)
+	}
+	second.releaseInstanceLock()
+}
+
+func TestVirtualRouter_InstanceLockPerFamily(t *testing.T) {
+	dir := t.TempDir()
+	v4, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	v6, _ := newTestRouter(t, nil, 240, "fe80::10", 100)
+	v4.SetInstanceLock(dir)
+	v6.SetInstanceLock(dir)
+	if err := v4.acquireInstanceLock(); err != nil {
+		t.Fatal(err)
+	}
+	defer v4.releaseInstanceLock()
+	if err := v6.acquireInstanceLock(); err != nil {
+		t.Fatalf("the same VRID of another family should not be locked, got %v", err)
+	}
+	v6.releaseInstanceLock()
+}
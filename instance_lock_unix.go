@@ -0,0 +1,27 @@
+//go:build unix
+
+package govrrp
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile 打开文件并以非阻塞方式获取排他建议锁
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrInstanceLocked
+		}
+		return nil, err
+	}
+	_ = f.Truncate(0)
+	_, _ = fmt.Fprintf(f, "%d\n", os.Getpid())
+	return f, nil
+}
@@ -0,0 +1,126 @@
+package govrrp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"net"
+)
+
+// ErrL2AdvertUnsupported 当前平台不支持通过二层发送心跳消息
+var ErrL2AdvertUnsupported = errors.New("sending advertisements at L2 is not supported")
+
+// 以太网帧相关常量
+const (
+	etherHeaderLen = 14     // 以太网帧头长度
+	etherTypeIPv4  = 0x0800 // IPv4 以太网类型
+	etherTypeIPv6  = 0x86dd // IPv6 以太网类型
+)
+
+// frameWriter 二层帧发送接口，frame 为包含以太网帧头的完整帧
+type frameWriter interface {
+	WriteFrame(frame []byte) error
+	Close() error
+}
+
+// SetL2Advertisement 设置 是否通过二层（Linux AF_PACKET）发送心跳消息，默认值为 false。
+//
+// 通过IP套接字发送时，以太网帧的源MAC地址由内核填充为工作网口的MAC地址；
+// 开启后由本库构造完整的以太网帧，以虚拟MAC地址作为源MAC地址发送，符合 RFC 5798 7.3 的要求。
+// 接收仍使用原有的IP套接字。需要 CAP_NET_RAW 权限，仅支持 Linux 平台，其他平台返回 ErrL2AdvertUnsupported。
+func (r *VirtualRouter) SetL2Advertisement(flag bool) error {
+	if r.l2Writer != nil {
+		_ = r.l2Writer.Close()
+		r.l2Writer = nil
+	}
+	r.connOpts.l2 = false
+	if !flag {
+		return nil
+	}
+	w, err := listenL2(r.ift)
+	if err != nil {
+		return fmt.Errorf("VRID [%d] open L2 sender on %s: %w", r.vrID, r.ift.Name, err)
+	}
+	r.l2Writer = w
+	r.connOpts.l2 = true
+	r.logger().Printf("VRID [%d] advertisements sent at L2 from virtual MAC %v", r.vrID, r.virtualMAC())
+	return nil
+}
+
+// virtualMAC 当前协议族的虚拟MAC地址
+func (r *VirtualRouter) virtualMAC() net.HardwareAddr {
+	if r.ipvX == IPv6 {
+		return r.virtualRouterMACAddressIPv6
+	}
+	return r.virtualRouterMACAddressIPv4
+}
+
+// writeL2Advert 以虚拟MAC地址为源MAC地址构造以太网帧并发送心跳消息
+func (r *VirtualRouter) writeL2Advert(packet *VRRPPacket) error {
+	frame, err := buildAdvertFrame(r.virtualMAC(), r.advertPseudoHeader(packet), packet.ToBytes())
+	if err != nil {
+		return err
+	}
+	return r.l2Writer.WriteFrame(frame)
+}
+
+// multicastMAC 组播地址对应的以太网组播MAC地址（RFC 1112 6.4、RFC 2464 7）
+func multicastMAC(group net.IP) (net.HardwareAddr, error) {
+	if ip4 := group.To4(); ip4 != nil {
+		if !ip4.IsMulticast() {
+			return nil, fmt.Errorf("destination %v is not a multicast address", group)
+		}
+		return net.HardwareAddr{0x01, 0x00, 0x5e, ip4[1] & 0x7f, ip4[2], ip4[3]}, nil
+	}
+	if ip6 := group.To16(); ip6 != nil && ip6.IsMulticast() {
+		return net.HardwareAddr{0x33, 0x33, ip6[12], ip6[13], ip6[14], ip6[15]}, nil
+	}
+	return nil, fmt.Errorf("destination %v is not a multicast address", group)
+}
+
+// buildAdvertFrame 构造心跳消息的以太网帧，IP头部的 TTL/Hop Limit 为 255，协议号为 112
+// src: 源MAC地址
+// pshdr: 伪头部，提供源、目的IP地址
+// payload: VRRP报文
+func buildAdvertFrame(src net.HardwareAddr, pshdr *PseudoHeader, payload []byte) ([]byte, error) {
+	dst, err := multicastMAC(pshdr.Daddr)
+	if err != nil {
+		return nil, err
+	}
+	var frame []byte
+	if saddr := pshdr.Saddr.To4(); saddr != nil {
+		frame = make([]byte, etherHeaderLen+ipv4.HeaderLen, etherHeaderLen+ipv4.HeaderLen+len(payload))
+		binary.BigEndian.PutUint16(frame[12:], etherTypeIPv4)
+		ip := frame[etherHeaderLen:]
+		ip[0] = 4<<4 | ipv4.HeaderLen>>2
+		binary.BigEndian.PutUint16(ip[2:], uint16(ipv4.HeaderLen+len(payload)))
+		ip[8] = VRRPMultiTTL
+		ip[9] = VRRPIPProtocolNumber
+		copy(ip[12:16], saddr)
+		copy(ip[16:20], pshdr.Daddr.To4())
+		binary.BigEndian.PutUint16(ip[10:], ^foldSum(sumWords(0, ip)))
+	} else {
+		frame = make([]byte, etherHeaderLen+ipv6.HeaderLen, etherHeaderLen+ipv6.HeaderLen+len(payload))
+		binary.BigEndian.PutUint16(frame[12:], etherTypeIPv6)
+		ip := frame[etherHeaderLen:]
+		ip[0] = 6 << 4
+		binary.BigEndian.PutUint16(ip[4:], uint16(len(payload)))
+		ip[6] = VRRPIPProtocolNumber
+		ip[7] = VRRPMultiTTL
+		copy(ip[8:24], pshdr.Saddr.To16())
+		copy(ip[24:40], pshdr.Daddr.To16())
+	}
+	copy(frame[0:6], dst)
+	copy(frame[6:12], src)
+	return append(frame, payload...), nil
+}
+
+// foldSum 将32位累加和折叠为16位
+func foldSum(sum uint32) uint16 {
+	for (sum >> 16) > 0 {
+		sum = sum&65535 + sum>>16
+	}
+	return uint16(sum)
+}
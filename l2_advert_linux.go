@@ -0,0 +1,38 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"github.com/mdlayher/packet"
+	"net"
+	"sync"
+)
+
+// packetFrameWriter 基于 AF_PACKET 套接字的二层帧发送接口
+type packetFrameWriter struct {
+	conn *packet.Conn
+
+	closeOnce sync.Once // 确保仅关闭一次
+	closeErr  error     // 关闭的结果
+}
+
+// listenL2 在工作网口上打开仅用于发送的 AF_PACKET 原始套接字，协议号为 0 表示不接收任何帧
+func listenL2(ift *net.Interface) (frameWriter, error) {
+	conn, err := packet.Listen(ift, packet.Raw, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &packetFrameWriter{conn: conn}, nil
+}
+
+func (w *packetFrameWriter) WriteFrame(frame []byte) error {
+	_, err := w.conn.WriteTo(frame, &packet.Addr{HardwareAddr: net.HardwareAddr(frame[0:6])})
+	return err
+}
+
+func (w *packetFrameWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.closeErr = w.conn.Close()
+	})
+	return w.closeErr
+}
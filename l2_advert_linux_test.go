@@ -0,0 +1,41 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestListenL2(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip(err)
+	}
+	w, err := listenL2(lo)
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("CAP_NET_RAW is required")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	pkt := &VRRPPacket{}
+	pkt.SetVersion(VRRPv3)
+	pkt.SetType()
+	pkt.SetVirtualRouterID(240)
+	pkt.SetPriority(100)
+	pkt.SetAdvertisementInterval(100)
+	pshdr := &PseudoHeader{Saddr: net.IPv4(127, 0, 0, 1), Daddr: VRRPMultiAddrIPv4, Protocol: VRRPIPProtocolNumber, Len: uint16(pkt.PacketSize())}
+	pkt.SetCheckSum(pshdr)
+	frame, err := buildAdvertFrame(net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x01, 240}, pshdr, pkt.ToBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteFrame(frame); err != nil {
+		t.Fatal(err)
+	}
+}
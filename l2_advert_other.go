@@ -0,0 +1,12 @@
+//go:build !linux
+
+package govrrp
+
+import (
+	"net"
+)
+
+// listenL2 当前平台不支持通过二层发送心跳消息
+func listenL2(*net.Interface) (frameWriter, error) {
+	return nil, ErrL2AdvertUnsupported
+}
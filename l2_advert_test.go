@@ -0,0 +1,93 @@
+package govrrp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+)
+
+// fakeFrameWriter 记录发送的二层帧
+type fakeFrameWriter struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func (w *fakeFrameWriter) WriteFrame(frame []byte) error {
+	w.mu.Lock()
+	w.frames = append(w.frames, append([]byte(nil), frame...))
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *fakeFrameWriter) Close() error { return nil }
+
+func TestVirtualRouter_L2Advertisement(t *testing.T) {
+	for _, tc := range []struct {
+		src       string
+		dstMAC    string
+		etherType uint16
+		ipLen     int
+	}{
+		{src: "192.168.0.10", dstMAC: "01:00:5e:00:00:12", etherType: etherTypeIPv4, ipLen: 20},
+		{src: "fe80::10", dstMAC: "33:33:00:00:00:12", etherType: etherTypeIPv6, ipLen: 40},
+	} {
+		vr, conn := newTestRouter(t, nil, 240, tc.src, 100)
+		vr.AddIPvXAddr(net.ParseIP(map[byte]string{IPv4: "192.168.0.100", IPv6: "fe80::100"}[vr.ipvX]))
+		w := &fakeFrameWriter{}
+		vr.l2Writer = w
+		vr.sendAdvertMessage()
+
+		if len(conn.sentPackets()) != 0 {
+			t.Fatalf("%s: expect no packet sent through the IP socket", tc.src)
+		}
+		if len(w.frames) != 1 {
+			t.Fatalf("%s: expect 1 frame, got %d", tc.src, len(w.frames))
+		}
+		frame := w.frames[0]
+		if dst := net.HardwareAddr(frame[0:6]).String(); dst != tc.dstMAC {
+			t.Errorf("%s: expect destination MAC %s, got %s", tc.src, tc.dstMAC, dst)
+		}
+		if src := net.HardwareAddr(frame[6:12]); !bytes.Equal(src, vr.virtualMAC()) {
+			t.Errorf("%s: expect virtual source MAC %v, got %v", tc.src, vr.virtualMAC(), src)
+		}
+		if typ := binary.BigEndian.Uint16(frame[12:14]); typ != tc.etherType {
+			t.Errorf("%s: expect ether type %#x, got %#x", tc.src, tc.etherType, typ)
+		}
+		ip := frame[etherHeaderLen:]
+		var saddr, daddr net.IP
+		if vr.ipvX == IPv4 {
+			if ip[8] != VRRPMultiTTL || ip[9] != VRRPIPProtocolNumber {
+				t.Errorf("unexpected TTL %d or protocol %d", ip[8], ip[9])
+			}
+			if foldSum(sumWords(0, ip[:tc.ipLen])) != 0xffff {
+				t.Error("invalid IPv4 header checksum")
+			}
+			saddr, daddr = net.IP(ip[12:16]), net.IP(ip[16:20])
+		} else {
+			if ip[6] != VRRPIPProtocolNumber || ip[7] != VRRPMultiTTL {
+				t.Errorf("unexpected next header %d or hop limit %d", ip[6], ip[7])
+			}
+			saddr, daddr = net.IP(ip[8:24]), net.IP(ip[24:40])
+		}
+		pkt, err := FromBytes(vr.ipvX, ip[tc.ipLen:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		pshdr := &PseudoHeader{Saddr: saddr, Daddr: daddr, Protocol: VRRPIPProtocolNumber, Len: uint16(pkt.PacketSize())}
+		if !pkt.ValidateCheckSum(pshdr) || pkt.GetVirtualRouterID() != 240 {
+			t.Errorf("%s: unexpected advertisement %v", tc.src, pkt)
+		}
+	}
+}
+
+func TestMulticastMAC(t *testing.T) {
+	if _, err := multicastMAC(net.ParseIP("192.168.0.1")); err == nil {
+		t.Error("expect error for unicast destination")
+	}
+	mac, err := multicastMAC(net.ParseIP("239.129.1.2"))
+	if err != nil || mac.String() != "01:00:5e:01:01:02" {
+		t.Errorf("unexpected MAC %v, %v", mac, err)
+	}
+}
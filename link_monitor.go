@@ -0,0 +1,88 @@
+package govrrp
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// SetLinkMonitorInterval 设置 检查工作网口链路状态的时间间隔，需在 Start 前调用，小于等于 0 表示不检查（默认不检查）。
+// 工作网口失去 FlagUp 或 FlagRunning 标志（如网线断开、网口被关闭）时，状态机进入 INIT 状态（触发 Master2Init 或 Backup2Init），
+// 停止发送心跳消息与主节点下线倒计时；链路恢复后重新由 INIT 状态启动，地址拥有者直接成为主节点，其余进入 BACKUP 状态。
+// 检查随虚拟路由器停止而结束。
+func (r *VirtualRouter) SetLinkMonitorInterval(interval time.Duration) *VirtualRouter {
+	r.linkMonitorInterval = interval
+	return r
+}
+
+// IsLinkDown 是否因工作网口链路断开处于 INIT 状态
+func (r *VirtualRouter) IsLinkDown() bool {
+	return r.linkDown.Load()
+}
+
+// linkUp 网口是否处于链路可用状态
+func linkUp(ift *net.Interface) bool {
+	return ift.Flags&net.FlagUp != 0 && ift.Flags&net.FlagRunning != 0
+}
+
+// linkMonitor 周期性检查工作网口链路状态，状态变化时通知状态机，状态机退出后结束
+// exited: 状态机退出通道，重新启动时通道会被替换，因此由启动方传入
+func (r *VirtualRouter) linkMonitor(exited <-chan struct{}) {
+	ticker := time.NewTicker(r.linkMonitorInterval)
+	defer ticker.Stop()
+	up := true
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+		ift, err := r.lookupInterface()
+		if err != nil {
+			r.debugf("lookup interface %s: %v", r.ift.Name, err)
+		}
+		// 网口不存在时同样视为链路断开
+		current := err == nil && linkUp(ift)
+		if current == up {
+			continue
+		}
+		up = current
+		event := LINKDOWN
+		if up {
+			event = LINKUP
+		}
+		r.logger().Printf("VRID [%d] interface %s %v detected", r.vrID, r.ift.Name, event)
+		select {
+		case r.eventChannel <- event:
+		case <-exited:
+			return
+		}
+	}
+}
+
+// handleLinkDown 在状态机协程中处理工作网口链路断开，由 MASTER 或 BACKUP 状态进入 INIT 状态。
+// 链路已断开，主节点无法发出优先级为 0 的心跳消息，因此直接停止发送。
+func (r *VirtualRouter) handleLinkDown() {
+	r.logger().Printf("VRID [%d] link down, reset to INIT state", r.vrID)
+	// 先于状态切换标记，使接收协程在 INIT 状态下继续运行
+	r.linkDown.Store(true)
+	r.paused.Store(false)
+	if atomic.LoadUint32(&r.state) == MASTER {
+		r.stopAdvertTicker()
+		atomic.StoreUint32(&r.state, INIT)
+		r.stateChanged(Master2Init)
+		r.mastershipLost(MastershipLostLinkDown, nil)
+		return
+	}
+	r.stopMasterDownTimer()
+	r.preemptDeadline.Store(0)
+	atomic.StoreUint32(&r.state, INIT)
+	r.stateChanged(Backup2Init)
+}
+
+// handleLinkUp 在状态机协程中处理工作网口链路恢复，重新由 INIT 状态启动，接收协程在链路断开期间未退出，无需重新启动
+func (r *VirtualRouter) handleLinkUp() {
+	r.logger().Printf("VRID [%d] link up, restart from INIT state", r.vrID)
+	r.leaveInit()
+	r.linkDown.Store(false)
+}
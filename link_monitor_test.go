@@ -0,0 +1,127 @@
+package govrrp
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_LinkMonitor(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	var up atomic.Bool
+	up.Store(true)
+	vr.lookupInterface = func() (*net.Interface, error) {
+		ift := &net.Interface{Index: 1, Name: "mem0", HardwareAddr: vr.GetInterface().HardwareAddr}
+		if up.Load() {
+			ift.Flags = net.FlagUp | net.FlagRunning
+		} else {
+			ift.Flags = net.FlagUp
+		}
+		return ift, nil
+	}
+	toInit := make(chan transition, 4)
+	vr.AddEventListener(Master2Init, func(*VirtualRouter) { toInit <- Master2Init })
+	vr.AddEventListener(Backup2Init, func(*VirtualRouter) { toInit <- Backup2Init })
+	vr.SetLinkMonitorInterval(testInterval / 2)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	// 链路断开（失去 FlagRunning），主节点进入 INIT 状态并停止发送心跳消息
+	up.Store(false)
+	select {
+	case tr := <-toInit:
+		if tr != Master2Init {
+			t.Errorf("expect Master2Init, got %v", tr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("link down should reset the master to INIT")
+	}
+	if vr.GetState() != INIT || !vr.IsLinkDown() {
+		t.Fatalf("expect INIT on link down, got %s", StateName(vr.GetState()))
+	}
+	if reason, _ := vr.GetMastershipLost(); reason != MastershipLostLinkDown {
+		t.Errorf("expect %s, got %s", MastershipLostLinkDown, reason)
+	}
+	sent := len(conn.sentPackets())
+	time.Sleep(5 * testInterval)
+	if n := len(conn.sentPackets()); n != sent {
+		t.Errorf("expect no advertisement while link is down, got %d more", n-sent)
+	}
+
+	// 链路恢复后重新参与选举，接收协程仍在运行
+	up.Store(true)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should recover after link up")
+	}
+	if vr.IsLinkDown() {
+		t.Error("link down flag should be cleared")
+	}
+	conn.deliver(newAdvertisement(240, 200, "192.168.0.20"), nil)
+	if !waitState(vr, BACKUP, time.Second) {
+		t.Fatal("advertisements should be processed after link up")
+	}
+
+	up.Store(false)
+	select {
+	case tr := <-toInit:
+		if tr != Backup2Init {
+			t.Errorf("expect Backup2Init, got %v", tr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("link down should reset the backup to INIT")
+	}
+}
+
+func TestVirtualRouter_LinkDownWatchdog(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	vr.SetAdvInterval(testInterval)
+	vr.SetPriorityAndMasterAdvInterval(255, testInterval)
+	var up atomic.Bool
+	up.Store(true)
+	vr.lookupInterface = func() (*net.Interface, error) {
+		ift := &net.Interface{Index: 1, Name: "mem0", HardwareAddr: vr.GetInterface().HardwareAddr}
+		if up.Load() {
+			ift.Flags = net.FlagUp | net.FlagRunning
+		} else {
+			ift.Flags = net.FlagUp
+		}
+		return ift, nil
+	}
+	vr.SetLinkMonitorInterval(testInterval / 2)
+	vr.SetWatchdog(5*testInterval, true)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	// 链路断开时间超过看门狗停滞时间，停留在 INIT 状态不应视为停滞
+	up.Store(false)
+	deadline := time.Now().Add(time.Second)
+	for !vr.IsLinkDown() && time.Now().Before(deadline) {
+		time.Sleep(testInterval / 4)
+	}
+	if !vr.IsLinkDown() {
+		t.Fatal("link down should be detected")
+	}
+	time.Sleep(15 * testInterval)
+	select {
+	case err := <-vr.Errors():
+		t.Fatalf("unexpected error while link is down: %v", err)
+	case <-conn.done:
+		t.Fatal("watchdog should not close the connection while link is down")
+	default:
+	}
+
+	up.Store(true)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should recover after link up")
+	}
+	sent := len(conn.sentPackets())
+	time.Sleep(3 * testInterval)
+	if len(conn.sentPackets()) == sent {
+		t.Error("master should keep sending advertisements after link up")
+	}
+}
@@ -0,0 +1,80 @@
+package govrrp
+
+import (
+	"bytes"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// SetMACCheckInterval 设置 检查工作网口MAC地址变化的时间间隔，需在 Start 前调用，小于等于 0 表示不检查（默认不检查）。
+// 绑定网卡（bond）切换主网卡时MAC地址可能变化，此时 ARP/NDP 广播中缓存的MAC地址失效，
+// 检测到变化后更新广播器的发送地址，若当前为主节点则立即重新广播虚拟IP地址。
+func (r *VirtualRouter) SetMACCheckInterval(interval time.Duration) *VirtualRouter {
+	r.macCheckInterval = interval
+	return r
+}
+
+// interfaceMAC 获取 工作网口当前的MAC地址
+func (r *VirtualRouter) interfaceMAC() net.HardwareAddr {
+	if mac := r.hwAddr.Load(); mac != nil {
+		return *mac
+	}
+	return nil
+}
+
+// refreshMAC 查询工作网口的当前MAC地址，发生变化时更新并通知状态机，返回是否发生变化
+func (r *VirtualRouter) refreshMAC() bool {
+	ift, err := r.lookupInterface()
+	if err != nil {
+		r.logger().Printf("ERROR VRID [%d] lookup interface %s: %v", r.vrID, r.ift.Name, err)
+		return false
+	}
+	mac := ift.HardwareAddr
+	old := r.interfaceMAC()
+	if bytes.Equal(mac, old) {
+		return false
+	}
+	r.hwAddr.Store(&mac)
+	r.logger().Printf("VRID [%d] interface %s MAC changed from %v to %v", r.vrID, r.ift.Name, old, mac)
+	select {
+	case r.macChanged <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// macMonitor 周期性检查工作网口MAC地址是否变化，状态机退出后结束
+// exited: 状态机退出通道，重新启动时通道会被替换，因此由启动方传入
+func (r *VirtualRouter) macMonitor(exited <-chan struct{}) {
+	ticker := time.NewTicker(r.macCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+			r.refreshMAC()
+		}
+	}
+}
+
+// handleMACChange 在状态机协程中处理工作网口MAC地址变化：
+// 重新绑定广播器使其使用新的MAC地址发送，当前为主节点时重新广播虚拟IP地址
+func (r *VirtualRouter) handleMACChange() {
+	if a, ok := r.addrAnnouncer.(interface{ Rebind(*net.Interface) error }); ok {
+		ift := *r.ift
+		ift.HardwareAddr = r.interfaceMAC()
+		if err := a.Rebind(&ift); err != nil {
+			r.logger().Printf("ERROR VRID [%d] rebind announcer: %v", r.vrID, err)
+			r.reportError(ErrorOpAnnounce, err)
+		}
+	}
+	if atomic.LoadUint32(&r.state) != MASTER {
+		return
+	}
+	if err := r.addrAnnouncer.AnnounceAll(r); err != nil {
+		r.logger().Printf("ERROR VRID [%d] re-announce after MAC change: %v", r.vrID, err)
+		r.reportError(ErrorOpAnnounce, err)
+	}
+}
@@ -0,0 +1,53 @@
+package govrrp
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_MACChange(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	announcer := vr.addrAnnouncer.(*fakeAnnouncer)
+	newMAC := net.HardwareAddr{0x02, 0, 0, 0, 0, 2}
+	var mu sync.Mutex
+	current := vr.GetInterface().HardwareAddr
+	vr.lookupInterface = func() (*net.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return &net.Interface{Index: 1, Name: "mem0", HardwareAddr: current}, nil
+	}
+	vr.SetMACCheckInterval(testInterval)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("owner should become master")
+	}
+	time.Sleep(3 * testInterval)
+	announcer.mu.Lock()
+	announced := announcer.count
+	announcer.mu.Unlock()
+
+	mu.Lock()
+	current = newMAC
+	mu.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for {
+		announcer.mu.Lock()
+		macs := append([]net.HardwareAddr(nil), announcer.macs...)
+		announcer.mu.Unlock()
+		if len(macs) > announced {
+			if last := macs[len(macs)-1]; last.String() != newMAC.String() {
+				t.Fatalf("expect re-announce with %v, got %v", newMAC, last)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expect re-announce after MAC change")
+		}
+		time.Sleep(testInterval)
+	}
+	if mac := vr.GetEffectiveMAC(); mac.String() != newMAC.String() {
+		t.Errorf("expect effective MAC %v, got %v", newMAC, mac)
+	}
+}
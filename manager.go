@@ -0,0 +1,110 @@
+package govrrp
+
+import (
+	"net"
+	"sync"
+)
+
+// VirtualRouterManager 虚拟路由器管理器，同一网口上的多个虚拟路由器共享一个虚拟IP地址广播器
+// （每个网口每种IP协议类型一个 ARP/NDP 客户端），减少文件描述符的占用。
+type VirtualRouterManager struct {
+	mu         sync.Mutex
+	announcers map[announcerKey]*sharedAnnouncer
+	// newAnnouncer 创建网口的虚拟IP地址广播器，便于测试替换
+	newAnnouncer func(*net.Interface, byte) (AddrAnnouncer, error)
+}
+
+// announcerKey 共享广播器的索引：网口序号与IP协议类型
+type announcerKey struct {
+	ifIndex int
+	ipvX    byte
+}
+
+// NewVirtualRouterManager 创建虚拟路由器管理器
+func NewVirtualRouterManager() *VirtualRouterManager {
+	return &VirtualRouterManager{
+		announcers:   make(map[announcerKey]*sharedAnnouncer),
+		newAnnouncer: newAddrAnnouncer,
+	}
+}
+
+// NewVirtualRouter 创建虚拟路由器，参数同 NewVirtualRouterSpec，
+// 虚拟IP地址广播器与同一网口上由本管理器创建的其他虚拟路由器共享。
+func (m *VirtualRouterManager) NewVirtualRouter(VRID byte, ift *net.Interface, preferIP net.IP, priority byte) (*VirtualRouter, error) {
+	return newVirtualRouterSpec(VRID, ift, preferIP, priority, m.Announcer)
+}
+
+// Announcer 获取 网口上指定IP协议类型的共享虚拟IP地址广播器，不存在时创建。
+// 每次调用返回独立的句柄，所有句柄关闭后底层广播器才会关闭。
+// 返回的句柄可用于 NewVirtualRouterWithConn，AnnounceAll 仍仅广播传入的虚拟路由器的虚拟IP地址。
+func (m *VirtualRouterManager) Announcer(ift *net.Interface, ipvX byte) (AddrAnnouncer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := announcerKey{ifIndex: ift.Index, ipvX: ipvX}
+	shared, ok := m.announcers[key]
+	if !ok {
+		announcer, err := m.newAnnouncer(ift, ipvX)
+		if err != nil {
+			return nil, err
+		}
+		shared = &sharedAnnouncer{announcer: announcer}
+		m.announcers[key] = shared
+	}
+	shared.refs++
+	return &sharedAnnouncerHandle{manager: m, key: key, shared: shared}, nil
+}
+
+// release 释放共享广播器的一个引用，引用全部释放后关闭底层广播器
+func (m *VirtualRouterManager) release(key announcerKey, shared *sharedAnnouncer) error {
+	m.mu.Lock()
+	shared.refs--
+	last := shared.refs == 0
+	if last && m.announcers[key] == shared {
+		delete(m.announcers, key)
+	}
+	m.mu.Unlock()
+	if !last {
+		return nil
+	}
+	return shared.announcer.Close()
+}
+
+// sharedAnnouncer 多个虚拟路由器共享的虚拟IP地址广播器
+type sharedAnnouncer struct {
+	mu        sync.Mutex // 串行化各虚拟路由器的广播
+	announcer AddrAnnouncer
+	refs      int // 未关闭的句柄数量，由管理器的锁保护
+}
+
+// sharedAnnouncerHandle 共享广播器的句柄，关闭时仅释放引用
+type sharedAnnouncerHandle struct {
+	manager   *VirtualRouterManager
+	key       announcerKey
+	shared    *sharedAnnouncer
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// AnnounceAll 广播 虚拟路由器的所有虚拟IP地址
+func (h *sharedAnnouncerHandle) AnnounceAll(vr *VirtualRouter) error {
+	h.shared.mu.Lock()
+	defer h.shared.mu.Unlock()
+	return h.shared.announcer.AnnounceAll(vr)
+}
+
+// Rebind 使用网口的当前信息重新创建底层广播器的客户端，底层广播器不支持时为空操作
+func (h *sharedAnnouncerHandle) Rebind(nif *net.Interface) error {
+	h.shared.mu.Lock()
+	defer h.shared.mu.Unlock()
+	if a, ok := h.shared.announcer.(interface{ Rebind(*net.Interface) error }); ok {
+		return a.Rebind(nif)
+	}
+	return nil
+}
+
+func (h *sharedAnnouncerHandle) Close() error {
+	h.closeOnce.Do(func() {
+		h.closeErr = h.manager.release(h.key, h.shared)
+	})
+	return h.closeErr
+}
@@ -0,0 +1,115 @@
+package govrrp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// recordingAnnouncer 记录每次广播的虚拟IP地址以及关闭次数的 AddrAnnouncer
+type recordingAnnouncer struct {
+	mu     sync.Mutex
+	vips   map[byte][]string // VRID -> 最近一次广播的虚拟IP地址
+	closed int
+}
+
+func (a *recordingAnnouncer) AnnounceAll(vr *VirtualRouter) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var vips []string
+	for _, vip := range vr.vipAddrs() {
+		vips = append(vips, vip.String())
+	}
+	sort.Strings(vips)
+	a.vips[vr.vrID] = vips
+	return nil
+}
+
+func (a *recordingAnnouncer) Close() error {
+	a.mu.Lock()
+	a.closed++
+	a.mu.Unlock()
+	return nil
+}
+
+func TestVirtualRouterManager_SharedAnnouncer(t *testing.T) {
+	m := NewVirtualRouterManager()
+	var created []*recordingAnnouncer
+	m.newAnnouncer = func(*net.Interface, byte) (AddrAnnouncer, error) {
+		a := &recordingAnnouncer{vips: make(map[byte][]string)}
+		created = append(created, a)
+		return a, nil
+	}
+
+	network := &memNetwork{}
+	ift := &net.Interface{Index: 1, Name: "mem0"}
+	newRouter := func(VRID byte, src string, vips ...string) *VirtualRouter {
+		announcer, err := m.Announcer(ift, IPv4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip := net.ParseIP(src).To4()
+		vr, err := NewVirtualRouterWithConn(VRID, network.dial(ip), announcer, ip, 100, IPv4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, vip := range vips {
+			vr.AddIPvXAddr(net.ParseIP(vip))
+		}
+		return vr
+	}
+	vr1 := newRouter(1, "192.168.0.10", "192.168.0.100", "192.168.0.101")
+	vr2 := newRouter(2, "192.168.0.10", "192.168.0.200")
+
+	if len(created) != 1 {
+		t.Fatalf("created %d announcers for one interface, want 1", len(created))
+	}
+	// 不同IP协议类型使用独立的广播器
+	v6, err := m.Announcer(ift, IPv6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("created %d announcers, want a separate IPv6 announcer", len(created))
+	}
+	_ = v6.Close()
+
+	for _, vr := range []*VirtualRouter{vr1, vr2} {
+		if err = vr.addrAnnouncer.AnnounceAll(vr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shared := created[0]
+	want := map[byte][]string{
+		1: {"192.168.0.100", "192.168.0.101"},
+		2: {"192.168.0.200"},
+	}
+	for VRID, vips := range want {
+		if got := shared.vips[VRID]; fmt.Sprint(got) != fmt.Sprint(vips) {
+			t.Errorf("VRID %d announced %v, want %v", VRID, got, vips)
+		}
+	}
+
+	// 仅当所有使用者关闭后才关闭底层广播器
+	vr1.close()
+	vr1.close()
+	if shared.closed != 0 {
+		t.Fatalf("shared announcer closed while still in use")
+	}
+	vr2.close()
+	if shared.closed != 1 {
+		t.Fatalf("shared announcer closed %d times, want 1", shared.closed)
+	}
+
+	// 全部释放后再次获取时重新创建
+	a, err := m.Announcer(ift, IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if len(created) != 3 {
+		t.Errorf("created %d announcers, want a new one after release", len(created))
+	}
+}
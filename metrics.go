@@ -0,0 +1,69 @@
+package govrrp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// metricDesc 指标描述
+type metricDesc struct {
+	name  string                       // 指标名称，计数器不含 _total 后缀
+	help  string                       // 指标说明
+	typ   string                       // 指标类型 counter | gauge
+	value func(*VirtualRouter) float64 // 获取指标值
+}
+
+// metricDescs 导出的虚拟路由器指标
+var metricDescs = []metricDesc{
+	{"govrrp_state", "Virtual router state (0 INIT, 1 MASTER, 2 BACKUP).", "gauge",
+		func(r *VirtualRouter) float64 { return float64(r.GetState()) }},
+	{"govrrp_priority", "Configured priority.", "gauge",
+		func(r *VirtualRouter) float64 { return float64(r.GetPriority()) }},
+	{"govrrp_effective_priority", "Advertised priority after track reductions.", "gauge",
+		func(r *VirtualRouter) float64 { return float64(r.GetEffectivePriority()) }},
+	{"govrrp_master_down_interval_seconds", "Master down interval.", "gauge",
+		func(r *VirtualRouter) float64 { return r.GetMasterDownInterval().Seconds() }},
+	{"govrrp_advertisements_received", "Advertisements received for this virtual router.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.received.Load()) }},
+	{"govrrp_advertisements_sent", "Advertisements sent successfully.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.advertsSent.Load()) }},
+	{"govrrp_master_down_expirations", "Master down timer expirations in BACKUP state.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.masterDownExpired.Load()) }},
+	{"govrrp_unexpected_type", "Received packets with a type other than ADVERTISEMENT.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.unexpectedType.Load()) }},
+	{"govrrp_owner_conflicts", "Advertisements with priority 255 received while being the address owner.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.ownerConflict.Load()) }},
+	{"govrrp_send_errors", "Advertisements that failed to send.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.sendErrors.Load()) }},
+	{"govrrp_foreign_interface", "Advertisements dropped for arriving on another interface.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.foreignIfIndex.Load()) }},
+	{"govrrp_zero_addr", "Advertisements dropped for carrying no addresses.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.zeroAddr.Load()) }},
+	{"govrrp_suspicious_priority", "Advertisements with priority 255 from a source that is not a known owner.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.suspiciousPriority.Load()) }},
+	{"govrrp_truncated_advertisements", "Advertisements dropped for being shorter than their declared address count.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.truncatedAdvert.Load()) }},
+	{"govrrp_delayed_advertisements", "Advertisement ticks that fired later than the configured tolerance.", "counter",
+		func(r *VirtualRouter) float64 { return float64(r.stats.delayedAdverts.Load()) }},
+}
+
+// WriteOpenMetrics 以 OpenMetrics 文本格式输出一组虚拟路由器的指标，无需依赖 Prometheus 客户端库。
+// 每个虚拟路由器以 vrid、interface、family 标签区分，输出可直接作为 HTTP 抓取接口的响应内容
+// （Content-Type: application/openmetrics-text; version=1.0.0; charset=utf-8）。
+func WriteOpenMetrics(w io.Writer, routers ...*VirtualRouter) error {
+	bw := bufio.NewWriter(w)
+	for _, desc := range metricDescs {
+		fmt.Fprintf(bw, "# HELP %s %s\n", desc.name, desc.help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", desc.name, desc.typ)
+		sample := desc.name
+		if desc.typ == "counter" {
+			sample += "_total"
+		}
+		for _, r := range routers {
+			fmt.Fprintf(bw, "%s{vrid=\"%d\",interface=%q,family=\"%s\"} %v\n", sample, r.vrID, r.ift.Name, r.familyName(), desc.value(r))
+		}
+	}
+	fmt.Fprint(bw, "# EOF\n")
+	return bw.Flush()
+}
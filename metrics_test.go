@@ -0,0 +1,57 @@
+package govrrp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.stats.received.Add(7)
+	v6, _ := newTestRouter(t, nil, 241, "fe80::10", 255)
+
+	var buf bytes.Buffer
+	if err := WriteOpenMetrics(&buf, vr, v6); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		"# HELP govrrp_state ",
+		"# TYPE govrrp_state gauge\n",
+		"# TYPE govrrp_advertisements_received counter\n",
+		"# TYPE govrrp_send_errors counter\n",
+		`govrrp_advertisements_received_total{vrid="240",interface="mem0",family="ipv4"} 7` + "\n",
+		`govrrp_priority{vrid="241",interface="mem0",family="ipv6"} 255` + "\n",
+		`govrrp_state{vrid="240",interface="mem0",family="ipv4"} 0` + "\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expect output to contain %q", line)
+		}
+	}
+	if !strings.HasSuffix(out, "# EOF\n") {
+		t.Error("expect output to end with # EOF")
+	}
+}
+
+func TestVirtualRouter_GetTransitionCounts(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	time.Sleep(3 * testInterval)
+
+	counts := vr.GetTransitionCounts()
+	if counts[Init2Backup.String()] != 1 || counts[Backup2Master.String()] != 1 || len(counts) != 2 {
+		t.Errorf("unexpected transition counts %v", counts)
+	}
+	stats := vr.GetStatistics()
+	if stats.MasterDownExpired != 1 {
+		t.Errorf("expect 1 master down expiration, got %d", stats.MasterDownExpired)
+	}
+	if stats.AdvertsSent == 0 {
+		t.Error("expect advertisements sent as master")
+	}
+}
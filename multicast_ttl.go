@@ -0,0 +1,45 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMulticastTTLUnsupported 当前连接不支持查询或设置组播TTL
+var ErrMulticastTTLUnsupported = errors.New("multicast TTL is not supported")
+
+// multicastTTLConn 支持查询与设置组播TTL的连接
+type multicastTTLConn interface {
+	SetMulticastTTL(ttl int) error
+	MulticastTTL() (int, error)
+}
+
+// SetMulticastTTL 设置 发送VRRP消息使用的组播TTL（IPv4）或跳数限制（IPv6 Hop Limit），取值范围 1~255。
+// RFC 5798 5.1.1.3 要求TTL为255，接收方会丢弃TTL不为255的消息，仅在特殊的测试或中继场景下修改。
+func (r *VirtualRouter) SetMulticastTTL(ttl int) error {
+	c, ok := r.vrrpConn.(multicastTTLConn)
+	if !ok {
+		return fmt.Errorf("VRID [%d] %w", r.vrID, ErrMulticastTTLUnsupported)
+	}
+	if err := c.SetMulticastTTL(ttl); err != nil {
+		return fmt.Errorf("VRID [%d] set multicast TTL %d: %w", r.vrID, ttl, err)
+	}
+	if ttl != 255 {
+		r.logger().Printf("WARN VRID [%d] multicast TTL set to %d, peers expecting 255 may drop advertisements", r.vrID, ttl)
+	}
+	r.connOpts.ttl = ttl
+	return nil
+}
+
+// GetMulticastTTL 获取 连接当前发送VRRP消息使用的组播TTL（IPv4）或跳数限制（IPv6 Hop Limit），默认为255
+func (r *VirtualRouter) GetMulticastTTL() (int, error) {
+	c, ok := r.vrrpConn.(multicastTTLConn)
+	if !ok {
+		return 0, fmt.Errorf("VRID [%d] %w", r.vrID, ErrMulticastTTLUnsupported)
+	}
+	ttl, err := c.MulticastTTL()
+	if err != nil {
+		return 0, fmt.Errorf("VRID [%d] get multicast TTL: %w", r.vrID, err)
+	}
+	return ttl, nil
+}
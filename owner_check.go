@@ -0,0 +1,66 @@
+package govrrp
+
+import (
+	"net"
+	"net/netip"
+)
+
+// SetKnownOwners 设置 已知的地址拥有者源IP地址，用于识别可疑的优先级 255 心跳消息。
+// 除此之外，源IP地址为本虚拟路由器的虚拟IP地址之一的路由器同样视为地址拥有者（RFC 5798 中拥有者的实际地址即虚拟IP地址）。
+func (r *VirtualRouter) SetKnownOwners(ips ...net.IP) *VirtualRouter {
+	owners := make([]netip.Addr, 0, len(ips))
+	for _, ip := range ips {
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			owners = append(owners, addr.Unmap())
+		}
+	}
+	r.knownOwners = owners
+	return r
+}
+
+// OnSuspiciousPriority 设置 收到非已知地址拥有者发出的优先级 255 心跳消息时的回调函数。
+// 按照 RFC 5798，备份路由器总是让渡于优先级为 255 的路由器，配置错误的非拥有者宣告 255 将导致其始终成为主节点。
+// 默认仅记录（见 Statistics.SuspiciousPriority）与回调，不改变选举行为，可通过 SetRejectSuspiciousPriority 丢弃此类消息。
+// 回调函数在接收协程中同步调用，请勿在其中执行耗时操作。
+func (r *VirtualRouter) OnSuspiciousPriority(handler func(src net.IP)) *VirtualRouter {
+	r.suspiciousPriorityHandler = handler
+	return r
+}
+
+// SetRejectSuspiciousPriority 设置 是否丢弃非已知地址拥有者发出的优先级 255 心跳消息，默认值为 false。
+// 开启后偏离 RFC 5798，请确保已通过 SetKnownOwners 或虚拟IP地址覆盖所有合法的地址拥有者。
+func (r *VirtualRouter) SetRejectSuspiciousPriority(flag bool) *VirtualRouter {
+	r.rejectSuspiciousPriority = flag
+	return r
+}
+
+// isKnownOwner 源IP地址是否为已知的地址拥有者
+func (r *VirtualRouter) isKnownOwner(src net.IP) bool {
+	addr, ok := netip.AddrFromSlice(src)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, owner := range r.knownOwners {
+		if owner == addr {
+			return true
+		}
+	}
+	r.vipMu.RLock()
+	_, ok = r.protectedIPaddrs[addr]
+	r.vipMu.RUnlock()
+	return ok
+}
+
+// checkSuspiciousPriority 检查心跳消息是否为非已知地址拥有者发出的优先级 255 消息，返回 true 表示应丢弃该消息
+func (r *VirtualRouter) checkSuspiciousPriority(packet *VRRPPacket) bool {
+	if packet.GetPriority() != 255 || r.isKnownOwner(packet.Pshdr.Saddr) {
+		return false
+	}
+	r.stats.suspiciousPriority.Add(1)
+	r.debugf("%s advertises priority 255 but is not a known address owner", packet.Pshdr.Saddr)
+	if r.suspiciousPriorityHandler != nil {
+		r.suspiciousPriorityHandler(packet.Pshdr.Saddr)
+	}
+	return r.rejectSuspiciousPriority
+}
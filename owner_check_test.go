@@ -0,0 +1,41 @@
+package govrrp
+
+import (
+	"net"
+	"testing"
+)
+
+func TestVirtualRouter_OnSuspiciousPriority(t *testing.T) {
+	for _, reject := range []bool{false, true} {
+		vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+		vr.state = BACKUP
+		vr.AddIPvXAddr(net.ParseIP("192.168.0.100"))
+		vr.SetKnownOwners(net.ParseIP("192.168.0.1"))
+		vr.SetRejectSuspiciousPriority(reject)
+		var suspicious []string
+		vr.OnSuspiciousPriority(func(src net.IP) {
+			suspicious = append(suspicious, src.String())
+		})
+
+		conn.deliver(newAdvertisement(240, 255, "192.168.0.1"), nil)   // 已知拥有者
+		conn.deliver(newAdvertisement(240, 255, "192.168.0.100"), nil) // 实际地址为虚拟IP地址的拥有者
+		conn.deliver(newAdvertisement(240, 255, "192.168.0.66"), nil)  // 非拥有者宣告 255
+		conn.deliver(newAdvertisement(240, 200, "192.168.0.77"), nil)
+		_ = conn.Close()
+
+		vr.fetchVRRPDaemon(vr.vrrpConn)
+		if len(suspicious) != 1 || suspicious[0] != "192.168.0.66" {
+			t.Errorf("reject=%v: expect callback for 192.168.0.66, got %v", reject, suspicious)
+		}
+		if n := vr.GetStatistics().SuspiciousPriority; n != 1 {
+			t.Errorf("reject=%v: expect 1 suspicious advertisement counted, got %d", reject, n)
+		}
+		accepted := 4
+		if reject {
+			accepted = 3
+		}
+		if n := len(vr.packetQueue); n != accepted {
+			t.Errorf("reject=%v: expect %d packets accepted, got %d", reject, accepted, n)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package pcap 从 tcpdump 等工具抓取的 pcap 文件中提取 VRRP Advertisement 消息，用于离线分析主备切换问题。
+//
+// 仅支持经典 pcap 格式（非 pcapng），链路类型支持 Ethernet、Linux cooked capture (SLL) 以及 Raw IP。
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/Trisia/govrrp"
+	"io"
+	"net"
+	"os"
+)
+
+// pcap 文件链路类型
+const (
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+)
+
+// 以太网帧类型
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86DD
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88A8
+)
+
+// maxSnapLen 记录长度上限，与 tcpdump 支持的最大抓包长度一致，
+// 全局头部中的 snaplen 为 0 或超过该值时以该值为准
+const maxSnapLen = 262144
+
+// ParsePcapFile 解析 pcap 文件，返回其中指定IP协议类型的 VRRP Advertisement 消息
+// path: pcap 文件路径
+// family: IP协议类型 govrrp.IPv4 或 govrrp.IPv6
+//
+// 解析失败或校验和错误的报文将被忽略，返回的报文均已设置伪头部。
+func ParsePcapFile(path string, family byte) ([]*govrrp.VRRPPacket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ParsePcapFile: %v", err)
+	}
+	defer f.Close()
+	return ParsePcap(f, family)
+}
+
+// ParsePcap 从 r 中读取 pcap 格式的数据，返回其中指定IP协议类型的 VRRP Advertisement 消息
+func ParsePcap(r io.Reader, family byte) ([]*govrrp.VRRPPacket, error) {
+	if family != govrrp.IPv4 && family != govrrp.IPv6 {
+		return nil, fmt.Errorf("ParsePcap: faulty IPvX version %d", family)
+	}
+	var gh [24]byte
+	if _, err := io.ReadFull(r, gh[:]); err != nil {
+		return nil, fmt.Errorf("ParsePcap: read global header: %v", err)
+	}
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(gh[:4]) {
+	case 0xa1b2c3d4, 0xa1b23c4d:
+		order = binary.LittleEndian
+	case 0xd4c3b2a1, 0x4d3cb2a1:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("ParsePcap: not a pcap file")
+	}
+	linkType := order.Uint32(gh[20:24])
+	snapLen := order.Uint32(gh[16:20])
+	if snapLen == 0 || snapLen > maxSnapLen {
+		snapLen = maxSnapLen
+	}
+
+	var packets []*govrrp.VRRPPacket
+	var rh [16]byte
+	for {
+		if _, err := io.ReadFull(r, rh[:]); err != nil {
+			if err == io.EOF {
+				return packets, nil
+			}
+			return nil, fmt.Errorf("ParsePcap: read record header: %v", err)
+		}
+		// 记录长度来自文件，超过 snaplen 说明文件已损坏，拒绝分配
+		inclLen := order.Uint32(rh[8:12])
+		if inclLen > snapLen {
+			return nil, fmt.Errorf("ParsePcap: record length %d exceeds snaplen %d", inclLen, snapLen)
+		}
+		frame := make([]byte, inclLen)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, fmt.Errorf("ParsePcap: read record: %v", err)
+		}
+		datagram, ok := ipDatagram(linkType, frame)
+		if !ok {
+			continue
+		}
+		if packet := parseVRRP(family, datagram); packet != nil {
+			packets = append(packets, packet)
+		}
+	}
+}
+
+// ipDatagram 剥离链路层头部，返回IP数据报
+func ipDatagram(linkType uint32, frame []byte) ([]byte, bool) {
+	var etherType uint16
+	switch linkType {
+	case linkTypeRaw:
+		return frame, true
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return nil, false
+		}
+		etherType = binary.BigEndian.Uint16(frame[12:14])
+		frame = frame[14:]
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return nil, false
+		}
+		etherType = binary.BigEndian.Uint16(frame[14:16])
+		frame = frame[16:]
+	default:
+		return nil, false
+	}
+	// 跳过 VLAN 标签
+	for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+		if len(frame) < 4 {
+			return nil, false
+		}
+		etherType = binary.BigEndian.Uint16(frame[2:4])
+		frame = frame[4:]
+	}
+	if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+		return nil, false
+	}
+	return frame, true
+}
+
+// parseVRRP 从IP数据报中解析 VRRP 报文，并根据IP头部重建伪头部验证校验和
+func parseVRRP(family byte, datagram []byte) *govrrp.VRRPPacket {
+	if len(datagram) < 1 || datagram[0]>>4 != family {
+		return nil
+	}
+	var src, dst net.IP
+	var payload []byte
+	switch family {
+	case govrrp.IPv4:
+		if len(datagram) < 20 {
+			return nil
+		}
+		ihl := int(datagram[0]&0x0F) * 4
+		total := int(binary.BigEndian.Uint16(datagram[2:4]))
+		if datagram[9] != govrrp.VRRPIPProtocolNumber || ihl < 20 || total < ihl || total > len(datagram) {
+			return nil
+		}
+		src, dst = net.IP(datagram[12:16]), net.IP(datagram[16:20])
+		// 以 IP 头部中的总长度为准，忽略以太网帧的填充
+		payload = datagram[ihl:total]
+	case govrrp.IPv6:
+		if len(datagram) < 40 {
+			return nil
+		}
+		plen := int(binary.BigEndian.Uint16(datagram[4:6]))
+		if datagram[6] != govrrp.VRRPIPProtocolNumber || 40+plen > len(datagram) {
+			return nil
+		}
+		src, dst = net.IP(datagram[8:24]), net.IP(datagram[24:40])
+		payload = datagram[40 : 40+plen]
+	}
+
+	packet, err := govrrp.FromBytes(family, payload)
+	if err != nil {
+		return nil
+	}
+	pshdr := &govrrp.PseudoHeader{
+		Saddr:    append(net.IP(nil), src...),
+		Daddr:    append(net.IP(nil), dst...),
+		Protocol: govrrp.VRRPIPProtocolNumber,
+		Len:      uint16(packet.PacketSize()),
+	}
+	if !packet.ValidateCheckSum(pshdr) {
+		return nil
+	}
+	packet.Pshdr = pshdr
+	return packet
+}
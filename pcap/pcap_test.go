@@ -0,0 +1,66 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/Trisia/govrrp"
+	"net"
+	"strings"
+	"testing"
+)
+
+// 包含一个 VRRPv3 Advertisement 的 pcap 文件，以太网链路
+// VRID 240 Priority 100 VIP 192.168.0.230，源地址 192.168.0.220
+const onePacketPcap = "d4c3b2a1020004000000000000000000ffff00000100000000f15365000000003c0000003c00000001005e000012000c29aabbcc080045c0002000000000ff700000c0a800dce000001231f0640100640608c0a800e60000000000000000000000000000"
+
+func TestParsePcap(t *testing.T) {
+	raw, _ := hex.DecodeString(onePacketPcap)
+	packets, err := ParsePcap(bytes.NewReader(raw), govrrp.IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 {
+		t.Fatalf("expect 1 advertisement, got %d", len(packets))
+	}
+	p := packets[0]
+	if p.GetVirtualRouterID() != 240 || p.GetPriority() != 100 {
+		t.Errorf("unexpected advertisement %s", p)
+	}
+	if !p.Pshdr.Saddr.Equal(net.IPv4(192, 168, 0, 220)) {
+		t.Errorf("unexpected source %v", p.Pshdr.Saddr)
+	}
+	vips := p.GetIPvXAddr(govrrp.IPv4)
+	if len(vips) != 1 || !vips[0].Equal(net.IPv4(192, 168, 0, 230)) {
+		t.Errorf("unexpected VIPs %v", vips)
+	}
+
+	packets, err = ParsePcap(bytes.NewReader(raw), govrrp.IPv6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 0 {
+		t.Errorf("expect no IPv6 advertisement, got %d", len(packets))
+	}
+}
+
+func TestParsePcap_OversizedRecord(t *testing.T) {
+	raw, _ := hex.DecodeString(onePacketPcap)
+	// 伪造记录头部中的 incl_len，超过全局头部中的 snaplen（65535）
+	forged := append([]byte(nil), raw...)
+	binary.LittleEndian.PutUint32(forged[24+8:24+12], 0xFFFFFFF0)
+	if _, err := ParsePcap(bytes.NewReader(forged), govrrp.IPv4); err == nil || !strings.Contains(err.Error(), "exceeds snaplen") {
+		t.Errorf("expect oversized record rejected, got %v", err)
+	}
+
+	// snaplen 为 0 时以最大抓包长度为准
+	forged = append([]byte(nil), raw...)
+	binary.LittleEndian.PutUint32(forged[16:20], 0)
+	if packets, err := ParsePcap(bytes.NewReader(forged), govrrp.IPv4); err != nil || len(packets) != 1 {
+		t.Errorf("expect 1 advertisement with zero snaplen, got %d %v", len(packets), err)
+	}
+	binary.LittleEndian.PutUint32(forged[24+8:24+12], maxSnapLen+1)
+	if _, err := ParsePcap(bytes.NewReader(forged), govrrp.IPv4); err == nil {
+		t.Error("expect record larger than the maximum snaplen rejected")
+	}
+}
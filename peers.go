@@ -0,0 +1,90 @@
+package govrrp
+
+import (
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultPeerTTL 对端信息默认的过期时间
+const defaultPeerTTL = 30 * time.Second
+
+// PeerInfo 同一虚拟路由ID下观测到的对端路由器
+type PeerInfo struct {
+	Addr        net.IP        // 对端源IP地址
+	Priority    byte          // 最近一次心跳消息中的优先级
+	AdvInterval time.Duration // 最近一次心跳消息中的心跳间隔
+	LastSeen    time.Time     // 最近一次收到心跳消息的时间
+}
+
+// peerTable 对端信息表
+type peerTable struct {
+	mu    sync.Mutex
+	ttl   time.Duration // 对端信息过期时间，0 表示使用 defaultPeerTTL
+	peers map[netip.Addr]PeerInfo
+}
+
+// SetPeerTTL 设置 对端信息的过期时间，超过该时间未收到心跳消息的对端将从 Peers 中移除，默认 30 秒。
+func (r *VirtualRouter) SetPeerTTL(ttl time.Duration) *VirtualRouter {
+	r.peers.mu.Lock()
+	r.peers.ttl = ttl
+	r.peers.mu.Unlock()
+	return r
+}
+
+// Peers 获取 同一虚拟路由ID下最近观测到的对端路由器，按源IP地址排序，
+// 用于确认预期的路由器均在线且状态正常。过期的对端不包含在内。
+func (r *VirtualRouter) Peers() []PeerInfo {
+	t := &r.peers
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(r.now())
+	addrs := make([]netip.Addr, 0, len(t.peers))
+	for addr := range t.peers {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	res := make([]PeerInfo, len(addrs))
+	for i, addr := range addrs {
+		res[i] = t.peers[addr]
+	}
+	return res
+}
+
+// observePeer 记录收到的对端心跳消息
+func (r *VirtualRouter) observePeer(packet *VRRPPacket) {
+	addr, ok := netip.AddrFromSlice(packet.Pshdr.Saddr)
+	if !ok {
+		return
+	}
+	addr = addr.Unmap()
+	now := r.now()
+	t := &r.peers
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.peers == nil {
+		t.peers = make(map[netip.Addr]PeerInfo)
+	}
+	t.prune(now)
+	t.peers[addr] = PeerInfo{
+		Addr:        net.IP(addr.AsSlice()),
+		Priority:    packet.GetPriority(),
+		AdvInterval: centisToDuration(packet.GetAdvertisementInterval()),
+		LastSeen:    now,
+	}
+}
+
+// prune 移除过期的对端信息，调用方需持有锁
+func (t *peerTable) prune(now time.Time) {
+	ttl := t.ttl
+	if ttl <= 0 {
+		ttl = defaultPeerTTL
+	}
+	for addr, info := range t.peers {
+		if now.Sub(info.LastSeen) > ttl {
+			delete(t.peers, addr)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package govrrp
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_Peers(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	now := time.Unix(1000, 0)
+	var mu sync.Mutex
+	current := now
+	vr.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return current
+	}
+	setNow := func(t time.Time) {
+		mu.Lock()
+		current = t
+		mu.Unlock()
+	}
+	vr.SetPeerTTL(time.Second)
+	vr.state = BACKUP
+
+	conn.deliver(newAdvertisement(240, 200, "192.168.0.30"), nil)
+	conn.deliver(newAdvertisement(241, 200, "192.168.0.40"), nil)
+	conn.deliver(newAdvertisement(240, 150, "192.168.0.20"), nil)
+	go vr.fetchVRRPDaemon(vr.vrrpConn)
+	for i := 0; i < 2; i++ {
+		<-vr.packetQueue
+	}
+
+	peers := vr.Peers()
+	if len(peers) != 2 {
+		t.Fatalf("expect 2 peers, got %v", peers)
+	}
+	if peers[0].Addr.String() != "192.168.0.20" || peers[0].Priority != 150 ||
+		peers[1].Addr.String() != "192.168.0.30" || peers[1].Priority != 200 {
+		t.Errorf("unexpected peers %v", peers)
+	}
+	if peers[0].AdvInterval != testInterval || !peers[0].LastSeen.Equal(now) {
+		t.Errorf("unexpected peer info %+v", peers[0])
+	}
+
+	// 仅 192.168.0.30 持续发送心跳消息，192.168.0.20 过期移除
+	setNow(now.Add(800 * time.Millisecond))
+	conn.deliver(newAdvertisement(240, 200, "192.168.0.30"), nil)
+	<-vr.packetQueue
+	setNow(now.Add(1500 * time.Millisecond))
+	peers = vr.Peers()
+	if len(peers) != 1 || peers[0].Addr.String() != "192.168.0.30" {
+		t.Errorf("expect the stale peer aged out, got %v", peers)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,46 @@
+package govrrp
+
+// defaultPreferredMasterBump 首选主节点默认提升的优先级
+const defaultPreferredMasterBump byte = 1
+
+// SetPreferredMaster 设置 是否为首选主节点，默认值为 false。
+// 所有路由器优先级相同时，无需修改优先级即可指定首选的主节点：开启后实际通告的优先级提升 SetPreferredMasterBump 设置的值（默认为 1），
+// 最高为 254，使其在相同基础优先级的对端中确定地胜出。同一虚拟路由器组中只应有一个节点开启该设置，
+// 否则提升相互抵消，仍按源IP地址决定主节点。地址拥有者（优先级 255）不受影响。
+func (r *VirtualRouter) SetPreferredMaster(flag bool) *VirtualRouter {
+	r.preferredMaster = flag
+	r.logger().Printf("VRID [%d] preferred master: %v, effective priority %d", r.vrID, flag, r.effectivePriority())
+	return r
+}
+
+// SetPreferredMasterBump 设置 首选主节点提升的优先级，取值范围 1~253，超出范围时取边界值。
+// 提升值应小于与其他节点的优先级差，避免首选设置凌驾于有意配置的优先级之上。
+func (r *VirtualRouter) SetPreferredMasterBump(bump byte) *VirtualRouter {
+	if bump < 1 {
+		bump = 1
+	} else if bump > 253 {
+		bump = 253
+	}
+	r.preferredMasterBump = bump
+	return r
+}
+
+// GetPreferredMaster 获取 是否为首选主节点
+func (r *VirtualRouter) GetPreferredMaster() bool {
+	return r.preferredMaster
+}
+
+// preferredPriority 首选主节点提升后的优先级，最高为 254
+func (r *VirtualRouter) preferredPriority(priority byte) byte {
+	if !r.preferredMaster {
+		return priority
+	}
+	bump := r.preferredMasterBump
+	if bump == 0 {
+		bump = defaultPreferredMasterBump
+	}
+	if int(priority)+int(bump) > 254 {
+		return 254
+	}
+	return priority + bump
+}
@@ -0,0 +1,40 @@
+package govrrp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_SetPreferredMaster(t *testing.T) {
+	network := &memNetwork{}
+	preferred, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	other, _ := newTestRouter(t, network, 240, "192.168.0.20", 100)
+	preferred.SetPreferredMaster(true)
+	if p := preferred.GetEffectivePriority(); p != 101 {
+		t.Fatalf("expect effective priority 101, got %d", p)
+	}
+
+	// 相同基础优先级下，源IP地址较大的节点先成为主节点，首选主节点启动后应抢占
+	startRouter(t, other)
+	if !waitState(other, MASTER, time.Second) {
+		t.Fatal("the first router should become master")
+	}
+	startRouter(t, preferred)
+	if !waitState(preferred, MASTER, time.Second) {
+		t.Fatalf("the preferred router should win, got %s", StateName(preferred.GetState()))
+	}
+	if !waitState(other, BACKUP, time.Second) {
+		t.Fatalf("the other router should yield, got %s", StateName(other.GetState()))
+	}
+
+	top, _ := newTestRouter(t, nil, 241, "192.168.0.10", 254)
+	top.SetPreferredMaster(true).SetPreferredMasterBump(10)
+	if p := top.GetEffectivePriority(); p != 254 {
+		t.Errorf("expect effective priority capped at 254, got %d", p)
+	}
+	owner, _ := newTestRouter(t, nil, 242, "192.168.0.10", 255)
+	owner.SetPreferredMaster(true)
+	if p := owner.GetEffectivePriority(); p != 255 {
+		t.Errorf("owner should not be affected, got %d", p)
+	}
+}
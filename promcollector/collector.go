@@ -0,0 +1,114 @@
+// Package promcollector 将虚拟路由器的运行指标导出为 Prometheus 指标。
+// 以独立模块发布（github.com/Trisia/govrrp/promcollector），仅需要 Prometheus 指标的用户才引入 Prometheus 客户端库；
+// 无需依赖客户端库时可使用 govrrp.WriteOpenMetrics。
+//
+// 本地开发时在仓库根目录执行 go work init . ./promcollector，使用同一仓库中的 govrrp。
+package promcollector
+
+import (
+	"fmt"
+	"github.com/Trisia/govrrp"
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+)
+
+// 每个虚拟路由器的指标标签
+var labels = []string{"vrid", "interface", "family"}
+
+var (
+	stateDesc = prometheus.NewDesc("govrrp_state",
+		"Virtual router state (0 INIT, 1 MASTER, 2 BACKUP).", labels, nil)
+	priorityDesc = prometheus.NewDesc("govrrp_effective_priority",
+		"Advertised priority after track reductions.", labels, nil)
+	sentDesc = prometheus.NewDesc("govrrp_advertisements_sent_total",
+		"Advertisements sent successfully.", labels, nil)
+	receivedDesc = prometheus.NewDesc("govrrp_advertisements_received_total",
+		"Advertisements received for this virtual router.", labels, nil)
+	sendErrorsDesc = prometheus.NewDesc("govrrp_send_errors_total",
+		"Advertisements that failed to send.", labels, nil)
+	checksumDesc = prometheus.NewDesc("govrrp_checksum_failures_total",
+		"Advertisements dropped for a bad checksum.", labels, nil)
+	ttlDesc = prometheus.NewDesc("govrrp_ttl_rejections_total",
+		"Advertisements dropped for a TTL or hop limit other than 255.", labels, nil)
+	droppedDesc = prometheus.NewDesc("govrrp_dropped_total",
+		"Received packets dropped, by reason.", append(labels, "reason"), nil)
+	masterDownDesc = prometheus.NewDesc("govrrp_master_down_expirations_total",
+		"Master down timer expirations in BACKUP state.", labels, nil)
+	transitionsDesc = prometheus.NewDesc("govrrp_transitions_total",
+		"State transitions, by transition.", append(labels, "transition"), nil)
+)
+
+// Collector 实现 prometheus.Collector，导出一组虚拟路由器的指标，每个虚拟路由器以 vrid、interface、family 标签区分。
+// 每次采集时读取虚拟路由器的统计快照，可在虚拟路由器运行期间并发采集。
+type Collector struct {
+	mu      sync.RWMutex
+	routers []*govrrp.VirtualRouter
+}
+
+// NewCollector 创建导出 routers 指标的 Collector
+func NewCollector(routers ...*govrrp.VirtualRouter) *Collector {
+	return &Collector{routers: append([]*govrrp.VirtualRouter(nil), routers...)}
+}
+
+// Add 添加需要导出指标的虚拟路由器
+func (c *Collector) Add(r *govrrp.VirtualRouter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routers = append(c.routers, r)
+}
+
+// Remove 移除虚拟路由器，此后不再导出其指标
+func (c *Collector) Remove(r *govrrp.VirtualRouter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, router := range c.routers {
+		if router == r {
+			c.routers = append(c.routers[:i], c.routers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{stateDesc, priorityDesc, sentDesc, receivedDesc, sendErrorsDesc,
+		checksumDesc, ttlDesc, droppedDesc, masterDownDesc, transitionsDesc} {
+		ch <- desc
+	}
+}
+
+// Collect 实现 prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	routers := append([]*govrrp.VirtualRouter(nil), c.routers...)
+	c.mu.RUnlock()
+	for _, r := range routers {
+		collect(ch, r)
+	}
+}
+
+// collect 导出一个虚拟路由器的指标
+func collect(ch chan<- prometheus.Metric, r *govrrp.VirtualRouter) {
+	family := "ipv4"
+	if r.GetIPvX() == govrrp.IPv6 {
+		family = "ipv6"
+	}
+	values := []string{fmt.Sprint(r.VRID()), r.GetInterface().Name, family}
+	stats := r.GetStatistics()
+	drops := r.DropReasons()
+
+	ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, float64(r.GetState()), values...)
+	ch <- prometheus.MustNewConstMetric(priorityDesc, prometheus.GaugeValue, float64(r.GetEffectivePriority()), values...)
+	ch <- prometheus.MustNewConstMetric(sentDesc, prometheus.CounterValue, float64(stats.AdvertsSent), values...)
+	ch <- prometheus.MustNewConstMetric(receivedDesc, prometheus.CounterValue, float64(stats.Received), values...)
+	ch <- prometheus.MustNewConstMetric(sendErrorsDesc, prometheus.CounterValue, float64(stats.SendErrors), values...)
+	ch <- prometheus.MustNewConstMetric(checksumDesc, prometheus.CounterValue, float64(drops[govrrp.TapDroppedChecksum]), values...)
+	ch <- prometheus.MustNewConstMetric(ttlDesc, prometheus.CounterValue, float64(drops[govrrp.TapDroppedTTL]), values...)
+	ch <- prometheus.MustNewConstMetric(masterDownDesc, prometheus.CounterValue, float64(stats.MasterDownExpired), values...)
+	for reason, n := range drops {
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(n), append(values, reason)...)
+	}
+	for t, n := range r.GetTransitionCounts() {
+		ch <- prometheus.MustNewConstMetric(transitionsDesc, prometheus.CounterValue, float64(n), append(values, t)...)
+	}
+}
@@ -0,0 +1,121 @@
+package promcollector
+
+import (
+	"github.com/Trisia/govrrp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memConn 基于内存的 VRRPMsgConnection，发送的报文直接丢弃，关闭后读取返回错误
+type memConn struct {
+	done chan struct{}
+	once sync.Once
+	tap  govrrp.RawTap
+}
+
+func (c *memConn) WriteMessage(*govrrp.VRRPPacket) error { return nil }
+
+func (c *memConn) ReadMessage() (*govrrp.VRRPPacket, error) {
+	<-c.done
+	return nil, govrrp.NetErr{}
+}
+
+func (c *memConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+func (c *memConn) ConnectionInfo() govrrp.ConnectionInfo {
+	return govrrp.ConnectionInfo{InterfaceName: "mem0", InterfaceIndex: 1}
+}
+
+func (c *memConn) SetRawTap(tap govrrp.RawTap) { c.tap = tap }
+
+// gather 采集指标，返回以指标名称与按名称排序的标签组合为键的值
+func gather(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range m.GetLabel() {
+				key += "," + label.GetName() + "=" + label.GetValue()
+			}
+			res[key] = value(m)
+		}
+	}
+	return res
+}
+
+func value(m *dto.Metric) float64 {
+	if m.Gauge != nil {
+		return m.Gauge.GetValue()
+	}
+	return m.Counter.GetValue()
+}
+
+func TestCollector(t *testing.T) {
+	conn := &memConn{done: make(chan struct{})}
+	vr, err := govrrp.NewVirtualRouterWithConn(240, conn, nil, net.IPv4(192, 168, 0, 10), 100, govrrp.IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr.SetAdvInterval(20 * time.Millisecond)
+	vr.SetPriorityAndMasterAdvInterval(100, 20*time.Millisecond)
+	conn.tap(nil, net.IPv4(192, 168, 0, 20), govrrp.TapDroppedChecksum)
+	conn.tap(nil, net.IPv4(192, 168, 0, 20), govrrp.TapDroppedTTL)
+	conn.tap(nil, net.IPv4(192, 168, 0, 20), govrrp.TapDroppedTTL)
+
+	reg := prometheus.NewRegistry()
+	collector := NewCollector()
+	reg.MustRegister(collector)
+	collector.Add(vr)
+
+	done := make(chan error, 1)
+	go func() { done <- vr.Start() }()
+	deadline := time.Now().Add(time.Second)
+	for vr.GetState() != govrrp.MASTER || vr.GetStatistics().AdvertsSent < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("router should become master and advertise")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	metrics := gather(t, reg)
+	labels := ",family=ipv4,interface=mem0,vrid=240"
+	for name, expect := range map[string]float64{
+		"govrrp_state" + labels:                                                                    float64(govrrp.MASTER),
+		"govrrp_effective_priority" + labels:                                                       100,
+		"govrrp_checksum_failures_total" + labels:                                                  1,
+		"govrrp_ttl_rejections_total" + labels:                                                     2,
+		"govrrp_dropped_total,family=ipv4,interface=mem0,reason=dropped-ttl,vrid=240":              2,
+		"govrrp_master_down_expirations_total" + labels:                                            1,
+		"govrrp_transitions_total,family=ipv4,interface=mem0,transition=init to backup,vrid=240":   1,
+		"govrrp_transitions_total,family=ipv4,interface=mem0,transition=backup to master,vrid=240": 1,
+		"govrrp_send_errors_total" + labels:                                                        0,
+	} {
+		if got, ok := metrics[name]; !ok || got != expect {
+			t.Errorf("expect %s = %v, got %v (present %v)", name, expect, got, ok)
+		}
+	}
+	if metrics["govrrp_advertisements_sent_total"+labels] < 2 {
+		t.Errorf("expect advertisements sent, got %v", metrics["govrrp_advertisements_sent_total"+labels])
+	}
+
+	vr.Stop()
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	collector.Remove(vr)
+	if metrics = gather(t, reg); len(metrics) != 0 {
+		t.Errorf("expect no metrics after remove, got %d", len(metrics))
+	}
+}
@@ -0,0 +1,41 @@
+package govrrp
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter 固定窗口限速器，限制每秒允许的操作次数
+type rateLimiter struct {
+	mu        sync.Mutex
+	perSecond int       // 每秒允许的最大次数，小于等于 0 表示不限速
+	window    time.Time // 当前计数窗口的起始时间
+	count     int       // 当前窗口内已允许的次数
+}
+
+// setRate 设置 每秒允许的最大次数
+func (l *rateLimiter) setRate(perSecond int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perSecond = perSecond
+	l.window = time.Time{}
+	l.count = 0
+}
+
+// allow 判断在 now 时刻是否允许执行一次操作
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perSecond <= 0 {
+		return true
+	}
+	if now.Sub(l.window) >= time.Second || now.Before(l.window) {
+		l.window = now
+		l.count = 0
+	}
+	if l.count >= l.perSecond {
+		return false
+	}
+	l.count++
+	return true
+}
@@ -0,0 +1,152 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Reloadable 支持重新加载配置的虚拟路由器
+type Reloadable interface {
+	// Start 启动，阻塞直至停止
+	Start() error
+	// Stop 停止，虚拟路由器在新的协程中启动，Stop 可能先于 Start 执行，此时随后的 Start 应立即返回
+	Stop()
+	// Reload 应用新的配置，无法直接生效时返回 ErrRestartRequired
+	Reload(cfg Config) error
+}
+
+// ReloadableFactory 根据配置创建可重新加载的虚拟路由器
+type ReloadableFactory func(cfg Config) (Reloadable, error)
+
+// configKey 虚拟路由器的唯一标识，同一网口同一协议类型下 VRID 唯一
+type configKey struct {
+	ift  string
+	ipvX byte
+	vrID byte
+}
+
+func keyOf(cfg Config) configKey {
+	return configKey{ift: cfg.Interface, ipvX: cfg.IPvX, vrID: cfg.VRID}
+}
+
+// reloadEntry 运行中的虚拟路由器
+type reloadEntry struct {
+	router Reloadable
+	cfg    Config // 当前生效的配置
+	done   chan struct{}
+}
+
+// Reloader 根据配置集合管理一组虚拟路由器，
+// 每次应用新的配置集合时，与运行中的虚拟路由器进行比较：
+// 启动新增的虚拟路由器，停止已移除的虚拟路由器，重新加载配置变更的虚拟路由器。
+// 可用于守护进程响应 SIGHUP 信号重新加载配置。
+type Reloader struct {
+	mu      sync.Mutex
+	factory ReloadableFactory
+	running map[configKey]*reloadEntry
+}
+
+// NewReloader 创建配置重新加载器
+// factory: 虚拟路由器创建函数，为 nil 时使用 NewVirtualRouterFromConfig
+func NewReloader(factory ReloadableFactory) *Reloader {
+	if factory == nil {
+		factory = func(cfg Config) (Reloadable, error) {
+			return NewVirtualRouterFromConfig(cfg)
+		}
+	}
+	return &Reloader{
+		factory: factory,
+		running: make(map[configKey]*reloadEntry),
+	}
+}
+
+// Apply 应用新的配置集合，返回过程中出现的全部错误
+// 单个虚拟路由器出错不影响其余虚拟路由器的处理。
+func (l *Reloader) Apply(cfgs []Config) error {
+	wanted := make(map[configKey]Config, len(cfgs))
+	for _, cfg := range cfgs {
+		key := keyOf(cfg)
+		if _, ok := wanted[key]; ok {
+			return fmt.Errorf("duplicate VRID [%d] on %s", cfg.VRID, cfg.Interface)
+		}
+		wanted[key] = cfg
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs []error
+	// 停止已移除的虚拟路由器
+	for key, entry := range l.running {
+		if _, ok := wanted[key]; !ok {
+			entry.stop()
+			delete(l.running, key)
+			logg().Printf("VRID [%d] on %s removed by reload", key.vrID, key.ift)
+		}
+	}
+	for _, cfg := range cfgs {
+		key := keyOf(cfg)
+		if entry, ok := l.running[key]; ok {
+			if entry.cfg.Equal(cfg) {
+				continue
+			}
+			logg().Printf("VRID [%d] on %s config changed: %s", key.vrID, key.ift, strings.Join(entry.cfg.Diff(cfg), "; "))
+			err := entry.router.Reload(cfg)
+			if err == nil {
+				entry.cfg = cfg
+				continue
+			}
+			if !errors.Is(err, ErrRestartRequired) {
+				errs = append(errs, err)
+				continue
+			}
+			// 无法直接生效，重新创建
+			entry.stop()
+			delete(l.running, key)
+			logg().Printf("VRID [%d] on %s restarted by reload", key.vrID, key.ift)
+		}
+		router, err := l.factory(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("VRID [%d] on %s: %w", cfg.VRID, cfg.Interface, err))
+			continue
+		}
+		l.running[key] = startEntry(router, cfg)
+	}
+	return errors.Join(errs...)
+}
+
+// StopAll 停止全部虚拟路由器
+func (l *Reloader) StopAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, entry := range l.running {
+		entry.stop()
+		delete(l.running, key)
+	}
+}
+
+// startEntry 在新的协程中启动虚拟路由器
+func startEntry(router Reloadable, cfg Config) *reloadEntry {
+	entry := &reloadEntry{router: router, cfg: cfg, done: make(chan struct{})}
+	go func() {
+		defer close(entry.done)
+		if err := router.Start(); err != nil {
+			logg().Printf("ERROR start virtual router: %v", err)
+		}
+	}()
+	return entry
+}
+
+// stop 停止虚拟路由器并等待其退出，确保资源释放后才可创建相同 VRID 的虚拟路由器。
+// 刚启动的虚拟路由器可能尚未开始运行，依赖 Stop 使随后的 Start 返回，否则将持有锁一直等待
+func (e *reloadEntry) stop() {
+	select {
+	case <-e.done:
+		return
+	default:
+	}
+	e.router.Stop()
+	<-e.done
+}
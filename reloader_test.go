@@ -0,0 +1,242 @@
+package govrrp
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeReloadable 记录启动、停止、重新加载过程的虚拟路由器
+type fakeReloadable struct {
+	cfg     Config
+	stopped chan struct{}
+	once    sync.Once
+	reloads int
+}
+
+func (f *fakeReloadable) Start() error {
+	<-f.stopped
+	return nil
+}
+
+func (f *fakeReloadable) Stop() {
+	f.once.Do(func() { close(f.stopped) })
+}
+
+func (f *fakeReloadable) Reload(cfg Config) error {
+	if cfg.Priority != f.cfg.Priority {
+		return ErrRestartRequired
+	}
+	f.cfg = cfg
+	f.reloads++
+	return nil
+}
+
+func (f *fakeReloadable) isStopped() bool {
+	select {
+	case <-f.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestReloader_Apply(t *testing.T) {
+	var created []*fakeReloadable
+	reloader := NewReloader(func(cfg Config) (Reloadable, error) {
+		if cfg.VRID == 99 {
+			return nil, errors.New("boom")
+		}
+		f := &fakeReloadable{cfg: cfg, stopped: make(chan struct{})}
+		created = append(created, f)
+		return f, nil
+	})
+	defer reloader.StopAll()
+
+	vip := net.ParseIP("192.168.0.100")
+	err := reloader.Apply([]Config{
+		{VRID: 1, Interface: "eth0", IPvX: IPv4, Priority: 100},
+		{VRID: 2, Interface: "eth0", IPvX: IPv4, Priority: 100},
+		{VRID: 3, Interface: "eth0", IPvX: IPv4, Priority: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 3 {
+		t.Fatalf("expect 3 routers started, got %d", len(created))
+	}
+	r1, r2, r3 := created[0], created[1], created[2]
+
+	// 1 移除，2 仅VIP变更，3 优先级变更需重建，4 新增，99 创建失败
+	err = reloader.Apply([]Config{
+		{VRID: 2, Interface: "eth0", IPvX: IPv4, Priority: 100, VIPs: []net.IP{vip}},
+		{VRID: 3, Interface: "eth0", IPvX: IPv4, Priority: 200},
+		{VRID: 4, Interface: "eth0", IPvX: IPv4, Priority: 100},
+		{VRID: 99, Interface: "eth0", IPvX: IPv4, Priority: 100},
+	})
+	if err == nil {
+		t.Error("expect error for failed router")
+	}
+	if !r1.isStopped() {
+		t.Error("removed router should be stopped")
+	}
+	if r2.isStopped() || r2.reloads != 1 || !r2.cfg.VIPs[0].Equal(vip) {
+		t.Error("changed router should be reloaded in place")
+	}
+	if !r3.isStopped() {
+		t.Error("router requiring restart should be stopped")
+	}
+
+	var vrids []int
+	for _, f := range created[3:] {
+		if f.isStopped() {
+			t.Errorf("VRID [%d] should be running", f.cfg.VRID)
+		}
+		vrids = append(vrids, int(f.cfg.VRID))
+	}
+	sort.Ints(vrids)
+	if len(vrids) != 2 || vrids[0] != 3 || vrids[1] != 4 {
+		t.Errorf("expect VRID 3 restarted and 4 started, got %v", vrids)
+	}
+
+	// 配置未变化时不重新加载
+	err = reloader.Apply([]Config{
+		{VRID: 2, Interface: "eth0", IPvX: IPv4, Priority: 100, VIPs: []net.IP{vip}},
+		{VRID: 3, Interface: "eth0", IPvX: IPv4, Priority: 200},
+		{VRID: 4, Interface: "eth0", IPvX: IPv4},
+	})
+	if err != nil || r2.reloads != 1 || len(created) != 5 {
+		t.Errorf("expect unchanged configs skipped, got err=%v reloads=%d created=%d", err, r2.reloads, len(created))
+	}
+
+	if err := reloader.Apply([]Config{{VRID: 5, Interface: "eth0"}, {VRID: 5, Interface: "eth0"}}); err == nil {
+		t.Error("expect error for duplicate VRID")
+	}
+
+	reloader.StopAll()
+	for _, f := range created {
+		if !f.isStopped() {
+			t.Errorf("VRID [%d] should be stopped", f.cfg.VRID)
+		}
+	}
+}
+
+func TestVirtualRouter_Reload(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 242, "192.168.0.10", 100)
+	vr.AddIPvXAddr(net.ParseIP("192.168.0.100"))
+	cfg := Config{
+		VRID:        242,
+		Interface:   vr.GetInterface().Name,
+		IPvX:        IPv4,
+		Priority:    100,
+		AdvInterval: vr.GetAdvInterval(),
+		Preempt:     vr.GetPreempt(),
+		VIPs:        []net.IP{net.ParseIP("192.168.0.101"), net.ParseIP("192.168.0.102")},
+	}
+	if err := vr.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	vips := vr.GetVIPs()
+	if len(vips) != 2 {
+		t.Fatalf("expect 2 VIPs, got %v", vips)
+	}
+	for _, vip := range vips {
+		if vip.Equal(net.ParseIP("192.168.0.100")) {
+			t.Error("VIP 192.168.0.100 should be removed")
+		}
+	}
+
+	cfg.AdvInterval = 3 * time.Second
+	if err := vr.Reload(cfg); !errors.Is(err, ErrRestartRequired) {
+		t.Errorf("expect ErrRestartRequired, got %v", err)
+	}
+}
+
+func TestReloader_SameVRIDAcrossFamilies(t *testing.T) {
+	network := &memNetwork{}
+	var mu sync.Mutex
+	routers := map[byte]*VirtualRouter{}
+	reloader := NewReloader(func(cfg Config) (Reloadable, error) {
+		src := "192.168.0.10"
+		if cfg.IPvX == IPv6 {
+			src = "fe80::10"
+		}
+		vr, _ := newTestRouter(t, network, cfg.VRID, src, 100)
+		vr.SetAdvInterval(testInterval)
+		vr.SetPriorityAndMasterAdvInterval(100, testInterval)
+		mu.Lock()
+		routers[cfg.IPvX] = vr
+		mu.Unlock()
+		return vr, nil
+	})
+	defer reloader.StopAll()
+
+	err := reloader.Apply([]Config{
+		{Interface: "mem0", VRID: 10, IPvX: IPv4, Priority: 100},
+		{Interface: "mem0", VRID: 10, IPvX: IPv6, Priority: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	v4, v6 := routers[IPv4], routers[IPv6]
+	mu.Unlock()
+	if v4 == nil || v6 == nil {
+		t.Fatalf("expect both families to run, got %v", routers)
+	}
+	if v4.Identity() != "mem0-ipv4-10" || v6.Identity() != "mem0-ipv6-10" {
+		t.Errorf("unexpected identities %q %q", v4.Identity(), v6.Identity())
+	}
+	// 两个协议类型互不干扰，各自成为主节点
+	if !waitState(v4, MASTER, time.Second) || !waitState(v6, MASTER, time.Second) {
+		t.Fatalf("expect both routers to become master, got ipv4=%d ipv6=%d", v4.GetState(), v6.GetState())
+	}
+	if n := v4.GetStatistics().Received + v6.GetStatistics().Received; n != 0 {
+		t.Errorf("expect no cross-talk between families, got %d advertisements received", n)
+	}
+}
+
+func TestReloader_ApplyThenStopAll(t *testing.T) {
+	network := &memNetwork{}
+	reloader := NewReloader(func(cfg Config) (Reloadable, error) {
+		vr, _ := newTestRouter(t, network, cfg.VRID, "192.168.0.10", 100)
+		vr.SetAdvInterval(testInterval)
+		vr.SetPriorityAndMasterAdvInterval(100, testInterval)
+		return vr, nil
+	})
+	// 虚拟路由器在新的协程中启动，随即停止时 Stop 可能先于 Start 执行，不应阻塞
+	for i := 0; i < 20; i++ {
+		if err := reloader.Apply([]Config{{Interface: "mem0", VRID: 10, IPvX: IPv4, Priority: 100}}); err != nil {
+			t.Fatal(err)
+		}
+		stopped := make(chan struct{})
+		go func() {
+			reloader.StopAll()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("StopAll right after Apply should not block")
+		}
+	}
+
+	// 移除刚启动的虚拟路由器同样不应阻塞
+	if err := reloader.Apply([]Config{{Interface: "mem0", VRID: 11, IPvX: IPv4, Priority: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	applied := make(chan error, 1)
+	go func() { applied <- reloader.Apply(nil) }()
+	select {
+	case err := <-applied:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("removing a just started router should not block")
+	}
+}
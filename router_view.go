@@ -0,0 +1,99 @@
+package govrrp
+
+import (
+	"net"
+	"time"
+)
+
+// RouterView 虚拟路由器的只读视图，仅包含查询方法，见 AddEventListenerView、AppendEventListenerView。
+// 状态变更处理函数在状态机协程中同步调用，若在其中调用 Stop、AddIPvXAddr 等修改方法，可能重入状态机导致阻塞，
+// 使用只读视图可避免处理函数意外修改虚拟路由器。
+type RouterView interface {
+	VRID() byte
+	GetIPvX() byte
+	GetState() uint32
+	GetPriority() byte
+	GetEffectivePriority() byte
+	GetPreempt() bool
+	GetAcceptMode() bool
+	GetInterface() *net.Interface
+	GetPreferredSourceIP() net.IP
+	GetVIPs() []net.IP
+	GetAdvInterval() time.Duration
+	GetMasterAdvInterval() time.Duration
+	GetMasterDownInterval() time.Duration
+	GetMasterAddr() net.IP
+	GetMasterPriority() byte
+	GetMastershipLost() (reason string, peer net.IP)
+	GetStatistics() Statistics
+	Uptime() time.Duration
+	TimeInState(state State) time.Duration
+	IsPaused() bool
+}
+
+var _ RouterView = (*VirtualRouter)(nil)
+
+// routerView RouterView 的实现，仅转发查询方法，处理函数无法通过类型断言取得 *VirtualRouter
+type routerView struct {
+	r *VirtualRouter
+}
+
+func (v routerView) VRID() byte                            { return v.r.VRID() }
+func (v routerView) GetIPvX() byte                         { return v.r.GetIPvX() }
+func (v routerView) GetState() uint32                      { return v.r.GetState() }
+func (v routerView) GetPriority() byte                     { return v.r.GetPriority() }
+func (v routerView) GetEffectivePriority() byte            { return v.r.GetEffectivePriority() }
+func (v routerView) GetPreempt() bool                      { return v.r.GetPreempt() }
+func (v routerView) GetAcceptMode() bool                   { return v.r.GetAcceptMode() }
+func (v routerView) GetVIPs() []net.IP                     { return v.r.GetVIPs() }
+func (v routerView) GetAdvInterval() time.Duration         { return v.r.GetAdvInterval() }
+func (v routerView) GetMasterAdvInterval() time.Duration   { return v.r.GetMasterAdvInterval() }
+func (v routerView) GetMasterDownInterval() time.Duration  { return v.r.GetMasterDownInterval() }
+func (v routerView) GetMasterAddr() net.IP                 { return v.r.GetMasterAddr() }
+func (v routerView) GetMasterPriority() byte               { return v.r.GetMasterPriority() }
+func (v routerView) GetStatistics() Statistics             { return v.r.GetStatistics() }
+func (v routerView) Uptime() time.Duration                 { return v.r.Uptime() }
+func (v routerView) TimeInState(state State) time.Duration { return v.r.TimeInState(state) }
+func (v routerView) IsPaused() bool                        { return v.r.IsPaused() }
+
+// GetInterface 获取 工作网口信息的副本（包括硬件地址），修改副本不影响虚拟路由器
+func (v routerView) GetInterface() *net.Interface {
+	ift := *v.r.GetInterface()
+	ift.HardwareAddr = append(net.HardwareAddr(nil), ift.HardwareAddr...)
+	return &ift
+}
+
+// GetPreferredSourceIP 获取 发送VRRP报文源地址的副本，修改副本不影响虚拟路由器
+func (v routerView) GetPreferredSourceIP() net.IP {
+	return append(net.IP(nil), v.r.GetPreferredSourceIP()...)
+}
+
+// GetMastershipLost 获取 最近一次失去主节点身份的原因，返回的对端地址为副本
+func (v routerView) GetMastershipLost() (string, net.IP) {
+	reason, peer := v.r.GetMastershipLost()
+	if peer != nil {
+		peer = append(net.IP(nil), peer...)
+	}
+	return reason, peer
+}
+
+// AddEventListenerView 添加状态机事件监听器，与 AddEventListener 相同，但处理函数接收只读视图，
+// 避免在状态机协程中意外调用修改方法。
+// return: 如果已经存在该类型的监听器，那么返回 true，否则返回 false
+//
+// 注意：与 AddEventListener 相同，该方法将替换该类型已注册的全部监听器（包括 VIPMigrator.Attach 等追加的监听器），
+// 若需与其他监听器共存请使用 AppendEventListenerView
+func (r *VirtualRouter) AddEventListenerView(typ transition, handler func(RouterView)) bool {
+	return r.AddEventListener(typ, viewHandler(r, handler))
+}
+
+// AppendEventListenerView 追加状态机事件监听器，与 AppendEventListener 相同，但处理函数接收只读视图
+func (r *VirtualRouter) AppendEventListenerView(typ transition, handler func(RouterView)) *VirtualRouter {
+	return r.AppendEventListener(typ, viewHandler(r, handler))
+}
+
+// viewHandler 将接收只读视图的处理函数包装为状态变更回调函数
+func viewHandler(r *VirtualRouter, handler func(RouterView)) func(*VirtualRouter) {
+	view := routerView{r: r}
+	return func(*VirtualRouter) { handler(view) }
+}
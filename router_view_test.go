@@ -0,0 +1,116 @@
+package govrrp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_AddEventListenerView(t *testing.T) {
+	network := &memNetwork{}
+	vr, conn := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	type seen struct {
+		typ   transition
+		state uint32
+		view  RouterView
+	}
+	views := make(chan seen, 4)
+	for _, typ := range []transition{Init2Backup, Backup2Master, Master2Backup} {
+		typ := typ
+		if vr.AddEventListenerView(typ, func(view RouterView) { views <- seen{typ, view.GetState(), view} }) {
+			t.Errorf("no listener should exist for %v", typ)
+		}
+	}
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	conn.deliver(newAdvertisement(240, 200, "192.168.0.20"), nil)
+
+	for _, expect := range []struct {
+		typ   transition
+		state uint32
+	}{{Init2Backup, BACKUP}, {Backup2Master, MASTER}, {Master2Backup, BACKUP}} {
+		select {
+		case got := <-views:
+			if got.typ != expect.typ || got.state != expect.state {
+				t.Errorf("expect %v in state %s, got %v in state %s", expect.typ, StateName(expect.state), got.typ, StateName(got.state))
+			}
+			if got.view.VRID() != 240 || got.view.GetPriority() != 100 {
+				t.Errorf("unexpected view VRID %d priority %d", got.view.VRID(), got.view.GetPriority())
+			}
+			// 只读视图不暴露修改方法，也无法断言为 *VirtualRouter
+			if _, ok := got.view.(*VirtualRouter); ok {
+				t.Error("view should not expose *VirtualRouter")
+			}
+			if _, ok := got.view.(interface{ Stop() }); ok {
+				t.Error("view should not expose Stop")
+			}
+			if _, ok := got.view.(interface{ AddIPvXAddr(net.IP) }); ok {
+				t.Error("view should not expose AddIPvXAddr")
+			}
+			got.view.GetInterface().Name = "changed"
+		case <-time.After(time.Second):
+			t.Fatalf("handler for %v not called", expect.typ)
+		}
+	}
+	if name := vr.GetInterface().Name; name != "mem0" {
+		t.Errorf("modifying the view's interface should not affect the router, got %s", name)
+	}
+}
+
+func TestVirtualRouter_AppendEventListenerView(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	fired := make(chan string, 4)
+	vr.AppendEventListener(Backup2Master, func(*VirtualRouter) { fired <- "handler" })
+	vr.AppendEventListenerView(Backup2Master, func(view RouterView) {
+		if view.GetState() != MASTER {
+			t.Errorf("expect MASTER in view, got %s", StateName(view.GetState()))
+		}
+		fired <- "view"
+	})
+	if n := vr.HandlerCount(Backup2Master); n != 2 {
+		t.Fatalf("expect 2 handlers, got %d", n)
+	}
+	// AddEventListenerView 替换该类型已注册的全部监听器
+	vr.AppendEventListener(Master2Backup, func(*VirtualRouter) {})
+	vr.AppendEventListener(Master2Backup, func(*VirtualRouter) {})
+	if !vr.AddEventListenerView(Master2Backup, func(RouterView) {}) || vr.HandlerCount(Master2Backup) != 1 {
+		t.Errorf("AddEventListenerView should replace existing listeners, got %d", vr.HandlerCount(Master2Backup))
+	}
+	startRouter(t, vr)
+	// 追加的监听器与只读视图监听器均被调用，按注册顺序
+	for _, expect := range []string{"handler", "view"} {
+		select {
+		case got := <-fired:
+			if got != expect {
+				t.Errorf("expect %s, got %s", expect, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s listener not called", expect)
+		}
+	}
+}
+
+func TestRouterView_ReturnsCopies(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	vr.mastershipLost(MastershipLostPreempted, net.IPv4(192, 168, 0, 20))
+	view := routerView{r: vr}
+
+	// 修改视图返回的地址与网口信息不影响虚拟路由器
+	view.GetInterface().HardwareAddr[5] = 0xff
+	view.GetPreferredSourceIP()[len(vr.GetPreferredSourceIP())-1] = 99
+	_, peer := view.GetMastershipLost()
+	peer[len(peer)-1] = 99
+
+	if mac := vr.GetInterface().HardwareAddr.String(); mac != "02:00:00:00:00:01" {
+		t.Errorf("modifying the view's hardware address should not affect the router, got %s", mac)
+	}
+	if src := vr.GetPreferredSourceIP(); !src.Equal(net.IPv4(192, 168, 0, 10)) {
+		t.Errorf("modifying the view's source IP should not affect the router, got %v", src)
+	}
+	if _, peer := vr.GetMastershipLost(); !peer.Equal(net.IPv4(192, 168, 0, 20)) {
+		t.Errorf("modifying the view's peer should not affect the router, got %v", peer)
+	}
+}
@@ -0,0 +1,25 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSocketMarkUnsupported 当前连接不支持设置套接字标记
+var ErrSocketMarkUnsupported = errors.New("setting socket mark is not supported")
+
+// SetSocketMark 设置 VRRP消息收发套接字的标记（Linux SO_MARK），
+// 便于通过防火墙规则（如 iptables -m mark）或策略路由（ip rule fwmark）识别本进程的VRRP控制流量。
+// 需要 CAP_NET_ADMIN 权限，仅在 Linux 平台生效，其他平台为空操作。需在 Start 前调用。
+func (r *VirtualRouter) SetSocketMark(mark uint32) error {
+	c, ok := r.vrrpConn.(interface{ SetSocketMark(uint32) error })
+	if !ok {
+		return fmt.Errorf("VRID [%d] %w", r.vrID, ErrSocketMarkUnsupported)
+	}
+	if err := c.SetSocketMark(mark); err != nil {
+		return fmt.Errorf("VRID [%d] set socket mark %#x: %w", r.vrID, mark, err)
+	}
+	r.connOpts.mark = &mark
+	r.logger().Printf("VRID [%d] socket mark set to %#x", r.vrID, mark)
+	return nil
+}
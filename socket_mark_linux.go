@@ -0,0 +1,19 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"syscall"
+)
+
+// setSocketMark 通过 SO_MARK 设置套接字标记
+func setSocketMark(raw syscall.RawConn, mark uint32) error {
+	var opErr error
+	err := raw.Control(func(fd uintptr) {
+		opErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, int(mark))
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestSetSocketMark(t *testing.T) {
+	udp, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("listen udp: %v", err)
+	}
+	defer udp.Close()
+	raw, err := udp.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &IPv4VRRPMsgCon{raw: raw}
+	if err = conn.SetSocketMark(0x112); err != nil {
+		if errors.Is(err, syscall.EPERM) {
+			t.Skipf("SO_MARK requires CAP_NET_ADMIN: %v", err)
+		}
+		t.Fatal(err)
+	}
+	var mark int
+	_ = raw.Control(func(fd uintptr) {
+		mark, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mark != 0x112 {
+		t.Errorf("expect socket mark 0x112, got %#x", mark)
+	}
+}
+
+func TestVirtualRouter_SetSocketMark(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	if err := vr.SetSocketMark(0x112); !errors.Is(err, ErrSocketMarkUnsupported) {
+		t.Errorf("expect ErrSocketMarkUnsupported on in-memory connection, got %v", err)
+	}
+	if err := (&IPv6VRRPMsgCon{}).SetSocketMark(0x112); !errors.Is(err, ErrSocketMarkUnsupported) {
+		t.Errorf("expect ErrSocketMarkUnsupported without socket, got %v", err)
+	}
+}
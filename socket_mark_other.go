@@ -0,0 +1,12 @@
+//go:build !linux
+
+package govrrp
+
+import (
+	"syscall"
+)
+
+// setSocketMark 当前平台不支持套接字标记，空操作
+func setSocketMark(syscall.RawConn, uint32) error {
+	return nil
+}
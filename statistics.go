@@ -0,0 +1,119 @@
+package govrrp
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Statistics 虚拟路由器运行统计信息
+type Statistics struct {
+	Received           uint64 // 收到的本虚拟路由器的心跳消息数量
+	UnexpectedType     uint64 // 收到的非 ADVERTISEMENT 类型报文数量
+	OwnerConflict      uint64 // 作为地址拥有者时收到其他拥有者心跳的次数
+	SendErrors         uint64 // 心跳消息发送失败次数
+	ForeignIfIndex     uint64 // 开启 SetRejectForeignInterface 后丢弃的非工作网口报文数量
+	ZeroAddr           uint64 // 策略为 ZeroAddrReject 时丢弃的未携带虚拟IP地址的心跳消息数量
+	SuspiciousPriority uint64 // 收到的非已知地址拥有者发出的优先级 255 心跳消息数量，见 OnSuspiciousPriority
+	TruncatedAdvert    uint64 // 丢弃的长度小于声明的地址数量所需长度的心跳消息数量，通常由分片丢失或 MTU 问题导致
+	DelayedAdverts     uint64 // 心跳定时器触发间隔超出允许延迟的次数，通常由主机负载过高导致，见 OnAdvertDelay
+	AdvertsSent        uint64 // 成功发送的心跳消息数量
+	MasterDownExpired  uint64 // BACKUP 状态下主节点下线倒计时到期的次数
+}
+
+// counters 虚拟路由器内部计数器，各字段均通过原子操作更新
+type counters struct {
+	received           atomic.Uint64
+	unexpectedType     atomic.Uint64
+	ownerConflict      atomic.Uint64
+	sendErrors         atomic.Uint64
+	foreignIfIndex     atomic.Uint64
+	zeroAddr           atomic.Uint64
+	suspiciousPriority atomic.Uint64
+	truncatedAdvert    atomic.Uint64
+	delayedAdverts     atomic.Uint64
+	advertsSent        atomic.Uint64
+	masterDownExpired  atomic.Uint64
+	transitions        [Backup2Init + 1]atomic.Uint64 // 按状态切换类型统计的切换次数
+}
+
+// countTransition 状态切换计数加一
+func (c *counters) countTransition(t transition) {
+	if t >= 0 && int(t) < len(c.transitions) {
+		c.transitions[t].Add(1)
+	}
+}
+
+// countDropped 根据接收错误的类型更新对应的计数器
+func (r *VirtualRouter) countDropped(err error) {
+	switch {
+	case errors.Is(err, ErrUnexpectedType):
+		r.stats.unexpectedType.Add(1)
+	case errors.Is(err, ErrForeignInterface):
+		r.stats.foreignIfIndex.Add(1)
+	case errors.Is(err, ErrTruncatedAdvert):
+		r.stats.truncatedAdvert.Add(1)
+	}
+}
+
+// GetStatistics 获取 虚拟路由器运行统计信息快照
+func (r *VirtualRouter) GetStatistics() Statistics {
+	return Statistics{
+		Received:           r.stats.received.Load(),
+		UnexpectedType:     r.stats.unexpectedType.Load(),
+		OwnerConflict:      r.stats.ownerConflict.Load(),
+		SendErrors:         r.stats.sendErrors.Load(),
+		ForeignIfIndex:     r.stats.foreignIfIndex.Load(),
+		ZeroAddr:           r.stats.zeroAddr.Load(),
+		SuspiciousPriority: r.stats.suspiciousPriority.Load(),
+		TruncatedAdvert:    r.stats.truncatedAdvert.Load(),
+		DelayedAdverts:     r.stats.delayedAdverts.Load(),
+		AdvertsSent:        r.stats.advertsSent.Load(),
+		MasterDownExpired:  r.stats.masterDownExpired.Load(),
+	}
+}
+
+// GetTransitionCounts 获取 各类状态切换发生的次数，键为状态切换的名称（如 "backup to master"），未发生的切换不包含在内
+func (r *VirtualRouter) GetTransitionCounts() map[string]uint64 {
+	res := make(map[string]uint64)
+	for t := range r.stats.transitions {
+		if n := r.stats.transitions[t].Load(); n > 0 {
+			res[transition(t).String()] = n
+		}
+	}
+	return res
+}
+
+// dropReasons 按原始报文监听结果统计的丢弃原因计数器
+type dropReasons struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// add 丢弃原因计数加一
+func (d *dropReasons) add(reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.counts == nil {
+		d.counts = make(map[string]uint64)
+	}
+	d.counts[reason]++
+}
+
+// snapshot 丢弃原因计数的快照
+func (d *dropReasons) snapshot() map[string]uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	res := make(map[string]uint64, len(d.counts))
+	for reason, n := range d.counts {
+		res[reason] = n
+	}
+	return res
+}
+
+// DropReasons 获取 收到的报文按丢弃原因汇总的数量快照，键为原始报文监听结果（TapDroppedTTL、TapDroppedVersion、
+// TapDroppedChecksum、TapDroppedVRID、TapDroppedMalformed、TapDroppedTruncated、TapDroppedFamily 等），未出现的原因不包含在内。
+// 与 SetRawTap 报告的结果一致，可用于一次性查看报文被丢弃的分布。
+func (r *VirtualRouter) DropReasons() map[string]uint64 {
+	return r.drops.snapshot()
+}
@@ -0,0 +1,102 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownTrack 跟踪对象不存在
+var ErrUnknownTrack = errors.New("unknown track")
+
+// Track 跟踪对象，用于根据外部对象（如上行链路、服务进程）的状态调整虚拟路由器的优先级
+type Track struct {
+	Name   string // 跟踪对象名称，在虚拟路由器内唯一
+	Weight byte   // 跟踪对象失败时降低的优先级
+	Fault  bool   // 是否为关键跟踪对象，关键跟踪对象失败时无论优先级如何，虚拟路由器均进入 BACKUP 状态
+}
+
+// trackState 跟踪对象及其状态
+type trackState struct {
+	Track
+	down bool
+}
+
+// AddTrackGroup 添加 一组跟踪对象，跟踪对象初始状态为正常。
+// 实际通告的优先级为配置的优先级减去所有失败跟踪对象的权重之和，最低为 1；
+// 任一关键跟踪对象失败时，虚拟路由器进入 BACKUP 状态且不会成为主节点，直至其恢复。
+// 地址拥有者（优先级 255）不受跟踪对象影响。同名跟踪对象将被替换。
+func (r *VirtualRouter) AddTrackGroup(tracks ...Track) *VirtualRouter {
+	r.trackMu.Lock()
+	defer r.trackMu.Unlock()
+	if r.tracks == nil {
+		r.tracks = make(map[string]*trackState)
+	}
+	for _, t := range tracks {
+		r.tracks[t.Name] = &trackState{Track: t}
+	}
+	return r
+}
+
+// SetTrackState 设置 跟踪对象的状态，up 为 false 表示跟踪对象失败
+func (r *VirtualRouter) SetTrackState(name string, up bool) error {
+	r.trackMu.Lock()
+	t, ok := r.tracks[name]
+	if !ok {
+		r.trackMu.Unlock()
+		return fmt.Errorf("VRID [%d] %w %s", r.vrID, ErrUnknownTrack, name)
+	}
+	changed := t.down == up
+	t.down = !up
+	r.trackMu.Unlock()
+	if !changed {
+		return nil
+	}
+	r.logger().Printf("VRID [%d] track %s up: %v, effective priority %d", r.vrID, name, up, r.effectivePriority())
+	// 通知状态机重新评估，通知已存在时无需重复发送
+	select {
+	case r.trackChanged <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// GetEffectivePriority 获取 考虑跟踪对象状态以及首选主节点设置（见 SetPreferredMaster）后实际通告的优先级
+func (r *VirtualRouter) GetEffectivePriority() byte {
+	return r.effectivePriority()
+}
+
+// effectivePriority 获取 当前实际通告的优先级
+func (r *VirtualRouter) effectivePriority() byte {
+	priority := r.priority
+	// 让渡主节点（优先级 0）与地址拥有者（优先级 255）不受跟踪对象影响
+	if priority == 0 || priority == 255 {
+		return priority
+	}
+	r.trackMu.Lock()
+	defer r.trackMu.Unlock()
+	var reduced int
+	for _, t := range r.tracks {
+		if t.down {
+			reduced += int(t.Weight)
+		}
+	}
+	if reduced >= int(priority) {
+		return r.preferredPriority(1)
+	}
+	return r.preferredPriority(priority - byte(reduced))
+}
+
+// inFault 是否有关键跟踪对象失败
+func (r *VirtualRouter) inFault() bool {
+	if r.priority == 255 {
+		return false
+	}
+	r.trackMu.Lock()
+	defer r.trackMu.Unlock()
+	for _, t := range r.tracks {
+		if t.down && t.Fault {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package govrrp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_AddTrackGroup(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.AddTrackGroup(Track{Name: "uplink", Weight: 20}, Track{Name: "service", Weight: 30})
+
+	for _, step := range []struct {
+		name   string
+		up     bool
+		expect byte
+	}{
+		{"uplink", false, 80},
+		{"service", false, 50},
+		{"service", false, 50},
+		{"uplink", true, 70},
+		{"service", true, 100},
+	} {
+		if err := vr.SetTrackState(step.name, step.up); err != nil {
+			t.Fatal(err)
+		}
+		if p := vr.GetEffectivePriority(); p != step.expect {
+			t.Errorf("track %s up %v: expect effective priority %d, got %d", step.name, step.up, step.expect, p)
+		}
+		if p := vr.CurrentAdvertisement().GetPriority(); p != step.expect {
+			t.Errorf("track %s up %v: expect advertised priority %d, got %d", step.name, step.up, step.expect, p)
+		}
+	}
+
+	vr.AddTrackGroup(Track{Name: "heavy", Weight: 200})
+	_ = vr.SetTrackState("heavy", false)
+	if p := vr.GetEffectivePriority(); p != 1 {
+		t.Errorf("expect effective priority floored at 1, got %d", p)
+	}
+
+	if err := vr.SetTrackState("missing", false); !errors.Is(err, ErrUnknownTrack) {
+		t.Errorf("expect ErrUnknownTrack, got %v", err)
+	}
+
+	owner, _ := newTestRouter(t, nil, 241, "192.168.0.10", 255)
+	owner.AddTrackGroup(Track{Name: "uplink", Weight: 20, Fault: true})
+	_ = owner.SetTrackState("uplink", false)
+	if p := owner.GetEffectivePriority(); p != 255 || owner.inFault() {
+		t.Errorf("owner should not be affected by tracks, got priority %d", p)
+	}
+}
+
+func TestVirtualRouter_TrackFault(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 242, "192.168.0.10", 100)
+	vr.AddTrackGroup(Track{Name: "uplink", Weight: 10, Fault: true})
+	lost := make(chan string, 4)
+	vr.OnMastershipLost(func(reason string, _ net.IP) { lost <- reason })
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	_ = vr.SetTrackState("uplink", false)
+	if !waitState(vr, BACKUP, time.Second) {
+		t.Fatal("critical track failure should force BACKUP")
+	}
+	select {
+	case reason := <-lost:
+		if reason != MastershipLostFault {
+			t.Errorf("expect %s, got %s", MastershipLostFault, reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("mastership lost callback not called")
+	}
+	// 无其他主节点时，仍保持 BACKUP 状态
+	time.Sleep(5 * testInterval)
+	if s := vr.GetState(); s != BACKUP {
+		t.Fatalf("expect to stay in BACKUP while in fault, got %s", StateName(s))
+	}
+
+	_ = vr.SetTrackState("uplink", true)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master after track recovery")
+	}
+}
@@ -0,0 +1,78 @@
+package govrrp
+
+import (
+	"sync"
+	"time"
+)
+
+// stateClock 虚拟路由器最近一次启动以来的运行时长与各状态的累计时长
+type stateClock struct {
+	mu      sync.Mutex
+	started time.Time        // 最近一次启动的时间，零值表示从未启动
+	stopped time.Time        // 最近一次停止的时间，运行中为零值
+	state   State            // 当前状态
+	since   time.Time        // 进入当前状态的时间
+	total   [3]time.Duration // 各状态的累计时长，不含当前状态持续的时间
+}
+
+// start 启动时重置计时，初始状态为 INIT
+func (c *stateClock) start(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started, c.stopped = now, time.Time{}
+	c.state, c.since = INIT, now
+	c.total = [3]time.Duration{}
+}
+
+// transit 切换至新状态，累计原状态的持续时间
+func (c *stateClock) transit(state State, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started.IsZero() || !c.stopped.IsZero() {
+		return
+	}
+	c.total[c.state] += now.Sub(c.since)
+	c.state, c.since = state, now
+}
+
+// stop 停止计时，累计当前状态的持续时间
+func (c *stateClock) stop(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started.IsZero() || !c.stopped.IsZero() {
+		return
+	}
+	c.total[c.state] += now.Sub(c.since)
+	c.stopped = now
+}
+
+// Uptime 获取 虚拟路由器最近一次启动以来的运行时长，已停止时为启动至停止的时长，从未启动时为 0
+func (r *VirtualRouter) Uptime() time.Duration {
+	c := &r.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.started.IsZero():
+		return 0
+	case !c.stopped.IsZero():
+		return c.stopped.Sub(c.started)
+	default:
+		return r.now().Sub(c.started)
+	}
+}
+
+// TimeInState 获取 虚拟路由器最近一次启动以来处于指定状态（INIT | MASTER | BACKUP）的累计时长，
+// 用于统计主节点角色的可用性等 SLA 指标。各状态的累计时长之和等于 Uptime。
+func (r *VirtualRouter) TimeInState(state State) time.Duration {
+	if state > BACKUP {
+		return 0
+	}
+	c := &r.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.total[state]
+	if !c.started.IsZero() && c.stopped.IsZero() && c.state == state {
+		d += r.now().Sub(c.since)
+	}
+	return d
+}
@@ -0,0 +1,79 @@
+package govrrp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtualRouter_TimeInState(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	now := time.Unix(1000, 0)
+	vr.now = func() time.Time { return now }
+	advance := func(d time.Duration) { now = now.Add(d) }
+
+	if vr.Uptime() != 0 || vr.TimeInState(INIT) != 0 {
+		t.Fatal("router that never started should report zero uptime")
+	}
+
+	vr.clock.start(now)
+	advance(time.Second)
+	vr.stateChanged(Init2Backup)
+	advance(3 * time.Second)
+	vr.stateChanged(Backup2Master)
+	advance(10 * time.Second)
+	vr.stateChanged(Master2Backup)
+	advance(2 * time.Second)
+	vr.stateChanged(Backup2Master)
+	advance(5 * time.Second)
+
+	// 当前处于 MASTER 状态，计入当前状态持续的时间
+	want := map[State]time.Duration{INIT: time.Second, BACKUP: 5 * time.Second, MASTER: 15 * time.Second}
+	for state, d := range want {
+		if got := vr.TimeInState(state); got != d {
+			t.Errorf("TimeInState(%s) = %v, want %v", StateName(state), got, d)
+		}
+	}
+	if got := vr.Uptime(); got != 21*time.Second {
+		t.Errorf("Uptime = %v, want 21s", got)
+	}
+
+	// 停止后计时冻结
+	vr.stateChanged(Master2Init)
+	advance(4 * time.Second)
+	vr.clock.stop(now)
+	advance(time.Hour)
+	if got := vr.Uptime(); got != 25*time.Second {
+		t.Errorf("Uptime after stop = %v, want 25s", got)
+	}
+	if got := vr.TimeInState(INIT); got != 5*time.Second {
+		t.Errorf("TimeInState(INIT) after stop = %v, want 5s", got)
+	}
+	if got := vr.TimeInState(MASTER); got != 15*time.Second {
+		t.Errorf("TimeInState(MASTER) after stop = %v, want 15s", got)
+	}
+
+	// 重新启动后重新计时
+	vr.clock.start(now)
+	advance(time.Second)
+	if vr.Uptime() != time.Second || vr.TimeInState(MASTER) != 0 || vr.TimeInState(INIT) != time.Second {
+		t.Errorf("restart should reset the accumulators: uptime %v, MASTER %v", vr.Uptime(), vr.TimeInState(MASTER))
+	}
+}
+
+func TestVirtualRouter_UptimeRunning(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("owner should become MASTER")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if vr.Uptime() <= 0 || vr.TimeInState(MASTER) <= 0 {
+		t.Errorf("running router should report uptime %v and MASTER time %v", vr.Uptime(), vr.TimeInState(MASTER))
+	}
+	vr.Stop()
+	up := vr.Uptime()
+	time.Sleep(10 * time.Millisecond)
+	if vr.Uptime() != up {
+		t.Error("uptime should stop increasing after Stop")
+	}
+}
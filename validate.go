@@ -0,0 +1,104 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// 心跳消息校验失败的原因
+var (
+	ErrFamilyMismatch   = errors.New("address family mismatch")
+	ErrVersionMismatch  = errors.New("VRRP version mismatch")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrVRIDMismatch     = errors.New("VRID mismatch")
+	ErrZeroAddr         = errors.New("advertisement carries no addresses")
+)
+
+// ValidationError 心跳消息校验失败的错误
+type ValidationError struct {
+	Result string // 对应的原始报文监听结果，取值见 TapDroppedVersion 等常量
+	Err    error  // 失败原因，取值见 ErrVRIDMismatch 等错误
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate 按照接收流程对心跳消息进行校验，用于诊断对端的心跳消息为何被丢弃。
+// 依次校验：源地址与地址序列的协议类型、VRRP协议版本、校验和（以 src 为源地址、虚拟路由器的组播地址为目的地址构造伪首部）、
+// VRID，以及 ZeroAddrReject 策略下的地址数量。开启 SetStrictFamily 时还校验地址序列与协议类型的一致性。校验失败时返回 *ValidationError。
+func (r *VirtualRouter) Validate(pkt *VRRPPacket, src net.IP) error {
+	invalid := func(result string, err error, format string, args ...interface{}) error {
+		return &ValidationError{Result: result, Err: fmt.Errorf("%w: "+format, append([]interface{}{err}, args...)...)}
+	}
+
+	count := int(pkt.GetIPvXAddrCount())
+	if r.ipvX == IPv4 {
+		if src.To4() == nil || len(pkt.IPAddress) != count {
+			return invalid(TapDroppedFamily, ErrFamilyMismatch, "expect IPv4 advertisement from %v", src)
+		}
+		src = src.To4()
+	} else if src.To4() != nil || len(pkt.IPAddress) != count*4 {
+		return invalid(TapDroppedFamily, ErrFamilyMismatch, "expect IPv6 advertisement from %v", src)
+	}
+	if r.strictFamily {
+		if err := pkt.checkFamily(r.ipvX); err != nil {
+			return &ValidationError{Result: TapDroppedFamily, Err: err}
+		}
+	}
+	if version := VRRPVersion(pkt.GetVersion()); version != r.version {
+		return invalid(TapDroppedVersion, ErrVersionMismatch, "expect %s, got %s", r.version, version)
+	}
+
+	pshdr := r.advertPseudoHeader(pkt)
+	pshdr.Saddr = src
+	if !pkt.ValidateCheckSum(pshdr) {
+		return invalid(TapDroppedChecksum, ErrChecksumMismatch, "checksum 0x%04X from %v to %v", pkt.GetCheckSum(), src, pshdr.Daddr)
+	}
+	if VRID := pkt.GetVirtualRouterID(); VRID != r.vrID {
+		return invalid(TapDroppedVRID, ErrVRIDMismatch, "expect %d, got %d", r.vrID, VRID)
+	}
+	if count == 0 && r.zeroAddrPolicy == ZeroAddrReject {
+		return invalid(TapDroppedZeroAddr, ErrZeroAddr, "rejected by policy")
+	}
+	return nil
+}
+
+// SetStrictFamily 设置 是否丢弃地址序列与协议类型不一致的心跳消息，默认关闭。
+// 正常情况下报文按连接的协议类型解析，不会出现协议类型不一致，开启后额外校验声明的地址数量、地址序列长度、
+// 报文长度以及地址的协议类型（如 IPv6 心跳消息中携带 IPv4 映射地址），用于防御构造的或被篡改的报文。
+func (r *VirtualRouter) SetStrictFamily(flag bool) *VirtualRouter {
+	r.strictFamily = flag
+	return r
+}
+
+// checkFamily 校验报文的地址序列与协议类型是否一致，不一致时返回 ErrFamilyMismatch
+func (packet *VRRPPacket) checkFamily(IPvXVersion byte) error {
+	count := int(packet.GetIPvXAddrCount())
+	words := count
+	if IPvXVersion == IPv6 {
+		words = count * 4
+	}
+	if len(packet.IPAddress) != words {
+		return fmt.Errorf("%w: %d addresses declared, %d address words for IPv%d", ErrFamilyMismatch, count, len(packet.IPAddress), IPvXVersion)
+	}
+	if packet.Pshdr != nil {
+		if int(packet.Pshdr.Len) != packet.PacketSize() {
+			return fmt.Errorf("%w: payload length %d, expect %d", ErrFamilyMismatch, packet.Pshdr.Len, packet.PacketSize())
+		}
+		if isIPv4 := packet.Pshdr.Saddr.To4() != nil; isIPv4 != (IPvXVersion == IPv4) {
+			return fmt.Errorf("%w: source %v for IPv%d", ErrFamilyMismatch, packet.Pshdr.Saddr, IPvXVersion)
+		}
+	}
+	for _, addr := range packet.GetIPAddrs() {
+		if addr.Is4In6() || addr.IsUnspecified() {
+			return fmt.Errorf("%w: invalid address %v for IPv%d", ErrFamilyMismatch, addr, IPvXVersion)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package govrrp
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestVirtualRouter_Validate(t *testing.T) {
+	peer, _ := newTestRouter(t, nil, 240, "192.168.0.20", 100)
+	peer.AddIPvXAddr(net.IPv4(192, 168, 0, 100))
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	src := net.IPv4(192, 168, 0, 20)
+
+	if err := vr.Validate(peer.CurrentAdvertisement(), src); err != nil {
+		t.Fatalf("expect valid advertisement, got %v", err)
+	}
+
+	corrupted := peer.CurrentAdvertisement()
+	corrupted.SetPriority(200)
+	otherVRID := peer.CurrentAdvertisement()
+	otherVRID.SetVirtualRouterID(241)
+	otherVRID.SetCheckSum(peer.advertPseudoHeader(otherVRID))
+	for _, tc := range []struct {
+		name   string
+		pkt    *VRRPPacket
+		src    net.IP
+		err    error
+		result string
+	}{
+		{"corrupted", corrupted, src, ErrChecksumMismatch, TapDroppedChecksum},
+		{"wrong source", peer.CurrentAdvertisement(), net.IPv4(192, 168, 0, 30), ErrChecksumMismatch, TapDroppedChecksum},
+		{"IPv6 source", peer.CurrentAdvertisement(), net.ParseIP("fe80::20"), ErrFamilyMismatch, TapDroppedFamily},
+		{"other VRID", otherVRID, src, ErrVRIDMismatch, TapDroppedVRID},
+	} {
+		err := vr.Validate(tc.pkt, tc.src)
+		var ve *ValidationError
+		if !errors.Is(err, tc.err) || !errors.As(err, &ve) || ve.Result != tc.result {
+			t.Errorf("%s: expect %v (%s), got %v", tc.name, tc.err, tc.result, err)
+		}
+	}
+
+	v2 := peer.CurrentAdvertisement()
+	v2.SetVersion(VRRPv2)
+	if err := vr.Validate(v2, src); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("expect ErrVersionMismatch, got %v", err)
+	}
+}
+
+func TestVirtualRouter_SetStrictFamily(t *testing.T) {
+	// IPv4 组收到按 IPv6 形状构造的报文：声明 1 个地址，地址序列却有 4 个字
+	crafted := func() *VRRPPacket {
+		pkt := newAdvertisement(240, 100, "192.168.0.20")
+		pkt.setIPvXAddrCount(1)
+		pkt.IPAddress = [][4]byte{{0x20, 0x01, 0x0d, 0xb8}, {}, {}, {0, 0, 0, 1}}
+		pkt.Pshdr.Len = uint16(pkt.PacketSize())
+		return pkt
+	}
+	valid := newAdvertisement(240, 100, "192.168.0.20")
+	valid.AddIPvXAddr(IPv4, net.IPv4(192, 168, 0, 100))
+	valid.Pshdr.Len = uint16(valid.PacketSize())
+
+	for _, strict := range []bool{false, true} {
+		vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+		vr.SetStrictFamily(strict)
+		vr.state = BACKUP
+		conn.deliver(crafted(), nil)
+		conn.deliver(valid, nil)
+		_ = conn.Close()
+
+		vr.fetchVRRPDaemon(vr.vrrpConn)
+		want := 2
+		if strict {
+			want = 1
+		}
+		if n := len(vr.packetQueue); n != want {
+			t.Errorf("strict=%v: %d advertisements accepted, want %d", strict, n, want)
+		}
+		if strict {
+			select {
+			case err := <-vr.errs:
+				if !errors.Is(err, ErrFamilyMismatch) {
+					t.Errorf("reported error %v, want ErrFamilyMismatch", err)
+				}
+			default:
+				t.Error("rejected advertisement was not reported")
+			}
+		}
+	}
+
+	// IPv6 心跳消息携带 IPv4 映射地址
+	mapped := newAdvertisement(240, 100, "fe80::20")
+	mapped.AddIPvXAddr(IPv6, net.ParseIP("::ffff:192.168.0.100"))
+	if err := mapped.checkFamily(IPv6); !errors.Is(err, ErrFamilyMismatch) {
+		t.Errorf("IPv4-mapped address in IPv6 advertisement: got %v, want ErrFamilyMismatch", err)
+	}
+	if err := valid.checkFamily(IPv4); err != nil {
+		t.Errorf("valid advertisement rejected: %v", err)
+	}
+	if err := valid.checkFamily(IPv6); !errors.Is(err, ErrFamilyMismatch) {
+		t.Errorf("IPv4 advertisement checked as IPv6: got %v, want ErrFamilyMismatch", err)
+	}
+}
@@ -1,11 +1,14 @@
 package govrrp
 
 import (
+	"errors"
 	"fmt"
 	"github.com/mdlayher/arp"
 	"github.com/mdlayher/ndp"
 	"io"
 	"net"
+	"net/netip"
+	"sync"
 	"time"
 )
 
@@ -14,45 +17,111 @@ type AddrAnnouncer interface {
 	AnnounceAll(vr *VirtualRouter) error
 }
 
+// ErrNoHardwareAddr 工作网口没有MAC地址，无法发送 ARP/NDP 广播
+var ErrNoHardwareAddr = errors.New("interface has no hardware address")
+
+// ErrNoLinkLocal 工作网口没有IPv6链路本地地址，无法发送 NDP 广播
+var ErrNoLinkLocal = errors.New("interface has no IPv6 link-local address")
+
+// newAddrAnnouncer 根据工作网口创建虚拟IP地址广播器
+// 仅以太网类型的网口支持 ARP/NDP 二层广播，其余链路类型（如 tun、PPP、回环接口）返回不做任何广播的广播器。
+func newAddrAnnouncer(ift *net.Interface, ipvX byte) (AddrAnnouncer, error) {
+	if reason := nonEthernetReason(ift); reason != "" {
+		logg().Printf("WARN interface %s %s, ARP/NDP announcement disabled", ift.Name, reason)
+		return noopAnnouncer{}, nil
+	}
+	if ipvX == IPv4 {
+		return NewIPv4AddrAnnouncer(ift)
+	}
+	announcer, err := NewIPIPv6AddrAnnouncer(ift)
+	if errors.Is(err, ErrNoLinkLocal) {
+		// 无法发送 NDP 广播时以监控模式运行，仍可参与选举
+		logg().Printf("WARN %v, NDP announcement disabled", err)
+		return noopAnnouncer{}, nil
+	}
+	return announcer, err
+}
+
+// nonEthernetReason 判断网口是否为以太网类型，是则返回空字符串，否则返回原因
+func nonEthernetReason(ift *net.Interface) string {
+	switch {
+	case len(ift.HardwareAddr) == 0:
+		return "has no hardware address"
+	case ift.Flags&net.FlagLoopback != 0:
+		return "is a loopback interface"
+	case ift.Flags&net.FlagPointToPoint != 0:
+		return "is a point-to-point link"
+	case len(ift.HardwareAddr) != 6:
+		// 以太网MAC地址长度为 6 字节，其余长度为 InfiniBand 等非以太网链路
+		return fmt.Sprintf("has a non-Ethernet hardware address %s", ift.HardwareAddr)
+	default:
+		return ""
+	}
+}
+
+// noopAnnouncer 不做任何广播的虚拟IP地址广播器，用于三层接口等无需二层广播的场景
+type noopAnnouncer struct{}
+
+func (noopAnnouncer) AnnounceAll(*VirtualRouter) error { return nil }
+
+func (noopAnnouncer) Close() error { return nil }
+
 // IPv6AddrAnnouncer IPv6 NDP广播，在指定网口上广播NDP消息通知其他主机VIP地址
 type IPv6AddrAnnouncer struct {
 	con *ndp.Conn
+
+	closeOnce sync.Once // 确保仅关闭一次
+	closeErr  error     // 关闭的结果
 }
 
 // NewIPIPv6AddrAnnouncer 创建IPv6 NDP广播
+// 网口没有链路本地地址时返回 ErrNoLinkLocal。
 func NewIPIPv6AddrAnnouncer(nif *net.Interface) (*IPv6AddrAnnouncer, error) {
+	if err := checkLinkLocal(nif); err != nil {
+		return nil, fmt.Errorf("IPv6AddrAnnouncer: %w", err)
+	}
 	con, ip, err := ndp.Listen(nif, ndp.LinkLocal)
 	if err != nil {
-		return nil, fmt.Errorf("IPv6AddrAnnouncer: %v", err)
+		return nil, fmt.Errorf("IPv6AddrAnnouncer: interface %s listen NDP on link-local address: %v", nif.Name, err)
 	}
-	logg.Printf("NDP client initialized, working on %v, source IP %v", nif.Name, ip)
+	logg().Printf("NDP client initialized, working on %v, source IP %v", nif.Name, ip)
 	return &IPv6AddrAnnouncer{con: con}, nil
 }
 
+// checkLinkLocal 检查 网口是否配置了IPv6链路本地地址，NDP 消息必须使用链路本地地址发送
+func checkLinkLocal(nif *net.Interface) error {
+	addrs, err := nif.Addrs()
+	if err != nil {
+		return fmt.Errorf("interface %s: %w: %v", nif.Name, ErrNoLinkLocal, err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() == nil && ipNet.IP.IsLinkLocalUnicast() {
+			return nil
+		}
+	}
+	return fmt.Errorf("interface %s: %w, enable IPv6 on the interface or assign an fe80::/10 address "+
+		"(check addr_gen_mode and disable_ipv6 sysctls)", nif.Name, ErrNoLinkLocal)
+}
+
 // AnnounceAll 广播 包含所有的IPv6虚拟IP地址
 func (nd *IPv6AddrAnnouncer) AnnounceAll(vr *VirtualRouter) error {
-	for key := range vr.protectedIPaddrs {
+	if len(vr.ownerMAC()) == 0 {
+		return fmt.Errorf("IPv6AddrAnnouncer.AnnounceAll: %w", ErrNoHardwareAddr)
+	}
+	// 虚拟IP地址集合可能被并发修改，遍历快照
+	for _, key := range vr.vipAddrs() {
 		multicastgroup, err := ndp.SolicitedNodeMulticast(key)
 		if err != nil {
 			// logg.Printf(ERROR, "IPv6AddrAnnouncer.AnnounceAll: %v", err)
 			return err
 		} else {
 			//send unsolicited NeighborAdvertisement to refresh link layer address cache
-			var msg = &ndp.NeighborAdvertisement{
-				Override:      true,
-				TargetAddress: key,
-				Options: []ndp.Option{
-					&ndp.LinkLayerAddress{
-						Direction: ndp.Source,
-						Addr:      vr.ift.HardwareAddr,
-					},
-				},
-			}
+			var msg = unsolicitedNeighborAdvertisement(vr, key)
 			if err = nd.con.WriteTo(msg, nil, multicastgroup); err != nil {
 				// logg.Printf(ERROR, "IPv6AddrAnnouncer.AnnounceAll: %v", err)
 				return err
 			} else {
-				logg.Printf("send unsolicited neighbor advertisement for %s", key.String())
+				vr.logger().Printf("send unsolicited neighbor advertisement for %s", key.String())
 			}
 		}
 	}
@@ -60,16 +129,38 @@ func (nd *IPv6AddrAnnouncer) AnnounceAll(vr *VirtualRouter) error {
 	return nil
 }
 
+// unsolicitedNeighborAdvertisement 构造虚拟IP地址的 unsolicited Neighbor Advertisement
+func unsolicitedNeighborAdvertisement(vr *VirtualRouter, vip netip.Addr) *ndp.NeighborAdvertisement {
+	return &ndp.NeighborAdvertisement{
+		Override:      true,
+		TargetAddress: vip,
+		Options: []ndp.Option{
+			&ndp.LinkLayerAddress{
+				Direction: ndp.Source,
+				Addr:      vr.ownerMAC(),
+			},
+		},
+	}
+}
+
 func (nd *IPv6AddrAnnouncer) Close() error {
-	if nd != nil && nd.con != nil {
-		return nd.con.Close()
+	if nd == nil {
+		return nil
 	}
-	return nil
+	nd.closeOnce.Do(func() {
+		if nd.con != nil {
+			nd.closeErr = nd.con.Close()
+		}
+	})
+	return nd.closeErr
 }
 
 // IPv4AddrAnnouncer IPv4 Gratuitous ARP广播，在指定网口上广播Gratuitous ARP消息通知其他主机VIP地址
 type IPv4AddrAnnouncer struct {
 	ARPClient *arp.Client
+
+	closeOnce sync.Once // 确保仅关闭一次
+	closeErr  error     // 关闭的结果
 }
 
 // NewIPv4AddrAnnouncer 创建IPv4 Gratuitous ARP广播
@@ -84,33 +175,58 @@ func NewIPv4AddrAnnouncer(nif *net.Interface) (*IPv4AddrAnnouncer, error) {
 
 // AnnounceAll 广播 gratuitous ARP response 包含所有的IPv4虚拟IP地址
 func (ar *IPv4AddrAnnouncer) AnnounceAll(vr *VirtualRouter) error {
+	if len(vr.ownerMAC()) == 0 {
+		return fmt.Errorf("IPv4AddrAnnouncer.AnnounceAll: %w", ErrNoHardwareAddr)
+	}
 	if err := ar.ARPClient.SetWriteDeadline(time.Now().Add(500 * time.Microsecond)); err != nil {
 		return err
 	}
-	// 构造 gratuitous ARP response
-	var packet arp.Packet
-	packet.HardwareType = 1       // ethernet
-	packet.ProtocolType = 0x0800  // IPv4 protocol
-	packet.HardwareAddrLength = 6 // ethernet mac address length
-	packet.IPLength = 4           // IPv4 address length
-	packet.Operation = 2          // Type response
-
-	for k := range vr.protectedIPaddrs {
-		packet.SenderHardwareAddr = vr.ift.HardwareAddr
-		packet.SenderIP = k
-		packet.TargetHardwareAddr = BroadcastHADAR
-		packet.TargetIP = k
-		logg.Printf("send gratuitous arp for %s", k.String())
-		if err := ar.ARPClient.WriteTo(&packet, BroadcastHADAR); err != nil {
+	// 虚拟IP地址集合可能被并发修改，遍历快照
+	for _, k := range vr.vipAddrs() {
+		vr.logger().Printf("send gratuitous arp for %s", k.String())
+		if err := ar.ARPClient.WriteTo(gratuitousARP(vr, k), BroadcastHADAR); err != nil {
 			return fmt.Errorf("IPv4AddrAnnouncer.AnnounceAll: %v", err)
 		}
 	}
 	return nil
 }
 
-func (ar *IPv4AddrAnnouncer) Close() error {
-	if ar != nil && ar.ARPClient != nil {
-		return ar.ARPClient.Close()
+// gratuitousARP 构造虚拟IP地址的 gratuitous ARP response
+func gratuitousARP(vr *VirtualRouter, vip netip.Addr) *arp.Packet {
+	return &arp.Packet{
+		HardwareType:       1,      // ethernet
+		ProtocolType:       0x0800, // IPv4 protocol
+		HardwareAddrLength: 6,      // ethernet mac address length
+		IPLength:           4,      // IPv4 address length
+		Operation:          2,      // Type response
+		SenderHardwareAddr: vr.ownerMAC(),
+		SenderIP:           vip,
+		TargetHardwareAddr: BroadcastHADAR,
+		TargetIP:           vip,
 	}
+}
+
+// Rebind 使用网口的当前信息（如变化后的MAC地址）重新创建 ARP 客户端
+func (ar *IPv4AddrAnnouncer) Rebind(nif *net.Interface) error {
+	client, err := arp.Dial(nif)
+	if err != nil {
+		return fmt.Errorf("IPv4AddrAnnouncer.Rebind: %v", err)
+	}
+	if ar.ARPClient != nil {
+		_ = ar.ARPClient.Close()
+	}
+	ar.ARPClient = client
 	return nil
 }
+
+func (ar *IPv4AddrAnnouncer) Close() error {
+	if ar == nil {
+		return nil
+	}
+	ar.closeOnce.Do(func() {
+		if ar.ARPClient != nil {
+			ar.closeErr = ar.ARPClient.Close()
+		}
+	})
+	return ar.closeErr
+}
@@ -0,0 +1,172 @@
+package govrrp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mdlayher/ndp"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestGratuitousARP_SenderMAC(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vip := netip.MustParseAddr("192.168.0.200")
+
+	packet := gratuitousARP(vr, vip)
+	if !bytes.Equal(packet.SenderHardwareAddr, vr.ift.HardwareAddr) {
+		t.Errorf("expect interface MAC %s, got %s", vr.ift.HardwareAddr, packet.SenderHardwareAddr)
+	}
+	if packet.SenderIP != vip || packet.TargetIP != vip {
+		t.Errorf("unexpected sender/target IP %s/%s", packet.SenderIP, packet.TargetIP)
+	}
+
+	vr.useVirtualMAC = true
+	packet = gratuitousARP(vr, vip)
+	if packet.SenderHardwareAddr.String() != "00:00:5e:00:01:f0" {
+		t.Errorf("expect virtual MAC 00:00:5e:00:01:f0, got %s", packet.SenderHardwareAddr)
+	}
+	if !bytes.Equal(packet.SenderHardwareAddr, vr.ownerMAC()) {
+		t.Error("sender MAC should match the owning MAC")
+	}
+}
+
+func TestUnsolicitedNeighborAdvertisement_SenderMAC(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "fe80::10", 100)
+	vip := netip.MustParseAddr("2001:db8::200")
+	for _, virtual := range []bool{false, true} {
+		vr.useVirtualMAC = virtual
+		msg := unsolicitedNeighborAdvertisement(vr, vip)
+		lla := msg.Options[0].(*ndp.LinkLayerAddress)
+		if !bytes.Equal(lla.Addr, vr.ownerMAC()) {
+			t.Errorf("virtual=%v: expect %s, got %s", virtual, vr.ownerMAC(), lla.Addr)
+		}
+	}
+	if vr.ownerMAC().String() != "00:00:5e:00:02:f0" {
+		t.Errorf("unexpected IPv6 virtual MAC %s", vr.ownerMAC())
+	}
+}
+
+func TestNewAddrAnnouncer_NonEthernet(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0, 0, 0, 0, 1}
+	for _, ift := range []*net.Interface{
+		{Index: 1, Name: "lo", Flags: net.FlagUp | net.FlagLoopback, HardwareAddr: mac},
+		{Index: 6, Name: "ppp0", Flags: net.FlagUp | net.FlagPointToPoint, HardwareAddr: mac},
+		{Index: 7, Name: "ib0", Flags: net.FlagUp, HardwareAddr: make(net.HardwareAddr, 20)},
+	} {
+		for _, ipvX := range []byte{IPv4, IPv6} {
+			announcer, err := newAddrAnnouncer(ift, ipvX)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := announcer.(noopAnnouncer); !ok {
+				t.Errorf("expect announcer to be disabled on %s, got %T", ift.Name, announcer)
+			}
+		}
+	}
+	if reason := nonEthernetReason(&net.Interface{Name: "eth0", Flags: net.FlagUp, HardwareAddr: mac}); reason != "" {
+		t.Errorf("expect Ethernet interface, got %q", reason)
+	}
+}
+
+func TestNewAddrAnnouncer_NoHardwareAddr(t *testing.T) {
+	ift := &net.Interface{Index: 5, Name: "tun0", Flags: net.FlagUp | net.FlagPointToPoint}
+	announcer, err := newAddrAnnouncer(ift, IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := announcer.(noopAnnouncer); !ok {
+		t.Errorf("expect announcer to be disabled on interface without MAC, got %T", announcer)
+	}
+
+	vr, err := newVirtualRouter(240, ift, net.ParseIP("10.0.0.1"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr.addrAnnouncer = announcer
+	if mac := vr.GetEffectiveMAC(); len(mac) != 0 {
+		t.Errorf("expect no effective MAC, got %s", mac)
+	}
+	if err = announcer.AnnounceAll(vr); err != nil {
+		t.Errorf("disabled announcer should not fail, got %v", err)
+	}
+	if err = (&IPv4AddrAnnouncer{}).AnnounceAll(vr); !errors.Is(err, ErrNoHardwareAddr) {
+		t.Errorf("expect ErrNoHardwareAddr, got %v", err)
+	}
+}
+
+func TestVirtualRouter_DisableAddrAnnouncer(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	if vr.GetEffectiveMAC().String() != "02:00:00:00:00:01" {
+		t.Errorf("unexpected effective MAC %s", vr.GetEffectiveMAC())
+	}
+	vr.DisableAddrAnnouncer()
+	if _, ok := vr.addrAnnouncer.(noopAnnouncer); !ok {
+		t.Errorf("expect disabled announcer, got %T", vr.addrAnnouncer)
+	}
+}
+
+func TestIPv6_LinkLocalSourceGlobalVIP(t *testing.T) {
+	linkLocal := net.ParseIP("fe80::10")
+	if got := ipv6ControlSource(net.ParseIP("2001:db8::10"), func() (net.IP, error) { return linkLocal, nil }); !got.Equal(linkLocal) {
+		t.Errorf("expect link-local source %v for advertisements, got %v", linkLocal, got)
+	}
+	if got := ipv6ControlSource(linkLocal, func() (net.IP, error) { return nil, errors.New("unexpected") }); !got.Equal(linkLocal) {
+		t.Errorf("expect link-local source kept, got %v", got)
+	}
+
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 240, "fe80::10", 255)
+	peer := network.dial(net.ParseIP("fe80::20"))
+	global := net.ParseIP("2001:db8::200")
+	vr.AddIPvXAddr(global)
+
+	if err := vr.SendOneAdvertisement(); err != nil {
+		t.Fatal(err)
+	}
+	res := <-peer.in
+	if !res.pkt.Pshdr.Saddr.IsLinkLocalUnicast() {
+		t.Errorf("expect advertisement source to be link-local, got %v", res.pkt.Pshdr.Saddr)
+	}
+	if addrs := res.pkt.GetIPAddrs(); len(addrs) != 1 || addrs[0] != netip.MustParseAddr("2001:db8::200") {
+		t.Errorf("expect advertisement to carry global VIP, got %v", addrs)
+	}
+
+	var targets []netip.Addr
+	vr.RangeVIPs(func(ip net.IP) bool {
+		key, _ := netip.AddrFromSlice(ip)
+		targets = append(targets, unsolicitedNeighborAdvertisement(vr, key).TargetAddress)
+		return true
+	})
+	if len(targets) != 1 || targets[0] != netip.MustParseAddr("2001:db8::200") {
+		t.Errorf("expect NA to target global VIP, got %v", targets)
+	}
+}
+
+func TestNewIPIPv6AddrAnnouncer_NoLinkLocal(t *testing.T) {
+	// 不存在的网口，没有任何地址
+	ift := &net.Interface{Index: 65000, Name: "fake0", Flags: net.FlagUp | net.FlagMulticast, HardwareAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 1}}
+	_, err := NewIPIPv6AddrAnnouncer(ift)
+	if !errors.Is(err, ErrNoLinkLocal) || !strings.Contains(err.Error(), "fake0") {
+		t.Fatalf("expect descriptive ErrNoLinkLocal, got %v", err)
+	}
+
+	announcer, err := newAddrAnnouncer(ift, IPv6)
+	if err != nil {
+		t.Fatalf("router construction should fall back to disabled announcer, got %v", err)
+	}
+	if _, ok := announcer.(noopAnnouncer); !ok {
+		t.Errorf("expect disabled announcer, got %T", announcer)
+	}
+}
+
+func TestAddrAnnouncer_CloseIdempotent(t *testing.T) {
+	for _, a := range []AddrAnnouncer{&IPv4AddrAnnouncer{}, &IPv6AddrAnnouncer{}, (*IPv4AddrAnnouncer)(nil), (*IPv6AddrAnnouncer)(nil), noopAnnouncer{}} {
+		for i := 0; i < 2; i++ {
+			if err := a.Close(); err != nil {
+				t.Errorf("%T close #%d: %v", a, i+1, err)
+			}
+		}
+	}
+}
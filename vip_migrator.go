@@ -0,0 +1,133 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+)
+
+// AddrBackend 网口地址管理接口，用于在网口上添加、删除虚拟IP地址，
+// Linux 下可基于 netlink 实现（如 netlink.AddrReplace / netlink.AddrDel）。
+type AddrBackend interface {
+	// AddAddr 在指定网口上添加地址
+	AddAddr(ifName string, addr netip.Prefix) error
+	// DelAddr 从指定网口上删除地址
+	DelAddr(ifName string, addr netip.Prefix) error
+}
+
+// VIPMigrator 虚拟IP地址迁移器，用于虚拟IP地址需要根据上行链路在本机的两个网口之间迁移的场景。
+// 将多个虚拟路由器（通常分别工作在不同网口上）关联至同一迁移器后，
+// 任一虚拟路由器成为主节点时，虚拟IP地址从原网口删除并添加至该虚拟路由器的工作网口；
+// 持有地址的虚拟路由器失去主节点身份时删除地址。保证同一时刻虚拟IP地址最多只在一个网口上。
+type VIPMigrator struct {
+	mu      sync.Mutex
+	backend AddrBackend
+	addrs   []netip.Prefix
+	current string // 当前持有虚拟IP地址的网口名称，为空表示没有网口持有
+}
+
+// NewVIPMigrator 创建虚拟IP地址迁移器
+// backend: 网口地址管理接口
+// addrs: 需要迁移的虚拟IP地址（含前缀长度）
+func NewVIPMigrator(backend AddrBackend, addrs ...netip.Prefix) *VIPMigrator {
+	return &VIPMigrator{backend: backend, addrs: addrs}
+}
+
+// Attach 关联虚拟路由器，注册状态变更监听器：
+// 进入 MASTER 状态时将虚拟IP地址迁移至其工作网口，离开 MASTER 状态时释放其持有的虚拟IP地址。
+// 迁移失败时记录日志并写入虚拟路由器的异步错误通道（操作类型为 ErrorOpMigrate）。需在 Start 前调用。
+func (m *VIPMigrator) Attach(routers ...*VirtualRouter) *VIPMigrator {
+	acquire := func(r *VirtualRouter) {
+		if err := m.MoveTo(r.ift.Name); err != nil {
+			r.logger().Printf("ERROR VRID [%d] migrate VIP to %s: %v", r.vrID, r.ift.Name, err)
+			r.reportError(ErrorOpMigrate, err)
+		}
+	}
+	release := func(r *VirtualRouter) {
+		if err := m.Release(r.ift.Name); err != nil {
+			r.logger().Printf("ERROR VRID [%d] release VIP from %s: %v", r.vrID, r.ift.Name, err)
+			r.reportError(ErrorOpMigrate, err)
+		}
+	}
+	for _, r := range routers {
+		r.AppendEventListener(Backup2Master, acquire).
+			AppendEventListener(Init2Master, acquire).
+			AppendEventListener(Master2Backup, release).
+			AppendEventListener(Master2Init, release)
+	}
+	return m
+}
+
+// MoveTo 将虚拟IP地址迁移至指定网口：先从原网口删除，再添加至新网口。
+// 添加失败时尝试恢复至原网口，并返回错误。
+func (m *VIPMigrator) MoveTo(ifName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == ifName {
+		return nil
+	}
+	old := m.current
+	if old != "" {
+		if err := m.delAll(old); err != nil {
+			return err
+		}
+		m.current = ""
+	}
+	if err := m.addAll(ifName); err != nil {
+		if old != "" {
+			if rerr := m.addAll(old); rerr != nil {
+				return errors.Join(err, fmt.Errorf("restore VIP on %s: %w", old, rerr))
+			}
+			m.current = old
+		}
+		return err
+	}
+	m.current = ifName
+	return nil
+}
+
+// Release 若指定网口持有虚拟IP地址，则将其删除；其他网口持有时不做任何操作
+func (m *VIPMigrator) Release(ifName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != ifName {
+		return nil
+	}
+	if err := m.delAll(ifName); err != nil {
+		return err
+	}
+	m.current = ""
+	return nil
+}
+
+// Current 获取 当前持有虚拟IP地址的网口名称，没有网口持有时返回空
+func (m *VIPMigrator) Current() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// addAll 在网口上添加所有虚拟IP地址，失败时删除已添加的地址
+func (m *VIPMigrator) addAll(ifName string) error {
+	for i, addr := range m.addrs {
+		if err := m.backend.AddAddr(ifName, addr); err != nil {
+			for _, added := range m.addrs[:i] {
+				_ = m.backend.DelAddr(ifName, added)
+			}
+			return fmt.Errorf("add %v to %s: %w", addr, ifName, err)
+		}
+	}
+	return nil
+}
+
+// delAll 从网口上删除所有虚拟IP地址
+func (m *VIPMigrator) delAll(ifName string) error {
+	var errs []error
+	for _, addr := range m.addrs {
+		if err := m.backend.DelAddr(ifName, addr); err != nil {
+			errs = append(errs, fmt.Errorf("delete %v from %s: %w", addr, ifName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package govrrp
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAddrBackend 记录各网口上地址的 AddrBackend
+type fakeAddrBackend struct {
+	mu     sync.Mutex
+	addrs  map[string]map[netip.Prefix]bool
+	failOn string // 向该网口添加地址时返回错误
+}
+
+func (b *fakeAddrBackend) AddAddr(ifName string, addr netip.Prefix) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ifName == b.failOn {
+		return errors.New("add failed")
+	}
+	if b.addrs == nil {
+		b.addrs = make(map[string]map[netip.Prefix]bool)
+	}
+	if b.addrs[ifName] == nil {
+		b.addrs[ifName] = make(map[netip.Prefix]bool)
+	}
+	b.addrs[ifName][addr] = true
+	return nil
+}
+
+func (b *fakeAddrBackend) DelAddr(ifName string, addr netip.Prefix) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.addrs[ifName], addr)
+	return nil
+}
+
+// holders 返回持有指定地址的网口
+func (b *fakeAddrBackend) holders(addr netip.Prefix) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var res []string
+	for name, addrs := range b.addrs {
+		if addrs[addr] {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+func TestVIPMigrator_Failover(t *testing.T) {
+	network := &memNetwork{}
+	primary, _ := newTestRouter(t, network, 240, "192.168.0.20", 200)
+	secondary, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	secondary.ift = &net.Interface{Index: 2, Name: "mem1", HardwareAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 2}}
+
+	vip := netip.MustParsePrefix("192.168.0.100/24")
+	backend := &fakeAddrBackend{}
+	migrator := NewVIPMigrator(backend, vip).Attach(primary, secondary)
+
+	waitHolder := func(name string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			holders := backend.holders(vip)
+			if len(holders) == 1 && holders[0] == name && migrator.Current() == name {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expect VIP only on %s, got %v", name, holders)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	startRouter(t, secondary)
+	startRouter(t, primary)
+	if !waitState(primary, MASTER, time.Second) {
+		t.Fatal("the primary router should become master")
+	}
+	waitHolder("mem0")
+
+	// 模拟主节点所在上行链路故障
+	primary.Stop()
+	if !waitState(secondary, MASTER, time.Second) {
+		t.Fatal("the secondary router should take over")
+	}
+	waitHolder("mem1")
+}
+
+func TestVIPMigrator_MoveToRollback(t *testing.T) {
+	vip := netip.MustParsePrefix("fe80::100/64")
+	backend := &fakeAddrBackend{failOn: "mem1"}
+	migrator := NewVIPMigrator(backend, vip)
+	if err := migrator.MoveTo("mem0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.MoveTo("mem1"); err == nil {
+		t.Fatal("expect error when adding to mem1 fails")
+	}
+	if holders := backend.holders(vip); len(holders) != 1 || holders[0] != "mem0" || migrator.Current() != "mem0" {
+		t.Errorf("expect VIP restored on mem0, got %v", holders)
+	}
+	if err := migrator.Release("mem1"); err != nil || migrator.Current() != "mem0" {
+		t.Errorf("releasing from a non-holder should be a no-op, got %v", err)
+	}
+	if err := migrator.Release("mem0"); err != nil || len(backend.holders(vip)) != 0 {
+		t.Errorf("expect VIP released, got %v %v", err, backend.holders(vip))
+	}
+}
@@ -0,0 +1,36 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVirtualMACUnsupported 当前平台不支持将虚拟MAC地址加入网口的地址过滤表
+var ErrVirtualMACUnsupported = errors.New("adding virtual MAC to interface is not supported")
+
+// SetUseVirtualMAC 设置 是否以 RFC 5798 7.3 定义的虚拟MAC地址（00-00-5E-00-01-{VRID} / 00-00-5E-00-02-{VRID}）
+// 发送 ARP/NDP 广播，默认值为 false，使用工作网口的MAC地址。
+//
+// 开启后主机以虚拟MAC地址应答虚拟IP地址，网口需要接收目的MAC为虚拟MAC地址的帧，
+// 因此将虚拟MAC地址加入工作网口的单播地址过滤表（Linux AF_PACKET PACKET_MR_UNICAST，需要 CAP_NET_RAW 权限），
+// 虚拟路由器停止或关闭该设置时移除。无法加入时返回错误且设置不生效，其他平台返回 ErrVirtualMACUnsupported。需在 Start 前调用。
+func (r *VirtualRouter) SetUseVirtualMAC(flag bool) error {
+	if r.vmacFilter != nil {
+		_ = r.vmacFilter.Close()
+		r.vmacFilter = nil
+	}
+	r.useVirtualMAC = false
+	r.connOpts.vmac = false
+	if !flag {
+		return nil
+	}
+	filter, err := addMACFilter(r.ift, r.virtualMAC())
+	if err != nil {
+		return fmt.Errorf("VRID [%d] add virtual MAC %v to %s: %w", r.vrID, r.virtualMAC(), r.ift.Name, err)
+	}
+	r.vmacFilter = filter
+	r.useVirtualMAC = true
+	r.connOpts.vmac = true
+	r.logger().Printf("VRID [%d] virtual IP addresses announced with virtual MAC %v", r.vrID, r.virtualMAC())
+	return nil
+}
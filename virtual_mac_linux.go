@@ -0,0 +1,40 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"golang.org/x/sys/unix"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// macFilter 持有单播地址过滤表成员关系的 AF_PACKET 套接字，关闭套接字时内核移除对应的MAC地址
+type macFilter struct {
+	fd        int
+	closeOnce sync.Once // 确保套接字仅关闭一次
+	closeErr  error     // 关闭的结果
+}
+
+func (f *macFilter) Close() error {
+	f.closeOnce.Do(func() {
+		f.closeErr = unix.Close(f.fd)
+	})
+	return f.closeErr
+}
+
+// addMACFilter 通过 PACKET_MR_UNICAST 成员关系将MAC地址加入网口的单播地址过滤表
+func addMACFilter(ift *net.Interface, mac net.HardwareAddr) (io.Closer, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	mreq := unix.PacketMreq{Ifindex: int32(ift.Index), Type: unix.PACKET_MR_UNICAST, Alen: uint16(len(mac))}
+	copy(mreq.Address[:], mac)
+	if err = unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &mreq); err != nil {
+		_ = unix.Close(fd)
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	return &macFilter{fd: fd}, nil
+}
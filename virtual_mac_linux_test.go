@@ -0,0 +1,63 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mdlayher/ndp"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+)
+
+func TestVirtualRouter_SetUseVirtualMAC(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skipf("loopback interface: %v", err)
+	}
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.ift = lo
+	if err = vr.SetUseVirtualMAC(true); err != nil {
+		if errors.Is(err, syscall.EPERM) {
+			t.Skipf("AF_PACKET requires CAP_NET_RAW: %v", err)
+		}
+		t.Fatal(err)
+	}
+	vip := netip.MustParseAddr("192.168.0.200")
+	if mac := gratuitousARP(vr, vip).SenderHardwareAddr; mac.String() != "00:00:5e:00:01:f0" {
+		t.Errorf("gratuitous ARP sender MAC %v, want virtual MAC 00:00:5e:00:01:f0", mac)
+	}
+	if !vr.connOpts.vmac || vr.vmacFilter == nil {
+		t.Error("virtual MAC setting should be kept for reopened connections")
+	}
+
+	if err = vr.SetUseVirtualMAC(false); err != nil {
+		t.Fatal(err)
+	}
+	if mac := gratuitousARP(vr, vip).SenderHardwareAddr; !bytes.Equal(mac, vr.interfaceMAC()) {
+		t.Errorf("gratuitous ARP sender MAC %v after disabling, want interface MAC %v", mac, vr.interfaceMAC())
+	}
+
+	// IPv6 使用 00-00-5E-00-02-{VRID}
+	vr6, _ := newTestRouter(t, nil, 240, "fe80::10", 100)
+	vr6.ift = lo
+	if err = vr6.SetUseVirtualMAC(true); err != nil {
+		t.Fatal(err)
+	}
+	defer vr6.close()
+	lla := unsolicitedNeighborAdvertisement(vr6, netip.MustParseAddr("2001:db8::200")).Options[0].(*ndp.LinkLayerAddress)
+	if lla.Addr.String() != "00:00:5e:00:02:f0" {
+		t.Errorf("neighbor advertisement link-layer address %v, want 00:00:5e:00:02:f0", lla.Addr)
+	}
+
+	// 无法加入地址过滤表时返回错误且设置不生效
+	vr.ift = &net.Interface{Index: 1 << 20, Name: "missing0"}
+	if err = vr.SetUseVirtualMAC(true); err == nil {
+		t.Fatal("adding virtual MAC to a missing interface should fail")
+	}
+	if vr.useVirtualMAC || vr.connOpts.vmac {
+		t.Error("virtual MAC should not be used after a failed SetUseVirtualMAC")
+	}
+}
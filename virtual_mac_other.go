@@ -0,0 +1,13 @@
+//go:build !linux
+
+package govrrp
+
+import (
+	"io"
+	"net"
+)
+
+// addMACFilter 当前平台不支持将MAC地址加入网口的地址过滤表
+func addMACFilter(*net.Interface, net.HardwareAddr) (io.Closer, error) {
+	return nil, ErrVirtualMACUnsupported
+}
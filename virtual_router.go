@@ -1,22 +1,39 @@
 package govrrp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
 	"net"
 	"net/netip"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
-var logg = log.New(os.Stdout, "[govrrp] ", log.LstdFlags)
+// defaultLogger 默认日志记录器，未设置虚拟路由日志记录器时使用
+var defaultLogger atomic.Pointer[log.Logger]
 
-// SetDefaultLogger 设置默认日志记录器
-func SetDefaultLogger(l *log.Logger) {
-	if l != nil {
-		logg = l
+func init() {
+	defaultLogger.Store(log.New(os.Stdout, "[govrrp] ", log.LstdFlags))
+}
+
+// logg 获取 默认日志记录器
+func logg() *log.Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefaultLogger 设置默认日志记录器，返回之前的日志记录器，便于恢复。
+// l 为 nil 时不做修改，返回当前的日志记录器。
+func SetDefaultLogger(l *log.Logger) *log.Logger {
+	if l == nil {
+		return defaultLogger.Load()
 	}
+	return defaultLogger.Swap(l)
 }
 
 // VirtualRouter 虚拟路由器，实现了VRRP协议的状态机
@@ -30,33 +47,137 @@ type VirtualRouter struct {
 	// 高优先级备份路由器是否抢占低优先级主路由器。
 	// 值为 true 表示 允许抢占，值为 false 表示 禁止抢占。默认值为 true。
 	preempt bool
-
-	// 为了防止与区域网内的其他VRRP路由器冲突，暂时不使用虚拟MAC地址，而是使用工作网口接口的MAC地址
+	// preemptEqualPriority 优先级相同时，是否允许源IP地址较大的备份路由器抢占主路由器。默认值为 true。
+	preemptEqualPriority bool
+	// accept 接受模式（RFC 5798 6.1 Accept_Mode），控制非地址拥有者的主路由器是否接收目的地址为虚拟IP地址的数据包。
+	// 默认值为 false，地址拥有者（优先级 255）始终视为 true，见 SetAcceptMode。
+	accept bool
+	// preemptDelay 抢占延迟，备份路由器首次具备抢占条件后等待该时长才抢占主路由器，0 表示立即抢占
+	preemptDelay time.Duration
+	// preemptDeadline 当前抢占延迟的截止时间（UnixNano），0 表示没有进行中的抢占延迟
+	preemptDeadline atomic.Int64
+	// minMasterDwell 最短主节点保持时间，成为主节点后在该时间内不因相同优先级的源IP地址比较而让渡，0 表示不限制
+	minMasterDwell time.Duration
+	// masterSince 最近一次成为主节点的时间，仅在状态机协程中访问
+	masterSince time.Time
+	// suppressInitialAdvert 地址拥有者启动进入 MASTER 状态时是否不立即发送心跳消息，仅用于测试与实验环境
+	suppressInitialAdvert bool
+	// zeroAddrPolicy 收到未携带虚拟IP地址（Count IPvX Addr 为 0）的心跳消息时的处理策略
+	zeroAddrPolicy ZeroAddrPolicy
+	// knownOwners 已知的地址拥有者源IP地址，见 SetKnownOwners
+	knownOwners []netip.Addr
+	// suspiciousPriorityHandler 收到非已知地址拥有者发出的优先级 255 心跳消息时的回调函数
+	suspiciousPriorityHandler func(src net.IP)
+	// rejectSuspiciousPriority 是否丢弃非已知地址拥有者发出的优先级 255 心跳消息
+	rejectSuspiciousPriority bool
+	// strictFamily 是否丢弃地址序列与协议类型不一致的心跳消息
+	strictFamily bool
+	// electionPolicy 选举策略，为 nil 时使用 RFCElectionPolicy
+	electionPolicy ElectionPolicy
+	// preferredMaster 是否为首选主节点，见 SetPreferredMaster
+	preferredMaster bool
+	// preferredMasterBump 首选主节点提升的优先级，0 表示默认值
+	preferredMasterBump byte
+
+	// 为了防止与区域网内的其他VRRP路由器冲突，默认不使用虚拟MAC地址，而是使用工作网口接口的MAC地址，见 SetUseVirtualMAC
 	virtualRouterMACAddressIPv4 net.HardwareAddr // IPv4 虚拟MAC地址
 	virtualRouterMACAddressIPv6 net.HardwareAddr // IPv6 虚拟MAC地址
-
-	advertisementInterval         uint16 // VRRP消息发送间隔时间（心跳间隔）
-	advertisementIntervalOfMaster uint16 // 主节点发出VRRP消息的间隔时间（心跳间隔）
-	skewTime                      uint16 // Skew_Time 用于根据节点的优先级计算 masterDownInterval
-	masterDownInterval            uint16 // 主节点失效时间，主节点在该时间内未发出VRRP消息则认为主节点失效
-
-	ift               *net.Interface      // 工作网口接口
-	ipvX              byte                // IP协议类型(IPv4 或 IPv6)
-	preferredSourceIP net.IP              // 优先使用的源IP地址（工作网口接口的IP地址）
-	protectedIPaddrs  map[netip.Addr]bool // 虚拟IP地址集合
+	useVirtualMAC               bool             // 是否使用虚拟MAC地址应答虚拟IP地址
+	vmacFilter                  io.Closer        // 虚拟MAC地址在网口单播地址过滤表中的成员关系，关闭时移除
+
+	advertisementInterval         uint16         // VRRP消息发送间隔时间（心跳间隔）
+	advertisementIntervalOfMaster uint16         // 主节点发出VRRP消息的间隔时间（心跳间隔）
+	skewTime                      uint16         // Skew_Time 用于根据节点的优先级计算 masterDownInterval
+	masterDownInterval            uint16         // 主节点失效时间，主节点在该时间内未发出VRRP消息则认为主节点失效
+	missedAdvertThreshold         int            // 触发故障切换的连续丢失心跳消息数量，0 表示默认值 3
+	masterDownJitter              float64        // 主节点下线倒计时随机抖动比例 [0, 1]，抖动范围为 Skew_Time 的该比例
+	jitterRand                    func() float64 // 抖动使用的随机数 [0, 1)，便于测试替换
+	remoteAdvInterval             atomic.Uint32  // BACKUP 状态下观测到的主节点心跳间隔，0 表示尚未观测到
+
+	ift               *net.Interface                   // 工作网口接口
+	hwAddr            atomic.Pointer[net.HardwareAddr] // 工作网口当前的MAC地址，绑定网卡切换主网卡时可能变化
+	ipvX              byte                             // IP协议类型(IPv4 或 IPv6)
+	version           VRRPVersion                      // 发送与接受的VRRP协议版本，默认为 VRRPv3
+	preferredSourceIP net.IP                           // 优先使用的源IP地址（工作网口接口的IP地址）
+	protectedIPaddrs  map[netip.Addr]net.IP            // 虚拟IP地址集合，值为该地址的规范形式
+	vipMu             sync.RWMutex                     // 虚拟IP地址集合读写锁
 
 	vrrpConn      VRRPMsgConnection // VRRP数据包收发送接口，用于发送和接收VRRP数据包。
 	addrAnnouncer AddrAnnouncer     // 虚拟IP地址广播器，用于向其他主机广播虚拟IP地址。
+	l2Writer      frameWriter       // 二层心跳消息发送接口，为空表示通过 vrrpConn 发送，见 SetL2Advertisement
+	connOpts      connOptions       // 已应用到连接上的设置，重新打开连接时再次应用
+
+	// dial 创建VRRP数据包收发接口与虚拟IP地址广播器，停止后再次启动时用于重新打开连接
+	dial func() (VRRPMsgConnection, AddrAnnouncer, error)
 
 	eventChannel chan EVENT       // 事件通道
+	trackChanged chan struct{}    // 跟踪对象状态变更通知
+	macChanged   chan struct{}    // 工作网口MAC地址变更通知
+	errs         chan error       // 异步错误通道，见 Errors
 	packetQueue  chan *VRRPPacket // VRRP数据包队列
+	exited       chan struct{}    // 状态机退出后关闭
+	running      atomic.Bool      // 状态机是否正在运行
+	stopping     atomic.Bool      // 已请求停止，状态机因停止事件进入 INIT 状态后直接退出
+	closed       chan struct{}    // 连接等资源回收后关闭
+	closeOnce    sync.Once        // 确保连接等资源仅回收一次
 
 	advertisementTicker *time.Ticker // VRRP消息发送定时器
 	masterDownTimer     *time.Timer  // 主节点失效倒计时
 
 	// 状态转换处理函数集合，用于注册用户监听的状态处理函数
 	// 当状态机状态发生变化时，将调用对应的处理函数
-	transitionHandler map[transition]func(*VirtualRouter)
+	transitionHandler map[transition][]func(*VirtualRouter)
+
+	log     atomic.Pointer[log.Logger] // 虚拟路由日志记录器，为空时使用默认日志记录器
+	tap     rawTapHolder               // 原始报文监听函数
+	tracks  map[string]*trackState     // 跟踪对象集合
+	trackMu sync.Mutex                 // 跟踪对象集合锁
+
+	debug  atomic.Bool // 是否开启调试日志
+	paused atomic.Bool // 是否已暂停，暂停期间不发送心跳消息、忽略收到的心跳消息，但保持连接
+	stats  counters    // 运行统计计数器
+	drops  dropReasons // 丢弃原因计数器
+	peers  peerTable   // 同一虚拟路由ID下观测到的对端路由器
+
+	lockDir  string   // 实例锁文件所在目录，为空表示不使用实例锁
+	lockFile *os.File // 已持有的实例锁文件
+
+	mastershipLostHandler func(reason string, peer net.IP)   // 失去主节点身份时的回调函数
+	lastMastershipLost    atomic.Pointer[mastershipLossInfo] // 最近一次失去主节点身份的原因
+	currentMaster         atomic.Pointer[masterInfo]         // BACKUP 状态下最近一次接受的主节点心跳消息，见 GetMasterAddr
+	lastElection          atomic.Pointer[string]             // 最近一次成为或保持主节点的原因
+	electionCandidate     string                             // BACKUP 状态下比较对端心跳消息后准备接管的原因，仅在状态机协程中访问
+
+	packetMutator      atomic.Pointer[func(*VRRPPacket)] // 心跳消息发送前的修改函数
+	sentObserver       atomic.Pointer[func(*VRRPPacket)] // 心跳消息发送成功后的观察函数
+	sendErrorHandler   func(error)                       // 心跳消息发送失败时的回调函数
+	sendErrorThreshold int                               // 连续发送失败次数阈值，超过后主节点进入 INIT 状态，0 表示不限制
+	sendFailures       int                               // 当前连续发送失败次数
+
+	advertDelayHandler   func(actual, expected time.Duration) // 定时心跳延迟发送时的回调函数
+	advertDelayTolerance time.Duration                        // 定时心跳允许的延迟，0 表示使用心跳间隔的一半
+	lastAdvertTick       time.Time                            // 最近一次心跳定时器触发的时间，仅在状态机协程中访问
+
+	advertLimiter rateLimiter      // 立即发送心跳消息的限速器，定时心跳不受限制
+	now           func() time.Time // 时钟，便于测试替换
+
+	clock              stateClock    // 运行时长与各状态的累计时长，见 Uptime
+	lastProgress       atomic.Int64  // 状态机最近一次取得进展的时间（UnixNano）
+	watchdogStall      time.Duration // 状态机停滞判定时间，0 表示不开启看门狗
+	watchdogForceClose bool          // 状态机停滞时是否强制关闭连接
+
+	macCheckInterval time.Duration                  // 检查工作网口MAC地址变化的时间间隔，0 表示不检查
+	lookupInterface  func() (*net.Interface, error) // 查询工作网口的当前信息，便于测试替换
+
+	linkMonitorInterval time.Duration // 检查工作网口链路状态的时间间隔，0 表示不检查
+	linkDown            atomic.Bool   // 是否因工作网口链路断开进入 INIT 状态
+
+	sourceSelector SourceSelector             // 源IP地址选择函数，nil 表示选择第一个符合条件的地址
+	autoSource     bool                       // 源地址是否根据网口地址自动选择（通过网口名称创建）
+	interfaceAddrs func() ([]net.Addr, error) // 查询工作网口的地址，便于测试替换
+
+	sendRetry      atomic.Bool      // 是否因连续发送失败进入 INIT 状态，等待重新参与选举
+	sendRetryTimer <-chan time.Time // 因连续发送失败进入 INIT 状态后重新参与选举的定时器，仅在状态机协程中访问
 }
 
 // NewVirtualRouterSpec 创建一个虚拟路由器实例
@@ -65,7 +186,69 @@ type VirtualRouter struct {
 // preferIP: 优先使用的源IP地址，请确保工作网口配置由该IP地址保持一致。
 // priority: 优先级，255 表示主节点，0 为特殊值不可使用，默认100。
 func NewVirtualRouterSpec(VRID byte, ift *net.Interface, preferIP net.IP, priority byte) (*VirtualRouter, error) {
-	var err error
+	return newVirtualRouterSpec(VRID, ift, preferIP, priority, newAddrAnnouncer)
+}
+
+// newVirtualRouterSpec 创建虚拟路由器，虚拟IP地址广播器由 newAnnouncer 创建
+func newVirtualRouterSpec(VRID byte, ift *net.Interface, preferIP net.IP, priority byte,
+	newAnnouncer func(*net.Interface, byte) (AddrAnnouncer, error)) (*VirtualRouter, error) {
+	vr, err := newVirtualRouter(VRID, ift, preferIP, priority)
+	if err != nil {
+		return nil, err
+	}
+	if vr.ipvX == IPv6 {
+		vr.preferredSourceIP = ipv6ControlSource(vr.preferredSourceIP, func() (net.IP, error) {
+			return interfacePreferIP(ift, IPv6)
+		})
+	}
+
+	vr.dial = func() (VRRPMsgConnection, AddrAnnouncer, error) {
+		// 创建 虚拟IP地址广播器
+		announcer, err := newAnnouncer(ift, vr.ipvX)
+		if err != nil {
+			return nil, nil, err
+		}
+		var conn VRRPMsgConnection
+		if vr.ipvX == IPv4 {
+			// 创建IPv4接口 (组播)
+			conn, err = NewIPv4VRRPMsgConn(ift, vr.preferredSourceIP, VRRPMultiAddrIPv4)
+		} else {
+			// 创建IPv6接口 (组播)
+			conn, err = NewIPv6VRRPMsgCon(ift, vr.preferredSourceIP, VRRPMultiAddrIPv6)
+		}
+		if err != nil {
+			_ = announcer.Close()
+			return nil, nil, err
+		}
+		return conn, announcer, nil
+	}
+	vr.vrrpConn, vr.addrAnnouncer, err = vr.dial()
+	if err != nil {
+		return nil, err
+	}
+	vr.attachTap()
+	vr.logger().Printf("VRID [%d] initialized, working on %s", VRID, ift.Name)
+	return vr, nil
+}
+
+// ipv6ControlSource 获取 IPv6 VRRP消息（控制面）使用的源地址
+// RFC 5798 5.1.2.1 IPv6 VRRP消息的源地址必须为发送网口的链路本地地址，虚拟IP地址则可以为全局地址，二者相互独立。
+// 当指定的源地址不是链路本地地址时，使用网口的链路本地地址发送VRRP消息，网口没有链路本地地址时保留指定的源地址。
+func ipv6ControlSource(preferIP net.IP, linkLocal func() (net.IP, error)) net.IP {
+	if preferIP.IsLinkLocalUnicast() {
+		return preferIP
+	}
+	ll, err := linkLocal()
+	if err != nil {
+		logg().Printf("WARN IPv6 source %v is not link-local, advertisements may be discarded by peers: %v", preferIP, err)
+		return preferIP
+	}
+	logg().Printf("WARN IPv6 source %v is not link-local, use %v for advertisements", preferIP, ll)
+	return ll.To16()
+}
+
+// newVirtualRouter 初始化虚拟路由器的状态，不创建网络连接以及虚拟IP地址广播器
+func newVirtualRouter(VRID byte, ift *net.Interface, preferIP net.IP, priority byte) (*VirtualRouter, error) {
 	var ipvX byte
 	if preferIP.To4() != nil {
 		ipvX = IPv4
@@ -82,10 +265,15 @@ func NewVirtualRouterSpec(VRID byte, ift *net.Interface, preferIP net.IP, priori
 	atomic.StoreUint32(&vr.state, INIT)
 	// 开启 抢占模式
 	vr.preempt = true
+	vr.preemptEqualPriority = true
 
 	vr.vrID = VRID
 	vr.ipvX = ipvX
+	vr.version = VRRPv3
 	vr.ift = ift
+	vr.hwAddr.Store(&ift.HardwareAddr)
+	vr.lookupInterface = func() (*net.Interface, error) { return net.InterfaceByIndex(ift.Index) }
+	vr.interfaceAddrs = ift.Addrs
 	vr.preferredSourceIP = preferIP
 
 	// ref RFC 5798 7.3. Virtual Router MAC Address
@@ -97,35 +285,17 @@ func NewVirtualRouterSpec(VRID byte, ift *net.Interface, preferIP net.IP, priori
 	vr.SetAdvInterval(defaultAdvertisementInterval)
 	vr.SetPriorityAndMasterAdvInterval(priority, defaultAdvertisementInterval)
 
-	vr.protectedIPaddrs = make(map[netip.Addr]bool)
+	vr.protectedIPaddrs = make(map[netip.Addr]net.IP)
 	vr.eventChannel = make(chan EVENT, EVENT_CHANNEL_SIZE)
+	vr.trackChanged = make(chan struct{}, 1)
+	vr.macChanged = make(chan struct{}, 1)
+	vr.errs = make(chan error, ERROR_CHANNEL_SIZE)
 	vr.packetQueue = make(chan *VRRPPacket, PACKET_QUEUE_SIZE)
-	vr.transitionHandler = make(map[transition]func(*VirtualRouter))
-
-	if ipvX == IPv4 {
-		// 创建 IPv4 虚拟IP地址广播器
-		vr.addrAnnouncer, err = NewIPv4AddrAnnouncer(ift)
-		if err != nil {
-			return nil, err
-		}
-		// 创建IPv4接口 (组播)
-		vr.vrrpConn, err = NewIPv4VRRPMsgConn(ift, vr.preferredSourceIP, VRRPMultiAddrIPv4)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// 创建 IPv6 虚拟IP地址广播器
-		vr.addrAnnouncer, err = NewIPIPv6AddrAnnouncer(ift)
-		if err != nil {
-			return nil, err
-		}
-		// 创建IPv6接口 (组播)
-		vr.vrrpConn, err = NewIPv6VRRPMsgCon(ift, vr.preferredSourceIP, VRRPMultiAddrIPv6)
-		if err != nil {
-			return nil, err
-		}
-	}
-	logg.Printf("VRID [%d] initialized, working on %s", VRID, ift.Name)
+	vr.exited = make(chan struct{})
+	vr.closed = make(chan struct{})
+	vr.transitionHandler = make(map[transition][]func(*VirtualRouter))
+	vr.now = time.Now
+	vr.jitterRand = rand.Float64
 	return vr, nil
 }
 
@@ -151,7 +321,55 @@ func NewVirtualRouter(VRID byte, nif string, Owner bool, IPvX byte) (*VirtualRou
 		priority = 255
 	}
 
-	return NewVirtualRouterSpec(VRID, ift, preferred, priority)
+	vr, err := NewVirtualRouterSpec(VRID, ift, preferred, priority)
+	if err != nil {
+		return nil, err
+	}
+	vr.autoSource = true
+	return vr, nil
+}
+
+// NewVirtualRouterWithConn 使用已有的VRRP数据包收发接口创建虚拟路由器，不查找网口也不创建套接字，
+// 用于 socket activation、文件描述符传递、降权运行以及自定义传输层等场景。
+// VRID: 虚拟路由ID (1~255)
+// conn: VRRP数据包收发接口，工作网口信息取自 conn 的 ConnectionInfo 方法（见 GetConnectionInfo），未实现时网口信息为空
+// announcer: 虚拟IP地址广播器，为 nil 时不做 ARP/NDP 广播
+// src: 发送VRRP消息的源地址，须与 family 一致
+// priority: 优先级，255 表示地址拥有者，0 为特殊值不可使用
+// family: IP协议类型(IPv4 或 IPv6)
+//
+// 由于连接由调用方提供，虚拟路由器停止后无法重新打开连接，再次调用 Start 将返回 ErrStopped。
+// conn.ReadMessage 返回的报文须设置伪首部 Pshdr（至少包含发送方地址），未设置的报文被丢弃，
+// 并以 TapDroppedNoPseudoHeader 计入 DropReasons。
+func NewVirtualRouterWithConn(VRID byte, conn VRRPMsgConnection, announcer AddrAnnouncer, src net.IP, priority byte, family byte) (*VirtualRouter, error) {
+	switch {
+	case conn == nil:
+		return nil, fmt.Errorf("VRID [%d] connection is nil", VRID)
+	case VRID == 0:
+		return nil, fmt.Errorf("invalid VRID 0")
+	case priority == 0:
+		return nil, fmt.Errorf("VRID [%d] priority 0 is reserved", VRID)
+	case family != IPv4 && family != IPv6:
+		return nil, fmt.Errorf("VRID [%d] invalid IP family %d", VRID, family)
+	case family == IPv4 && src.To4() == nil:
+		return nil, fmt.Errorf("VRID [%d] source %v is not an IPv4 address", VRID, src)
+	case family == IPv6 && (src.To16() == nil || src.To4() != nil):
+		return nil, fmt.Errorf("VRID [%d] source %v is not an IPv6 address", VRID, src)
+	}
+	info := connInfoOf(conn)
+	ift := &net.Interface{Index: info.InterfaceIndex, Name: info.InterfaceName}
+	vr, err := newVirtualRouter(VRID, ift, src, priority)
+	if err != nil {
+		return nil, err
+	}
+	if announcer == nil {
+		announcer = noopAnnouncer{}
+	}
+	vr.vrrpConn = conn
+	vr.addrAnnouncer = announcer
+	vr.attachTap()
+	vr.logger().Printf("VRID [%d] initialized with provided connection, working on %s", VRID, ift.Name)
+	return vr, nil
 }
 
 // 设置 虚拟路由的优先级，如为主节点那么忽略
@@ -185,18 +403,64 @@ func (r *VirtualRouter) SetPriorityAndMasterAdvInterval(priority byte, interval
 // 并更新 skewTime 和 masterDownInterval
 func (r *VirtualRouter) setMasterAdvInterval(Interval uint16) *VirtualRouter {
 	r.advertisementIntervalOfMaster = Interval
+	r.skewTime, r.masterDownInterval = masterDownInterval(r.effectivePriority(), Interval)
+	if n := r.missedAdvertThreshold; n > 0 && n != defaultMissedAdvertThreshold {
+		// Master_Down_Interval = (n * Master_Adver_Interval) + Skew_time
+		down := uint32(n)*uint32(Interval) + uint32(r.skewTime)
+		if down > 0xFFFF {
+			down = 0xFFFF
+		}
+		r.masterDownInterval = uint16(down)
+	}
+	// logg.Printf("set MasterAdvInterval skewTime: %d, masterDownInterval: %d\n", r.skewTime, r.masterDownInterval)
+	// 从 MasterDownInterval 和 SkewTime 的计算方式来看，
+	// 同一组VirtualRouter中，Priority 越高的Router越快地认为某个Master失效
+	return r
+}
+
+// defaultMissedAdvertThreshold RFC 5798 规定的触发故障切换的连续丢失心跳消息数量
+const defaultMissedAdvertThreshold = 3
+
+// SetMissedAdvertThreshold 设置 触发故障切换的连续丢失心跳消息数量，默认为 3（RFC 5798），n 不能小于 1。
+// Master_Down_Interval 将按 (n * Master_Adver_Interval) + Skew_Time 重新计算。
+// n 越小故障切换越快，但网络抖动或短暂丢包更容易导致误切换（双主）；n 越大越稳定，但故障切换越慢。
+// 同一虚拟路由器组内的路由器应使用相同的设置。
+func (r *VirtualRouter) SetMissedAdvertThreshold(n int) error {
+	if n < 1 {
+		return fmt.Errorf("VRID [%d] missed advertisement threshold must be at least 1, got %d", r.vrID, n)
+	}
+	r.missedAdvertThreshold = n
+	r.setMasterAdvInterval(r.advertisementIntervalOfMaster)
+	return nil
+}
 
+// masterDownInterval 根据优先级与主节点心跳间隔（单位厘秒）计算 Skew_Time 与 Master_Down_Interval（单位厘秒）
+func masterDownInterval(priority byte, masterAdvInterval uint16) (skewTime, downInterval uint16) {
 	// Skew_Time = (((256 - priority) * Master_Adver_Interval) / 256)
 	// Skew_Time =  (256 * Master_Adver_Interval - priority * Master_Adver_Interval) / 256
 	// Skew_Time =  Master_Adver_Interval - priority * Master_Adver_Interval / 256
-	r.skewTime = r.advertisementIntervalOfMaster - uint16(float32(r.advertisementIntervalOfMaster)*float32(r.priority)/256)
+	skewTime = masterAdvInterval - uint16(float32(masterAdvInterval)*float32(priority)/256)
 
 	// Master_Down_Interval  = (3 * Master_Adver_Interval) + Skew_time
-	r.masterDownInterval = 3*r.advertisementIntervalOfMaster + r.skewTime
-	// logg.Printf("set MasterAdvInterval skewTime: %d, masterDownInterval: %d\n", r.skewTime, r.masterDownInterval)
-	// 从 MasterDownInterval 和 SkewTime 的计算方式来看，
-	// 同一组VirtualRouter中，Priority 越高的Router越快地认为某个Master失效
-	return r
+	downInterval = 3*masterAdvInterval + skewTime
+	return skewTime, downInterval
+}
+
+// ComputeFailoverTime 计算 主节点失效后，指定优先级的备份路由器成为主节点所需的最长时间，
+// 即 Master_Down_Interval，用于在不创建虚拟路由器的情况下规划故障切换时间。
+// priority: 备份路由器优先级
+// interval: 主节点心跳间隔，精度为 10 ms，不能小于 10 ms
+func ComputeFailoverTime(priority byte, interval time.Duration) time.Duration {
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	_, down := masterDownInterval(priority, uint16(interval/(10*time.Millisecond)))
+	return time.Duration(down) * 10 * time.Millisecond
+}
+
+// ExpectedFailoverTime 获取 主节点失效后，当前虚拟路由器成为主节点所需的最长时间（Master_Down_Interval）
+func (r *VirtualRouter) ExpectedFailoverTime() time.Duration {
+	return centisToDuration(r.masterDownInterval)
 }
 
 // SetPreemptMode 设置 抢占模式
@@ -207,218 +471,968 @@ func (r *VirtualRouter) SetPreemptMode(flag bool) *VirtualRouter {
 	return r
 }
 
-// AddIPvXAddr 添加虚拟IP
-func (r *VirtualRouter) AddIPvXAddr(ip net.IP) {
-	if (r.ipvX == IPv4 && ip.To4() == nil) || (r.ipvX == IPv6 && ip.To16() == nil) {
-		return
-	}
-	var bin []byte
-	if r.ipvX == IPv4 {
-		bin = ip.To4()
-	} else {
-		bin = ip.To16()
+// SetAcceptMode 设置 接受模式（RFC 5798 6.1 Accept_Mode），默认值为 false。
+// 值为 true 表示 成为主路由器后接收目的地址为虚拟IP地址的数据包（如 ICMP Echo、TCP 连接），
+// 值为 false 表示 仅转发而不在本地接收。地址拥有者（优先级 255）本身持有虚拟IP地址，始终接收，不受该设置影响。
+// 本库不修改路由与防火墙规则，由使用者在 Init2Master、Backup2Master 事件中根据 GetAcceptMode 安装本地路由或接收规则。
+func (r *VirtualRouter) SetAcceptMode(flag bool) *VirtualRouter {
+	r.accept = flag
+	return r
+}
+
+// SetMulticastRejoinInterval 设置 周期性重新加入VRRP组播组的时间间隔，小于等于 0 表示关闭（默认关闭）。
+// 用于网络中IGMP/MLD查询器变更导致组播成员关系丢失的场景。
+func (r *VirtualRouter) SetMulticastRejoinInterval(interval time.Duration) *VirtualRouter {
+	r.connOpts.rejoinInterval = interval
+	if c, ok := r.vrrpConn.(interface{ SetRejoinInterval(time.Duration) }); ok {
+		c.SetRejoinInterval(interval)
 	}
-	key, ok := netip.AddrFromSlice(bin)
+	return r
+}
+
+// SetMulticastGroup 设置 VRRP消息的目的组播地址，默认为 VRRPMultiAddrIPv4 或 VRRPMultiAddrIPv6，
+// 接收时同步加入该组播组，地址须为与虚拟路由协议类型一致的组播地址。
+// 用于测试环境或兼容特定厂商设备，需在 Start 前调用。
+func (r *VirtualRouter) SetMulticastGroup(group net.IP) error {
+	c, ok := r.vrrpConn.(interface{ SetGroup(net.IP) error })
 	if !ok {
-		return
+		return fmt.Errorf("VRID [%d] connection does not support changing multicast group", r.vrID)
 	}
-	logg.Printf("VRID [%d] VIP %v added", r.vrID, ip)
-	r.protectedIPaddrs[key] = true
+	if err := c.SetGroup(group); err != nil {
+		return err
+	}
+	r.connOpts.group = group
+	r.logger().Printf("VRID [%d] multicast group set to %v", r.vrID, group)
+	return nil
 }
 
-// RemoveIPvXAddr 移除 虚拟路由的虚拟IP地址
-func (r *VirtualRouter) RemoveIPvXAddr(ip net.IP) {
-	key, _ := netip.AddrFromSlice(ip)
-	logg.Printf("VRID [%d] IP %v removed", r.vrID, ip)
-	if _, ok := r.protectedIPaddrs[key]; ok {
-		delete(r.protectedIPaddrs, key)
+// SetProtocolVersion 设置 发送与接受的VRRP协议版本，默认为 VRRPv3，需在 Start 前调用。
+// VRRPv2（RFC 3768）仅支持 IPv4，心跳间隔以秒为单位（不足 1 秒的部分向上取整），不使用认证；
+// VRRPv3（RFC 5798）支持 IPv4 与 IPv6。其他版本以及不支持的组合返回错误。
+func (r *VirtualRouter) SetProtocolVersion(version VRRPVersion) error {
+	switch {
+	case version == VRRPv3:
+	case version == VRRPv2 && r.ipvX == IPv4:
+		if r.advertisementInterval%100 != 0 {
+			r.logger().Printf("WARN VRID [%d] VRRPv2 advertisement interval %v is rounded up to whole seconds", r.vrID, r.GetAdvInterval())
+		}
+	case version == VRRPv2:
+		return fmt.Errorf("VRID [%d] %s does not support IPv6", r.vrID, version)
+	default:
+		return fmt.Errorf("VRID [%d] unsupported %s", r.vrID, version)
 	}
+	if c, ok := r.vrrpConn.(interface{ SetVersion(VRRPVersion) error }); ok {
+		if err := c.SetVersion(version); err != nil {
+			return err
+		}
+	} else if version != VRRPv3 {
+		return fmt.Errorf("VRID [%d] connection does not support %s", r.vrID, version)
+	}
+	r.version = version
+	r.logger().Printf("VRID [%d] protocol version set to %s", r.vrID, version)
+	return nil
 }
 
-// VRID 返回 虚拟路由的 ID
-func (r *VirtualRouter) VRID() byte {
-	return r.vrID
-}
+// ZeroAddrPolicy 收到未携带虚拟IP地址（Count IPvX Addr 为 0）的心跳消息时的处理策略
+type ZeroAddrPolicy int
 
-// 虚拟路由器的 Master 发送 VRRP Advertisement 消息 (心跳消息)
-func (r *VirtualRouter) sendAdvertMessage() {
-	//for k := range r.protectedIPaddrs {
-	//	logg.Printf("VRID [%d] send advert message of IP %s", r.vrID, k.String())
-	//}
-	// 根据构造VRRP消息
-	x := r.assembleVRRPPacket()
-	// 发送 VRRP Advertisement 消息
-	if err := r.vrrpConn.WriteMessage(x); err != nil {
-		logg.Printf("ERROR sending vrrp message: %v", err)
-	}
+const (
+	ZeroAddrAccept ZeroAddrPolicy = iota // 接受，仅用于主节点选举（默认）
+	ZeroAddrReject                       // 丢弃，并计入 Statistics.ZeroAddr
+)
+
+// SetZeroAddrPolicy 设置 收到未携带虚拟IP地址的心跳消息时的处理策略，默认为 ZeroAddrAccept。
+// RFC 5798 要求心跳消息至少携带一个虚拟IP地址，对端实现存在缺陷时可能发送地址数量为 0 的心跳消息。
+func (r *VirtualRouter) SetZeroAddrPolicy(policy ZeroAddrPolicy) *VirtualRouter {
+	r.zeroAddrPolicy = policy
+	return r
 }
 
-// assembleVRRPPacket 根据当前的虚拟路由信息组装 VRRP Advertisement 消息
-func (r *VirtualRouter) assembleVRRPPacket() *VRRPPacket {
+// SetRawTap 设置 原始报文监听函数，用于协议调试与互通问题排查。
+// 每一个收到的报文在交由状态机处理前，均会连同校验结果（通过或丢弃原因）报告给该函数，tap 为 nil 时关闭。
+// 监听函数在接收协程中同步调用，请勿在其中执行耗时操作或修改报文。
+func (r *VirtualRouter) SetRawTap(tap func(pkt *VRRPPacket, src net.IP, result string)) *VirtualRouter {
+	r.tap.set(tap)
+	r.attachTap()
+	return r
+}
 
-	var packet VRRPPacket
-	packet.SetPriority(r.priority)
-	packet.SetVersion(VRRPv3)
-	packet.SetVirtualRouterID(r.vrID)
-	packet.SetAdvertisementInterval(r.advertisementInterval)
-	packet.SetType()
-	for k := range r.protectedIPaddrs {
-		packet.AddIPAddr(k)
-	}
-	// 构造伪首部，用于计算校验码
-	var pshdr PseudoHeader
-	pshdr.Protocol = VRRPIPProtocolNumber
-	if r.ipvX == IPv4 {
-		pshdr.Daddr = VRRPMultiAddrIPv4
-	} else {
-		pshdr.Daddr = VRRPMultiAddrIPv6
+// attachTap 将连接的校验结果转交虚拟路由器统计丢弃原因并报告给原始报文监听函数，替换连接后需重新调用
+func (r *VirtualRouter) attachTap() {
+	if c, ok := r.vrrpConn.(interface{ SetRawTap(RawTap) }); ok {
+		c.SetRawTap(r.reportTap)
 	}
-	pshdr.Len = uint16(packet.PacketSize())
-	pshdr.Saddr = r.preferredSourceIP
-	packet.SetCheckSum(&pshdr)
-	return &packet
 }
 
-// fetchVRRPDaemon VRRP Advertisement 消息接收精灵，持续接收VRRP Advertisement 消息，收到的消息会被放入 packetQueue 队列中。
-// 如果虚拟路由器处于 INIT 状态，则停止接收 VRRP Advertisement 消息，请确启动该携程前 VirtualRouter 的 state 状态为 MASTER 或 BACKUP。
-func (r *VirtualRouter) fetchVRRPDaemon() {
-	logg.Printf("VRID [%d] fetch vrrp msg daemon start", r.vrID)
-	for {
-		if atomic.LoadUint32(&r.state) == INIT {
-			// 如果虚拟路由器处于 INIT 状态，则停止接收 VRRP Advertisement 消息
-			logg.Printf("VRID [%d] fetch vrrp msg daemon stopped", r.vrID)
-			return
-		}
-		packet, err := r.vrrpConn.ReadMessage()
-		if err != nil {
-			// 由于网络原因，接收 VRRP Advertisement 消息失败，停止接收 VRRP Advertisement 消息
-			if _, ok := err.(NetErr); ok {
-				logg.Printf("ERROR receive vrrp message: %v, fetch message will be stop", err)
-				return
-			} else {
-				//logg.Printf("ERROR receive err format vrrp message: %v", err)
-				// 由于消息格式错误，忽略该消息
-				continue
-			}
-		}
-		//logg.Printf("VRID [%d] received VRRP packet: \n%s\n\n", r.vrID, packet.String())
-		if r.vrID != packet.GetVirtualRouterID() {
-			// 忽略不同 VRID 的 VRRP Advertisement 消息
-			continue
-		}
+// reportTap 统计丢弃原因，并向原始报文监听函数报告校验结果
+func (r *VirtualRouter) reportTap(pkt *VRRPPacket, src net.IP, result string) {
+	if result != TapAccepted {
+		r.drops.add(result)
+	}
+	r.tap.report(pkt, src, result)
+}
 
-		r.packetQueue <- packet
+// SetRejectForeignInterface 设置 是否丢弃非工作网口收到的VRRP消息，默认关闭。
+// 多网口主机上同一组播消息可能从多个网口收到，开启后根据控制消息中的网口索引丢弃非工作网口收到的消息，
+// 丢弃数量见 Statistics.ForeignIfIndex。
+func (r *VirtualRouter) SetRejectForeignInterface(flag bool) *VirtualRouter {
+	r.connOpts.strict = flag
+	if c, ok := r.vrrpConn.(interface{ SetStrictInterface(bool) }); ok {
+		c.SetStrictInterface(flag)
 	}
+	return r
 }
 
-// 初始化 心跳定时器
-func (r *VirtualRouter) makeAdvertTicker() {
-	r.advertisementTicker = time.NewTicker(time.Duration(r.advertisementInterval*10) * time.Millisecond)
+// SetMissingTTLPolicy 设置 IPv4 连接接收数据包时控制消息中没有TTL数据的处理策略，默认为 MissingTTLReject。
+// 部分虚拟化回环测试环境中控制消息的TTL为 0 或缺失，导致自身发出的有效心跳消息也被丢弃，
+// 可设置为 MissingTTLAccept 接受此类数据包（记录一次警告日志），TTL不为255的数据包仍然丢弃。
+func (r *VirtualRouter) SetMissingTTLPolicy(policy MissingTTLPolicy) *VirtualRouter {
+	r.connOpts.missingTTL = policy
+	if c, ok := r.vrrpConn.(interface{ SetMissingTTLPolicy(MissingTTLPolicy) }); ok {
+		c.SetMissingTTLPolicy(policy)
+	}
+	return r
 }
 
-// 停止心跳定时器
-func (r *VirtualRouter) stopAdvertTicker() {
-	r.advertisementTicker.Stop()
+// SetPreemptEqualPriority 设置 优先级相同时是否根据源IP地址抢占主路由器
+// 该设置独立于抢占模式，值为 false 时，备份路由器收到优先级相同的心跳消息即认为其来自主路由器，
+// 不再因自身源IP地址较大而抢占，避免两个相同优先级的路由器重启时发生主备震荡。默认值为 true。
+func (r *VirtualRouter) SetPreemptEqualPriority(flag bool) *VirtualRouter {
+	r.preemptEqualPriority = flag
+	return r
 }
 
-// makeMasterDownTimer 初始化 主节点下线倒计时器
-func (r *VirtualRouter) makeMasterDownTimer() {
-	if r.masterDownTimer == nil {
-		r.masterDownTimer = time.NewTimer(time.Duration(r.masterDownInterval*10) * time.Millisecond)
+// WouldPreempt 假设收到指定优先级与源地址的对端心跳消息，判断当前虚拟路由器是否会接管主节点，
+// 用于在没有实际流量的情况下验证抢占配置。判断依据当前的抢占模式、相同优先级抢占设置、
+// 考虑跟踪对象后的实际优先级，以及优先级相同时源IP地址较大者优先的规则。
+// 对端优先级为 0 表示主节点让渡，此时总是接管；关键跟踪对象失败时总是不接管。
+func (r *VirtualRouter) WouldPreempt(peerPriority byte, peerIP net.IP) bool {
+	if r.inFault() {
+		return false
+	}
+	if peerPriority == 0 {
+		return true
+	}
+	if !r.preempt {
+		return false
+	}
+	priority := r.effectivePriority()
+	if peerPriority != priority {
+		return priority > peerPriority
+	}
+	if !r.preemptEqualPriority {
+		return false
+	}
+	if r.ipvX == IPv4 {
+		peerIP = peerIP.To4()
 	} else {
-		r.resetMasterDownTimer()
+		peerIP = peerIP.To16()
 	}
+	return largerThan(r.preferredSourceIP, peerIP)
 }
 
-// 停止 主节点下线倒计时器
-func (r *VirtualRouter) stopMasterDownTimer() {
-	//logg.Printf("VRID [%d] master down timer stopped", r.vrID)
-	if !r.masterDownTimer.Stop() {
-		select {
-		case <-r.masterDownTimer.C:
-		default:
-		}
-		//logg.Printf( "VRID [%d] master down timer expired before we stop it, drain the channel", r.vrID)
+// SetPreemptDelay 设置 抢占延迟，默认值为 0 表示立即抢占。
+// 开启后备份路由器首次收到可被抢占的低优先级心跳消息时开始计时，
+// 延迟期间仍将其视为主节点的心跳消息，到期后才抢占，用于等待本机路由等服务就绪。
+// 主节点让渡（优先级为 0）或主节点失效时不受该延迟影响。
+func (r *VirtualRouter) SetPreemptDelay(delay time.Duration) *VirtualRouter {
+	if delay < 0 {
+		delay = 0
 	}
+	r.preemptDelay = delay
+	return r
 }
 
-// resetMasterDownTimer 重置 主节点下线倒计时
-func (r *VirtualRouter) resetMasterDownTimer() {
-	r.stopMasterDownTimer()
-	r.masterDownTimer.Reset(time.Duration(r.masterDownInterval*10) * time.Millisecond)
+// GetPreemptDelay 获取 抢占延迟
+func (r *VirtualRouter) GetPreemptDelay() time.Duration {
+	return r.preemptDelay
 }
 
-// 设置 主节点下线倒计时为 skewTime
-func (r *VirtualRouter) resetMasterDownTimerToSkewTime() {
-	r.stopMasterDownTimer()
-	r.masterDownTimer.Reset(time.Duration(r.skewTime*10) * time.Millisecond)
+// PreemptDelayRemaining 获取 进行中的抢占延迟的剩余时间，没有进行中的抢占延迟时返回 0
+func (r *VirtualRouter) PreemptDelayRemaining() time.Duration {
+	deadline := r.preemptDeadline.Load()
+	if deadline == 0 {
+		return 0
+	}
+	remaining := time.Unix(0, deadline).Sub(r.now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
 }
 
-// 当状态机状态发生变更时，调用对应的处理函数
-func (r *VirtualRouter) stateChanged(t transition) {
-	if work, ok := r.transitionHandler[t]; ok && work != nil {
-		work(r)
-		logg.Printf("VRID [%d] handler of transition [%s] called", r.vrID, t)
+// delayPreempt 判断是否处于抢占延迟期间，首次调用时开始计时
+func (r *VirtualRouter) delayPreempt() bool {
+	if r.preemptDelay <= 0 {
+		return false
+	}
+	now := r.now()
+	deadline := r.preemptDeadline.Load()
+	if deadline == 0 {
+		r.logger().Printf("VRID [%d] preempt delayed for %v", r.vrID, r.preemptDelay)
+		r.preemptDeadline.Store(now.Add(r.preemptDelay).UnixNano())
+		return true
 	}
-	return
+	return now.UnixNano() < deadline
 }
 
-// GetPriority 获取 虚拟路由的优先级
-func (r *VirtualRouter) GetPriority() byte {
-	return r.priority
+// SetMinMasterDwell 设置 最短主节点保持时间，默认值为 0 表示不限制。
+// 成为主节点后在该时间内，收到优先级相同但源IP地址较大的心跳消息时不让渡主节点，
+// 用于减少两个实际优先级相近的路由器（如跟踪对象抖动）之间的主备震荡。
+// 收到更高优先级的心跳消息时仍立即让渡。
+func (r *VirtualRouter) SetMinMasterDwell(dwell time.Duration) *VirtualRouter {
+	if dwell < 0 {
+		dwell = 0
+	}
+	r.minMasterDwell = dwell
+	return r
 }
 
-// GetState 获取 虚拟路由的状态
-// return INIT | MASTER | BACKUP
-func (r *VirtualRouter) GetState() uint32 {
-	return atomic.LoadUint32(&r.state)
+// inMasterDwell 是否处于最短主节点保持时间内
+func (r *VirtualRouter) inMasterDwell() bool {
+	return r.minMasterDwell > 0 && r.now().Sub(r.masterSince) < r.minMasterDwell
 }
 
-// GetInterface 获取 虚拟路由的工作网口
-func (r *VirtualRouter) GetInterface() *net.Interface {
-	return r.ift
+// SetSuppressInitialAdvert 设置 地址拥有者启动进入 MASTER 状态时是否不立即发送心跳消息，默认值为 false。
+// 开启后首个心跳消息由心跳定时器到期（或手动调用 SendOneAdvertisement）发出，
+// 用于回放抓包场景或编排测试，生产环境请勿开启。
+func (r *VirtualRouter) SetSuppressInitialAdvert(flag bool) *VirtualRouter {
+	r.suppressInitialAdvert = flag
+	return r
 }
 
-// GetPreferredSourceIP 获取 虚拟路由的优先IP地址
-func (r *VirtualRouter) GetPreferredSourceIP() net.IP {
-	return r.preferredSourceIP
+// SetLogger 设置 虚拟路由的日志记录器，优先于默认日志记录器，l 为 nil 时恢复使用默认日志记录器
+func (r *VirtualRouter) SetLogger(l *log.Logger) *VirtualRouter {
+	r.log.Store(l)
+	return r
 }
 
-// GetAdvInterval 获取 虚拟路由的心跳发送间隔
-func (r *VirtualRouter) GetAdvInterval() time.Duration {
-	return time.Duration(r.advertisementInterval) * 10 * time.Millisecond
+// logger 获取 虚拟路由当前生效的日志记录器
+func (r *VirtualRouter) logger() *log.Logger {
+	if l := r.log.Load(); l != nil {
+		return l
+	}
+	return logg()
 }
 
-// GetPreempt 获取 虚拟路由的抢占模式
-func (r *VirtualRouter) GetPreempt() bool {
-	return r.preempt
+// SetDebug 设置 是否开启调试日志，开启后状态机处理的每个事件（事件、心跳定时器、心跳消息、主节点下线倒计时）
+// 均会连同虚拟路由ID与当前状态记录至日志，用于故障排查，默认关闭。
+func (r *VirtualRouter) SetDebug(flag bool) *VirtualRouter {
+	r.debug.Store(flag)
+	return r
 }
 
-// GetVIPs 获取 虚拟路由的保护IP地址
-func (r *VirtualRouter) GetVIPs() []net.IP {
-	vips := make([]net.IP, 0)
-	for k := range r.protectedIPaddrs {
-		vips = append(vips, k.AsSlice())
+// debugf 开启调试日志时记录状态机事件
+func (r *VirtualRouter) debugf(format string, args ...interface{}) {
+	if !r.debug.Load() {
+		return
 	}
-	return vips
+	r.logger().Printf("DEBUG VRID [%d] state %s: %s", r.vrID, StateName(atomic.LoadUint32(&r.state)), fmt.Sprintf(format, args...))
 }
 
-// largerThan 比较IP数值大小 ip1 > ip2 （用于在优先级相同时IP大的优先）
-func largerThan(ip1, ip2 net.IP) bool {
-	if len(ip1) != len(ip2) {
-		//logg.Printf(FATAL, "largerThan: two compared IP addresses must have the same length")
-		return false
+// SetMaxAdvertRate 设置 每秒最多立即发送的心跳消息数量，用于防止频繁的状态切换或配置变更产生大量心跳消息。
+// 定时发送的心跳消息不受该限制，小于等于 0 表示不限制（默认不限制）。
+func (r *VirtualRouter) SetMaxAdvertRate(perSecond int) *VirtualRouter {
+	r.advertLimiter.setRate(perSecond)
+	return r
+}
+
+// vipKey 将IP地址转换为虚拟路由协议类型的标准形式，地址协议类型不匹配时返回 false
+func (r *VirtualRouter) vipKey(ip net.IP) (netip.Addr, net.IP, bool) {
+	if (r.ipvX == IPv4 && ip.To4() == nil) || (r.ipvX == IPv6 && ip.To16() == nil) {
+		return netip.Addr{}, nil, false
 	}
-	for index := range ip1 {
-		if ip1[index] > ip2[index] {
-			return true
-		} else if ip1[index] < ip2[index] {
+	var bin []byte
+	if r.ipvX == IPv4 {
+		bin = ip.To4()
+	} else {
+		bin = ip.To16()
+	}
+	key, ok := netip.AddrFromSlice(bin)
+	return key, bin, ok
+}
+
+// AddIPvXAddr 添加虚拟IP
+func (r *VirtualRouter) AddIPvXAddr(ip net.IP) {
+	key, bin, ok := r.vipKey(ip)
+	if !ok {
+		return
+	}
+	r.logger().Printf("VRID [%d] VIP %v added", r.vrID, ip)
+	r.vipMu.Lock()
+	r.protectedIPaddrs[key] = bin
+	r.vipMu.Unlock()
+	if err := r.checkMTU(); err != nil {
+		r.logger().Printf("WARN %v", err)
+	}
+}
+
+// ErrAdvertisementTooLarge 心跳消息超过工作网口的 MTU
+var ErrAdvertisementTooLarge = errors.New("advertisement too large")
+
+// AdvertisementSize 获取 当前虚拟IP地址集合对应的心跳消息的IP报文长度（字节），包含IP首部（IPv4 20 字节，IPv6 40 字节）
+func (r *VirtualRouter) AdvertisementSize() int {
+	r.vipMu.RLock()
+	count := len(r.protectedIPaddrs)
+	r.vipMu.RUnlock()
+	if r.ipvX == IPv4 {
+		if r.version == VRRPv2 {
+			return 20 + 8 + count*net.IPv4len + VRRPv2AuthDataLen
+		}
+		return 20 + 8 + count*net.IPv4len
+	}
+	return 40 + 8 + count*net.IPv6len
+}
+
+// checkMTU 检查 心跳消息是否超过工作网口的 MTU，超过时心跳消息将被分片，部分设备会丢弃分片的控制报文。
+// 网口 MTU 未知（为 0）时不检查。
+func (r *VirtualRouter) checkMTU() error {
+	if r.ift == nil || r.ift.MTU <= 0 {
+		return nil
+	}
+	if size := r.AdvertisementSize(); size > r.ift.MTU {
+		return fmt.Errorf("VRID [%d] %w: %d bytes exceeds MTU %d of %s, use fewer VIPs per virtual router",
+			r.vrID, ErrAdvertisementTooLarge, size, r.ift.MTU, r.ift.Name)
+	}
+	return nil
+}
+
+// RemoveIPvXAddr 移除 虚拟路由的虚拟IP地址
+func (r *VirtualRouter) RemoveIPvXAddr(ip net.IP) {
+	key, _, ok := r.vipKey(ip)
+	if !ok {
+		return
+	}
+	r.logger().Printf("VRID [%d] IP %v removed", r.vrID, ip)
+	r.vipMu.Lock()
+	defer r.vipMu.Unlock()
+	if _, ok := r.protectedIPaddrs[key]; ok {
+		delete(r.protectedIPaddrs, key)
+	}
+}
+
+// VRID 返回 虚拟路由的 ID
+func (r *VirtualRouter) VRID() byte {
+	return r.vrID
+}
+
+// GetIPvX 获取 虚拟路由的协议类型 IPv4 | IPv6
+func (r *VirtualRouter) GetIPvX() byte {
+	return r.ipvX
+}
+
+// 虚拟路由器的 Master 发送 VRRP Advertisement 消息 (心跳消息)
+func (r *VirtualRouter) sendAdvertMessage() {
+	//for k := range r.protectedIPaddrs {
+	//	logg.Printf("VRID [%d] send advert message of IP %s", r.vrID, k.String())
+	//}
+	if err := r.writeAdvertMessage(); err != nil {
+		r.sendFailures++
+		return
+	}
+	r.sendFailures = 0
+}
+
+// writeAdvertMessage 组装并发送 VRRP Advertisement 消息，发送前调用报文修改函数，
+// 发送成功后更新统计并调用观察函数，失败时记录日志、上报错误并调用发送失败回调函数
+func (r *VirtualRouter) writeAdvertMessage() error {
+	// 根据构造VRRP消息
+	x := r.assembleVRRPPacket()
+	if fn := r.packetMutator.Load(); fn != nil {
+		(*fn)(x)
+		// 报文内容可能已被修改，重新计算校验码
+		x.SetCheckSum(r.advertPseudoHeader(x))
+	}
+	// 发送 VRRP Advertisement 消息
+	var err error
+	if r.l2Writer != nil {
+		err = r.writeL2Advert(x)
+	} else {
+		err = r.vrrpConn.WriteMessage(x)
+	}
+	if err != nil {
+		r.logger().Printf("ERROR sending vrrp message: %v", err)
+		r.reportError(ErrorOpSend, err)
+		r.stats.sendErrors.Add(1)
+		if r.sendErrorHandler != nil {
+			r.sendErrorHandler(err)
+		}
+		return err
+	}
+	r.stats.advertsSent.Add(1)
+	if fn := r.sentObserver.Load(); fn != nil {
+		(*fn)(x)
+	}
+	return nil
+}
+
+// SetPacketMutator 设置 心跳消息发送前的修改函数，fn 为 nil 表示取消。
+// 修改函数在报文构造完成后调用，调用后重新计算校验码，可用于设置厂商特定字段或测试对端对异常报文的处理。
+//
+// 注意：该功能面向高级用户，修改后的报文可能违反 RFC 5798，导致对端丢弃或选举异常。
+// 修改函数在状态机协程中同步调用，不应阻塞。
+func (r *VirtualRouter) SetPacketMutator(fn func(*VRRPPacket)) *VirtualRouter {
+	if fn == nil {
+		r.packetMutator.Store(nil)
+	} else {
+		r.packetMutator.Store(&fn)
+	}
+	return r
+}
+
+// SetSentObserver 设置 心跳消息发送成功后的观察函数，用于记录或统计发出的心跳消息，
+// 确认实际通告的优先级与虚拟IP地址，fn 为 nil 表示取消。
+// 观察函数在状态机协程中同步调用，不应阻塞，也不应修改报文。
+func (r *VirtualRouter) SetSentObserver(fn func(*VRRPPacket)) *VirtualRouter {
+	if fn == nil {
+		r.sentObserver.Store(nil)
+	} else {
+		r.sentObserver.Store(&fn)
+	}
+	return r
+}
+
+// 异步错误的操作类型
+const (
+	ErrorOpSend     = "send"     // 发送心跳消息失败
+	ErrorOpReceive  = "receive"  // 收到无法解析或校验失败的心跳消息
+	ErrorOpSocket   = "socket"   // 连接异常，停止接收心跳消息
+	ErrorOpAnnounce = "announce" // 发送 ARP/NDP 广播失败
+	ErrorOpWatchdog = "watchdog" // 状态机停滞，见 SetWatchdog
+	ErrorOpMigrate  = "migrate"  // 在网口间迁移虚拟IP地址失败，见 VIPMigrator
+)
+
+// RouterError 虚拟路由器运行过程中产生的异步错误
+type RouterError struct {
+	VRID byte   // 虚拟路由ID
+	Op   string // 操作类型，取值见 ErrorOpSend 等常量
+	Err  error  // 原始错误
+}
+
+func (e *RouterError) Error() string {
+	return fmt.Sprintf("VRID [%d] %s: %v", e.VRID, e.Op, e.Err)
+}
+
+func (e *RouterError) Unwrap() error {
+	return e.Err
+}
+
+// Errors 获取 异步错误通道，通道中的错误类型为 *RouterError，
+// 包括心跳消息发送失败、接收失败、连接异常以及 ARP/NDP 广播失败，便于集中处理与告警。
+// 通道容量为 ERROR_CHANNEL_SIZE，写入不会阻塞状态机，通道已满时丢弃最早的错误。
+func (r *VirtualRouter) Errors() <-chan error {
+	return r.errs
+}
+
+// reportError 向异步错误通道写入错误，通道已满时丢弃最早的错误
+func (r *VirtualRouter) reportError(op string, err error) {
+	e := &RouterError{VRID: r.vrID, Op: op, Err: err}
+	for {
+		select {
+		case r.errs <- e:
+			return
+		default:
+		}
+		select {
+		case <-r.errs:
+		default:
+		}
+	}
+}
+
+// OnSendError 设置 心跳消息发送失败时的回调函数，可用于对持续的发送失败（如上行链路故障）进行告警。
+// 回调函数在状态机协程中同步调用，不应阻塞。
+func (r *VirtualRouter) OnSendError(handler func(err error)) *VirtualRouter {
+	r.sendErrorHandler = handler
+	return r
+}
+
+// SetSendErrorThreshold 设置 连续发送失败次数阈值，主节点连续发送心跳消息失败达到该次数后进入 INIT 状态，
+// 小于等于 0 表示不限制（默认不限制）。
+// 进入 INIT 状态后经过 Master_Down_Interval 重新参与选举，上行链路恢复、发送成功后即恢复正常，无需重新启动。
+func (r *VirtualRouter) SetSendErrorThreshold(n int) *VirtualRouter {
+	r.sendErrorThreshold = n
+	return r
+}
+
+// ErrAdvertRateLimited 立即发送心跳消息超出了限速
+var ErrAdvertRateLimited = errors.New("advertisement rate limit exceeded")
+
+// SendOneAdvertisement 立即组装并发送一个 VRRP Advertisement 消息，无论虚拟路由器处于何种状态
+// 可用于验证发送路径以及组播的可达性，连接已建立时可在 Start 前调用，受 SetMaxAdvertRate 限速。
+// 发送过程与周期性心跳消息相同，同样应用报文修改函数、二层发送方式、发送观察函数并计入统计。
+func (r *VirtualRouter) SendOneAdvertisement() error {
+	if r.vrrpConn == nil {
+		return errors.New("SendOneAdvertisement: VRRP connection not established")
+	}
+	if !r.advertLimiter.allow(r.now()) {
+		return fmt.Errorf("SendOneAdvertisement: %w", ErrAdvertRateLimited)
+	}
+	return r.writeAdvertMessage()
+}
+
+// 立即发送 VRRP Advertisement 消息，超出限速时放弃发送
+//
+// return: 是否发送了消息
+func (r *VirtualRouter) sendImmediateAdvertMessage() bool {
+	if !r.advertLimiter.allow(r.now()) {
+		r.logger().Printf("VRID [%d] immediate advertisement dropped, exceed the max advertisement rate", r.vrID)
+		return false
+	}
+	r.sendAdvertMessage()
+	return true
+}
+
+// CurrentAdvertisement 获取 当前时刻虚拟路由器将要发送的VRRP消息副本，
+// 包含实际通告的优先级、虚拟IP地址集合、心跳间隔，以及根据源地址与组播地址计算的校验和。
+// 返回的报文为只读副本，可随时调用，用于核对实际发送的报文内容。
+func (r *VirtualRouter) CurrentAdvertisement() *VRRPPacket {
+	return r.assembleVRRPPacket()
+}
+
+// assembleVRRPPacket 根据当前的虚拟路由信息组装 VRRP Advertisement 消息
+func (r *VirtualRouter) assembleVRRPPacket() *VRRPPacket {
+
+	var packet VRRPPacket
+	packet.SetPriority(r.effectivePriority())
+	packet.SetVersion(r.version)
+	packet.SetVirtualRouterID(r.vrID)
+	packet.SetAdvertisementInterval(r.advertisementInterval)
+	packet.SetType()
+	if r.version == VRRPv2 {
+		// VRRPv2 不使用认证，认证数据全部为 0
+		packet.Header[4] = VRRPv2AuthNone
+		packet.AuthData = make([]byte, VRRPv2AuthDataLen)
+	}
+	r.vipMu.RLock()
+	for k := range r.protectedIPaddrs {
+		packet.AddIPAddr(k)
+	}
+	r.vipMu.RUnlock()
+	packet.SetCheckSum(r.advertPseudoHeader(&packet))
+	return &packet
+}
+
+// advertPseudoHeader 构造 发送心跳消息的伪首部，用于计算校验码
+func (r *VirtualRouter) advertPseudoHeader(packet *VRRPPacket) *PseudoHeader {
+	var pshdr PseudoHeader
+	pshdr.Protocol = VRRPIPProtocolNumber
+	if group := r.GetConnectionInfo().Group; group != nil {
+		// 使用连接实际发送的组播地址，见 SetMulticastGroup
+		pshdr.Daddr = group
+	} else if r.ipvX == IPv4 {
+		pshdr.Daddr = VRRPMultiAddrIPv4
+	} else {
+		pshdr.Daddr = VRRPMultiAddrIPv6
+	}
+	pshdr.Len = uint16(packet.PacketSize())
+	pshdr.Saddr = r.preferredSourceIP
+	return &pshdr
+}
+
+// fetchVRRPDaemon VRRP Advertisement 消息接收精灵，持续接收VRRP Advertisement 消息，收到的消息会被放入 packetQueue 队列中。
+// 如果虚拟路由器处于 INIT 状态，则停止接收 VRRP Advertisement 消息，请确启动该携程前 VirtualRouter 的 state 状态为 MASTER 或 BACKUP。
+// conn: 接收消息的连接，重新启动时连接会被替换，因此由启动方传入
+func (r *VirtualRouter) fetchVRRPDaemon(conn VRRPMsgConnection) {
+	r.logger().Printf("VRID [%d] fetch vrrp msg daemon start", r.vrID)
+	for {
+		if atomic.LoadUint32(&r.state) == INIT && !r.linkDown.Load() && !r.sendRetry.Load() {
+			// 如果虚拟路由器处于 INIT 状态，则停止接收 VRRP Advertisement 消息
+			// 因工作网口链路断开或连续发送失败进入 INIT 状态时继续接收，恢复后无需重新启动
+			r.logger().Printf("VRID [%d] fetch vrrp msg daemon stopped", r.vrID)
+			return
+		}
+		packet, err := conn.ReadMessage()
+		if err != nil {
+			// 由于网络原因，接收 VRRP Advertisement 消息失败，停止接收 VRRP Advertisement 消息
+			if _, ok := err.(NetErr); ok {
+				r.logger().Printf("ERROR receive vrrp message: %v, fetch message will be stop", err)
+				r.reportError(ErrorOpSocket, err)
+				return
+			} else {
+				//logg.Printf("ERROR receive err format vrrp message: %v", err)
+				// 由于消息格式错误，忽略该消息
+				if errors.Is(err, ErrTruncatedAdvert) {
+					// 与格式错误的短报文不同，截断的报文通常说明分片丢失或路径 MTU 不足
+					r.logger().Printf("WARN VRID [%d] %v, check the MTU and fragmentation on the path", r.vrID, err)
+				}
+				r.countDropped(err)
+				r.reportError(ErrorOpReceive, err)
+				continue
+			}
+		}
+		//logg.Printf("VRID [%d] received VRRP packet: \n%s\n\n", r.vrID, packet.String())
+		if r.linkDown.Load() || r.sendRetry.Load() {
+			// 链路断开或等待重新参与选举期间状态机不处理心跳消息，直接丢弃
+			continue
+		}
+		if packet.Pshdr == nil {
+			// 自定义连接返回的报文（如 FromBytes 解析的报文）未设置伪首部，无法获取发送方地址
+			r.reportTap(packet, nil, TapDroppedNoPseudoHeader)
+			r.reportError(ErrorOpReceive, ErrNoPseudoHeader)
+			continue
+		}
+		if r.vrID != packet.GetVirtualRouterID() {
+			// 忽略不同 VRID 的 VRRP Advertisement 消息
+			r.reportTap(packet, packet.Pshdr.Saddr, TapDroppedVRID)
+			continue
+		}
+		if r.strictFamily {
+			if err = packet.checkFamily(r.ipvX); err != nil {
+				// 丢弃地址序列与协议类型不一致的 VRRP Advertisement 消息
+				r.debugf("advertisement from %s dropped: %v", packet.Pshdr.Saddr, err)
+				r.reportTap(packet, packet.Pshdr.Saddr, TapDroppedFamily)
+				r.reportError(ErrorOpReceive, err)
+				continue
+			}
+		}
+		if packet.GetIPvXAddrCount() == 0 && r.zeroAddrPolicy == ZeroAddrReject {
+			// 丢弃未携带虚拟IP地址的 VRRP Advertisement 消息
+			r.stats.zeroAddr.Add(1)
+			r.reportTap(packet, packet.Pshdr.Saddr, TapDroppedZeroAddr)
+			continue
+		}
+		if r.checkSuspiciousPriority(packet) {
+			// 丢弃非已知地址拥有者发出的优先级 255 心跳消息
+			r.reportTap(packet, packet.Pshdr.Saddr, TapDroppedSuspiciousPriority)
+			continue
+		}
+		r.reportTap(packet, packet.Pshdr.Saddr, TapAccepted)
+
+		r.stats.received.Add(1)
+		r.observePeer(packet)
+		r.packetQueue <- packet
+	}
+}
+
+// 初始化 心跳定时器
+func (r *VirtualRouter) makeAdvertTicker() {
+	r.advertisementTicker = time.NewTicker(time.Duration(r.advertisementInterval*10) * time.Millisecond)
+	r.lastAdvertTick = r.now()
+}
+
+// 停止心跳定时器
+func (r *VirtualRouter) stopAdvertTicker() {
+	r.advertisementTicker.Stop()
+}
+
+// makeMasterDownTimer 初始化 主节点下线倒计时器
+func (r *VirtualRouter) makeMasterDownTimer() {
+	if r.masterDownTimer == nil {
+		r.masterDownTimer = time.NewTimer(r.masterDownDuration())
+	} else {
+		r.resetMasterDownTimer()
+	}
+}
+
+// 停止 主节点下线倒计时器
+func (r *VirtualRouter) stopMasterDownTimer() {
+	//logg.Printf("VRID [%d] master down timer stopped", r.vrID)
+	if !r.masterDownTimer.Stop() {
+		select {
+		case <-r.masterDownTimer.C:
+		default:
+		}
+		//logg.Printf( "VRID [%d] master down timer expired before we stop it, drain the channel", r.vrID)
+	}
+}
+
+// resetMasterDownTimer 重置 主节点下线倒计时
+func (r *VirtualRouter) resetMasterDownTimer() {
+	r.stopMasterDownTimer()
+	r.masterDownTimer.Reset(r.masterDownDuration())
+}
+
+// masterDownDuration 计算 主节点下线倒计时时长，在 Master_Down_Interval 的基础上增加随机抖动，
+// 抖动仅延长倒计时，且不超过 Skew_Time 的 masterDownJitter 比例，不会缩短RFC规定的检测时间
+func (r *VirtualRouter) masterDownDuration() time.Duration {
+	d := time.Duration(r.masterDownInterval) * 10 * time.Millisecond
+	if r.masterDownJitter > 0 {
+		skew := time.Duration(r.skewTime) * 10 * time.Millisecond
+		d += time.Duration(float64(skew) * r.masterDownJitter * r.jitterRand())
+	}
+	return d
+}
+
+// SetMasterDownJitter 设置 主节点下线倒计时的随机抖动比例，取值范围 [0, 1]，默认 0 表示不抖动。
+// 多个相同优先级的备份路由器在主节点失效时几乎同时到期并发送心跳消息，
+// 开启后在 Master_Down_Interval 之上随机增加至多 Skew_Time * fraction 的时间，错开各备份路由器的抢占时机。
+func (r *VirtualRouter) SetMasterDownJitter(fraction float64) *VirtualRouter {
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	r.masterDownJitter = fraction
+	return r
+}
+
+// 设置 主节点下线倒计时为 skewTime
+func (r *VirtualRouter) resetMasterDownTimerToSkewTime() {
+	r.stopMasterDownTimer()
+	r.masterDownTimer.Reset(time.Duration(r.skewTime*10) * time.Millisecond)
+}
+
+// 当状态机状态发生变更时，调用对应的处理函数
+func (r *VirtualRouter) stateChanged(t transition) {
+	r.clock.transit(t.To(), r.now())
+	r.stats.countTransition(t)
+	for _, work := range r.transitionHandler[t] {
+		if work == nil {
+			continue
+		}
+		work(r)
+		r.logger().Printf("VRID [%d] handler of transition [%s] called", r.vrID, t)
+	}
+}
+
+// GetPriority 获取 虚拟路由的优先级
+func (r *VirtualRouter) GetPriority() byte {
+	return r.priority
+}
+
+// GetState 获取 虚拟路由的状态
+// return INIT | MASTER | BACKUP
+func (r *VirtualRouter) GetState() uint32 {
+	return atomic.LoadUint32(&r.state)
+}
+
+// GetInterface 获取 虚拟路由的工作网口
+func (r *VirtualRouter) GetInterface() *net.Interface {
+	return r.ift
+}
+
+// Identity 获取 虚拟路由器的唯一标识，由 工作网口名称、协议类型 与 虚拟路由ID 组成，如 "eth0-ipv4-10"。
+// 同一网口上相同VRID的 IPv4 与 IPv6 虚拟路由器是相互独立的两个实例（虚拟MAC地址也不同），标识不同。
+func (r *VirtualRouter) Identity() string {
+	return fmt.Sprintf("%s-%s-%d", r.ift.Name, r.familyName(), r.vrID)
+}
+
+// familyName 协议类型名称，"ipv4" 或 "ipv6"
+func (r *VirtualRouter) familyName() string {
+	if r.ipvX == IPv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// GetConnectionInfo 获取 虚拟路由VRRP连接的绑定信息，
+// 连接需实现 ConnectionInfo() ConnectionInfo 方法（IPv4VRRPMsgCon、IPv6VRRPMsgCon 均已实现），未实现时返回零值
+func (r *VirtualRouter) GetConnectionInfo() ConnectionInfo {
+	return connInfoOf(r.vrrpConn)
+}
+
+// connInfoOf 获取 连接的绑定信息，连接未实现 ConnectionInfo 方法时返回零值
+func connInfoOf(conn VRRPMsgConnection) ConnectionInfo {
+	if c, ok := conn.(interface{ ConnectionInfo() ConnectionInfo }); ok {
+		return c.ConnectionInfo()
+	}
+	return ConnectionInfo{}
+}
+
+// ownerMAC 应答虚拟IP地址的MAC地址，ARP/NDP 广播均以此作为发送方MAC地址
+// 使用虚拟MAC地址时为对应协议族的虚拟MAC地址，否则为工作网口的MAC地址。
+func (r *VirtualRouter) ownerMAC() net.HardwareAddr {
+	if r.useVirtualMAC {
+		if r.ipvX == IPv6 {
+			return r.virtualRouterMACAddressIPv6
+		}
+		return r.virtualRouterMACAddressIPv4
+	}
+	return r.interfaceMAC()
+}
+
+// GetEffectiveMAC 获取 当前应答虚拟IP地址的MAC地址，工作网口没有MAC地址时返回空
+func (r *VirtualRouter) GetEffectiveMAC() net.HardwareAddr {
+	return r.ownerMAC()
+}
+
+// DisableAddrAnnouncer 关闭 虚拟IP地址的 ARP/NDP 广播，用于仅三层转发、无需二层广播的接口
+func (r *VirtualRouter) DisableAddrAnnouncer() *VirtualRouter {
+	if r.addrAnnouncer != nil {
+		_ = r.addrAnnouncer.Close()
+	}
+	r.addrAnnouncer = noopAnnouncer{}
+	return r
+}
+
+// GetPreferredSourceIP 获取 虚拟路由的优先IP地址
+func (r *VirtualRouter) GetPreferredSourceIP() net.IP {
+	return r.preferredSourceIP
+}
+
+// GetAdvInterval 获取 虚拟路由的心跳发送间隔
+func (r *VirtualRouter) GetAdvInterval() time.Duration {
+	return centisToDuration(r.advertisementInterval)
+}
+
+// GetAdvIntervalCentis 获取 VRRP消息发送间隔（心跳间隔），单位厘秒
+func (r *VirtualRouter) GetAdvIntervalCentis() uint16 {
+	return r.advertisementInterval
+}
+
+// GetMasterAdvInterval 获取 主节点的心跳间隔（Master_Adver_Interval）
+func (r *VirtualRouter) GetMasterAdvInterval() time.Duration {
+	return centisToDuration(r.advertisementIntervalOfMaster)
+}
+
+// GetMasterAdvIntervalCentis 获取 主节点的心跳间隔（Master_Adver_Interval），单位厘秒
+func (r *VirtualRouter) GetMasterAdvIntervalCentis() uint16 {
+	return r.advertisementIntervalOfMaster
+}
+
+// GetSkewTime 获取 Skew_Time，计算方式为 ((256 - Priority) * Master_Adver_Interval) / 256
+func (r *VirtualRouter) GetSkewTime() time.Duration {
+	return centisToDuration(r.skewTime)
+}
+
+// GetSkewTimeCentis 获取 Skew_Time，单位厘秒
+func (r *VirtualRouter) GetSkewTimeCentis() uint16 {
+	return r.skewTime
+}
+
+// GetMasterDownInterval 获取 Master_Down_Interval，计算方式为 (3 * Master_Adver_Interval) + Skew_Time
+func (r *VirtualRouter) GetMasterDownInterval() time.Duration {
+	return centisToDuration(r.masterDownInterval)
+}
+
+// GetMasterDownIntervalCentis 获取 Master_Down_Interval，单位厘秒
+func (r *VirtualRouter) GetMasterDownIntervalCentis() uint16 {
+	return r.masterDownInterval
+}
+
+// centisToDuration 厘秒转换为时间间隔
+func centisToDuration(centis uint16) time.Duration {
+	return time.Duration(centis) * 10 * time.Millisecond
+}
+
+// observeRemoteAdvInterval 记录主节点的心跳间隔，与本地配置不一致时记录告警日志
+func (r *VirtualRouter) observeRemoteAdvInterval(interval uint16) {
+	prev := r.remoteAdvInterval.Swap(uint32(interval))
+	if prev != uint32(interval) && interval != r.advertisementInterval {
+		r.logger().Printf("WARN VRID [%d] advertisement interval of master %v mismatches local %v",
+			r.vrID, time.Duration(interval)*10*time.Millisecond, r.GetAdvInterval())
+	}
+}
+
+// GetRemoteAdvInterval 获取 BACKUP 状态下观测到的主节点心跳间隔，尚未观测到时返回 0
+func (r *VirtualRouter) GetRemoteAdvInterval() time.Duration {
+	return time.Duration(r.remoteAdvInterval.Load()) * 10 * time.Millisecond
+}
+
+// IntervalMismatch 主节点心跳间隔是否与本地配置的心跳间隔不一致
+// 心跳间隔配置不一致将导致故障切换时间不可预期，尚未观测到主节点心跳间隔时返回 false。
+func (r *VirtualRouter) IntervalMismatch() bool {
+	remote := r.remoteAdvInterval.Load()
+	return remote != 0 && remote != uint32(r.advertisementInterval)
+}
+
+// GetPreempt 获取 虚拟路由的抢占模式
+func (r *VirtualRouter) GetPreempt() bool {
+	return r.preempt
+}
+
+// GetAcceptMode 获取 主路由器当前生效的接受模式，地址拥有者始终为 true，非 MASTER 状态时始终为 false
+func (r *VirtualRouter) GetAcceptMode() bool {
+	if r.GetState() != MASTER {
+		return false
+	}
+	return r.priority == 255 || r.accept
+}
+
+// GetVIPs 获取 虚拟路由的保护IP地址
+func (r *VirtualRouter) GetVIPs() []net.IP {
+	addrs := r.vipAddrs()
+	vips := make([]net.IP, 0, len(addrs))
+	for _, k := range addrs {
+		vips = append(vips, k.AsSlice())
+	}
+	return vips
+}
+
+// vipAddrs 获取 虚拟IP地址集合的快照，用于在不持有锁的情况下遍历（如广播 ARP/NDP 报文）
+func (r *VirtualRouter) vipAddrs() []netip.Addr {
+	r.vipMu.RLock()
+	defer r.vipMu.RUnlock()
+	addrs := make([]netip.Addr, 0, len(r.protectedIPaddrs))
+	for k := range r.protectedIPaddrs {
+		addrs = append(addrs, k)
+	}
+	return addrs
+}
+
+// RangeVIPs 遍历 虚拟路由的保护IP地址，遍历过程中不分配内存
+// 当 fn 返回 false 时停止遍历。
+//
+// 注意：fn 中不可修改传入的IP地址，如需保留请复制；fn 中不可调用 AddIPvXAddr 或 RemoveIPvXAddr。
+func (r *VirtualRouter) RangeVIPs(fn func(net.IP) bool) {
+	r.vipMu.RLock()
+	defer r.vipMu.RUnlock()
+	for _, ip := range r.protectedIPaddrs {
+		if !fn(ip) {
+			return
+		}
+	}
+}
+
+// largerThan 比较IP数值大小 ip1 > ip2 （用于在优先级相同时IP大的优先）
+// 同一IPv4地址可能为4字节或16字节（IPv4-mapped IPv6）形式，比较前统一转换为相同长度，地址族不同时返回 false
+func largerThan(ip1, ip2 net.IP) bool {
+	if v4, peer4 := ip1.To4(), ip2.To4(); v4 != nil || peer4 != nil {
+		ip1, ip2 = v4, peer4
+	} else {
+		ip1, ip2 = ip1.To16(), ip2.To16()
+	}
+	if ip1 == nil || ip2 == nil {
+		return false
+	}
+	for index := range ip1 {
+		if ip1[index] > ip2[index] {
+			return true
+		} else if ip1[index] < ip2[index] {
 			return false
 		}
 	}
 	return false
 }
 
+// startup 处理启动事件，由 INIT 状态切换至 MASTER 或 BACKUP 状态，并开始接收VRRP消息
+func (r *VirtualRouter) startup() {
+	r.leaveInit()
+	// 监听VRRP消息
+	go r.fetchVRRPDaemon(r.vrrpConn)
+}
+
+// leaveInit 由 INIT 状态切换至 MASTER 或 BACKUP 状态，地址拥有者直接成为主节点
+func (r *VirtualRouter) leaveInit() {
+	if r.priority == 255 {
+		r.logger().Printf("VRID [%d] enter owner mode", r.vrID)
+		if !r.suppressInitialAdvert {
+			r.sendImmediateAdvertMessage()
+		}
+		if err := r.addrAnnouncer.AnnounceAll(r); err != nil {
+			r.logger().Printf("ERROR INIT to MASTER gratuitous arp sending: %v", err)
+			r.reportError(ErrorOpAnnounce, err)
+		}
+		// 设置广播定时器
+		r.makeAdvertTicker()
+		r.logger().Printf("VRID [%d] enter MASTER state", r.vrID)
+		r.setElectionReason(ElectionReasonOwner)
+		r.currentMaster.Store(nil)
+		r.masterSince = r.now()
+		atomic.StoreUint32(&r.state, MASTER)
+		r.stateChanged(Init2Master)
+	} else {
+		r.logger().Printf("VRID [%d] VR is not the owner of protected IP addresses", r.vrID)
+		r.setMasterAdvInterval(r.advertisementIntervalOfMaster)
+		// set up master down timer
+		r.makeMasterDownTimer()
+		r.logger().Printf("VRID [%d] enter BACKUP state", r.vrID)
+		atomic.StoreUint32(&r.state, BACKUP)
+		r.stateChanged(Init2Backup)
+	}
+}
+
 // stateMachine 状态机
 //
 // RFC 5798 6.3. State Transition Diagram
@@ -436,40 +1450,43 @@ func largerThan(ip1, ip2 net.IP) bool {
 //	|               |<----------------------|               |
 //	+---------------+                       +---------------+
 func (r *VirtualRouter) stateMachine() {
+	defer close(r.exited)
+	// 状态变更处理函数均在状态机协程中同步执行，退出时已全部完成，此时再回收连接
 	defer r.close()
+	defer r.running.Store(false)
+	defer r.stopping.Store(false)
+	defer func() { r.clock.stop(r.now()) }()
 	for {
+		// 记录状态机进展，见 LastProgress
+		r.lastProgress.Store(r.now().UnixNano())
+		// 资源已被强制回收（见 StopWithTimeout），退出状态机
+		select {
+		case <-r.closed:
+			r.logger().Printf("VRID [%d] resources closed, exit state machine.", r.vrID)
+			return
+		default:
+		}
+
 		switch r.state {
 		case INIT:
 
 			select {
+			case <-r.closed:
+				continue
+			case <-r.sendRetryTimer:
+				r.logger().Printf("VRID [%d] retry participating after consecutive advertisement send failures", r.vrID)
+				r.sendRetryTimer = nil
+				r.leaveInit()
+				r.sendRetry.Store(false)
 			case event := <-r.eventChannel:
+				r.debugf("event %v received", event)
 				if event == START {
-					logg.Printf("VRID [%d] event %v received", r.vrID, event)
-					if r.priority == 255 {
-						logg.Printf("VRID [%d] enter owner mode", r.vrID)
-						r.sendAdvertMessage()
-						if err := r.addrAnnouncer.AnnounceAll(r); err != nil {
-							logg.Printf("ERROR INIT to MASTER gratuitous arp sending: %v", err)
-						}
-						// 设置广播定时器
-						r.makeAdvertTicker()
-						logg.Printf("VRID [%d] enter MASTER state", r.vrID)
-						atomic.StoreUint32(&r.state, MASTER)
-						r.stateChanged(Init2Master)
-					} else {
-						logg.Printf("VRID [%d] VR is not the owner of protected IP addresses", r.vrID)
-						r.setMasterAdvInterval(r.advertisementIntervalOfMaster)
-						// set up master down timer
-						r.makeMasterDownTimer()
-						logg.Printf("VRID [%d] enter BACKUP state", r.vrID)
-						atomic.StoreUint32(&r.state, BACKUP)
-						r.stateChanged(Init2Backup)
-					}
-
-					// 监听VRRP消息
-					go r.fetchVRRPDaemon()
+					r.logger().Printf("VRID [%d] event %v received", r.vrID, event)
+					r.startup()
+				} else if event == LINKUP && r.linkDown.Load() {
+					r.handleLinkUp()
 				} else if event == SHUTDOWN {
-					logg.Printf("VRID [%d] SHUTDOWN close state machine.", r.vrID)
+					r.logger().Printf("VRID [%d] SHUTDOWN close state machine.", r.vrID)
 					return
 				}
 			}
@@ -478,12 +1495,14 @@ func (r *VirtualRouter) stateMachine() {
 
 			select {
 			case event := <-r.eventChannel:
+				r.debugf("event %v received", event)
 				// 收到 shutdown 事件
 				if event == SHUTDOWN {
-					logg.Printf("VRID [%d] SHUTDOWN event received virtual route will reset to INIT state.", r.vrID)
+					r.logger().Printf("VRID [%d] SHUTDOWN event received virtual route will reset to INIT state.", r.vrID)
 					// 关闭心跳包定时器
 					r.stopAdvertTicker()
-					// 设置优先级为 0（表示让渡主节点），并广播发送消息
+					// 设置优先级为 0（表示让渡主节点），并广播发送消息，
+					// 在执行状态变更处理函数与关闭连接之前同步发出
 					var priority = r.priority
 					r.setPriority(0)
 					r.sendAdvertMessage()
@@ -492,26 +1511,98 @@ func (r *VirtualRouter) stateMachine() {
 					// 进入初始化状态
 					atomic.StoreUint32(&r.state, INIT)
 					r.stateChanged(Master2Init)
+					r.mastershipLost(MastershipLostShutdown, nil)
+					if r.stopping.Load() {
+						r.logger().Printf("VRID [%d] SHUTDOWN close state machine.", r.vrID)
+						return
+					}
+				} else if event == PAUSE {
+					r.logger().Printf("VRID [%d] PAUSE event received, yield mastership and stop participating", r.vrID)
+					r.stopAdvertTicker()
+					// 发送优先级为 0 的心跳消息，通知备份路由器立即接管
+					var priority = r.priority
+					r.setPriority(0)
+					r.sendAdvertMessage()
+					r.setPriority(priority)
+					// 暂停期间不参与选举，主节点下线倒计时保持停止
+					r.makeMasterDownTimer()
+					r.stopMasterDownTimer()
+					r.paused.Store(true)
+					atomic.StoreUint32(&r.state, BACKUP)
+					r.stateChanged(Master2Backup)
+					r.mastershipLost(MastershipLostPaused, nil)
+				} else if event == LINKDOWN {
+					r.handleLinkDown()
 				}
 			case <-r.advertisementTicker.C:
+				r.debugf("advertisement ticker fired")
+				r.observeAdvertTick()
 				// 心跳包定时器到期，发送心跳包
 				r.sendAdvertMessage()
+				// 连续发送失败次数超过阈值，认为上行链路故障，进入初始化状态
+				if r.sendErrorThreshold > 0 && r.sendFailures >= r.sendErrorThreshold {
+					r.logger().Printf("VRID [%d] %d consecutive advertisement send failures, reset to INIT state", r.vrID, r.sendFailures)
+					r.stopAdvertTicker()
+					r.sendFailures = 0
+					// 等待 Master_Down_Interval 后重新参与选举，期间继续接收（并丢弃）心跳消息
+					r.sendRetry.Store(true)
+					r.sendRetryTimer = time.After(r.masterDownDuration())
+					atomic.StoreUint32(&r.state, INIT)
+					r.stateChanged(Master2Init)
+					r.mastershipLost(MastershipLostSendFailure, nil)
+				}
+			case <-r.macChanged:
+				r.debugf("interface MAC changed to %v", r.interfaceMAC())
+				r.handleMACChange()
+			case <-r.trackChanged:
+				r.debugf("track state changed, effective priority %d", r.effectivePriority())
+				if r.inFault() {
+					r.logger().Printf("VRID [%d] critical track failed, transit into BACKUP state", r.vrID)
+					r.stopAdvertTicker()
+					// 发送优先级为 0 的心跳消息，通知备份路由器立即接管
+					var priority = r.priority
+					r.setPriority(0)
+					r.sendAdvertMessage()
+					r.setPriority(priority)
+					// 初始化主节点下线倒计时
+					r.makeMasterDownTimer()
+					atomic.StoreUint32(&r.state, BACKUP)
+					r.stateChanged(Master2Backup)
+					r.mastershipLost(MastershipLostFault, nil)
+				} else {
+					// 立即通告新的优先级，使更高优先级的备份路由器及时抢占，受 SetMaxAdvertRate 限速
+					r.sendImmediateAdvertMessage()
+				}
 			case packet := <-r.packetQueue:
-				// 优先级比主节点高，或者 优先级相同但是源IP比主节点的优先源IP大
-				// 那么认为 收到了一个更高优先级的主节点的心跳包，主节点让渡
-				if packet.GetPriority() > r.priority ||
-					(packet.GetPriority() == r.priority && largerThan(packet.Pshdr.Saddr, r.preferredSourceIP)) {
+				r.debugf("advertisement from %s priority %d processed", packet.Pshdr.Saddr, packet.GetPriority())
+				// 地址拥有者（优先级 255）永不让渡主节点，
+				// 收到其他优先级为 255 的心跳包说明网络中存在配置错误的重复拥有者
+				if r.priority == 255 {
+					if packet.GetPriority() == 255 {
+						r.stats.ownerConflict.Add(1)
+						r.logger().Printf("VRID [%d] duplicate owner conflict, %s also advertises priority 255", r.vrID, packet.Pshdr.Saddr)
+					}
+				} else if !r.election().ShouldYield(r, packet) {
+					// 忽略优先级低的所有消息，记录保持主节点的原因
+					r.setElectionReason(r.electionReason(packet))
+				} else if packet.GetPriority() == r.effectivePriority() && r.inMasterDwell() {
+					// 处于最短主节点保持时间内，不因相同优先级的源IP地址比较而让渡，更高优先级的抢占不受影响
+					r.debugf("yield to %s deferred by minimum master dwell", packet.Pshdr.Saddr)
+					r.setElectionReason(ElectionReasonDwell)
+				} else {
+					// 选举策略判定让渡（默认策略下：优先级比主节点高，或者 优先级相同但是源IP比主节点的优先源IP大）
+					// 那么认为 收到了一个更高优先级的主节点的心跳包，主节点让渡
 					// 停止心跳包定时器
 					r.stopAdvertTicker()
 					// 设置新的主节点心跳消息发送定时器
 					r.setMasterAdvInterval(packet.GetAdvertisementInterval())
 					// 初始化主节点下线倒计时
 					r.makeMasterDownTimer()
+					r.observeMaster(packet)
 					// 切换状态至备份节点
 					atomic.StoreUint32(&r.state, BACKUP)
 					r.stateChanged(Master2Backup)
-				} else {
-					// 忽略优先级低的所有消息
+					r.mastershipLost(MastershipLostPreempted, packet.Pshdr.Saddr)
 				}
 			}
 
@@ -519,98 +1610,499 @@ func (r *VirtualRouter) stateMachine() {
 
 			select {
 			case event := <-r.eventChannel:
-
+				r.debugf("event %v received", event)
 				if event == SHUTDOWN {
-					logg.Printf("VRID [%d] SHUTDOWN event received virtual route will reset to INIT state.", r.vrID)
+					r.logger().Printf("VRID [%d] SHUTDOWN event received virtual route will reset to INIT state.", r.vrID)
 					// 关闭主节点下线倒计时
 					r.stopMasterDownTimer()
 					// 设置状态为 初始化
 					atomic.StoreUint32(&r.state, INIT)
+					r.paused.Store(false)
+					r.preemptDeadline.Store(0)
 					r.stateChanged(Backup2Init)
-					//return
+					if r.stopping.Load() {
+						r.logger().Printf("VRID [%d] SHUTDOWN close state machine.", r.vrID)
+						return
+					}
+				} else if event == PAUSE && !r.paused.Load() {
+					r.logger().Printf("VRID [%d] PAUSE event received, stop participating", r.vrID)
+					r.stopMasterDownTimer()
+					r.paused.Store(true)
+				} else if event == RESUME && r.paused.Load() {
+					r.logger().Printf("VRID [%d] RESUME event received, resume participating", r.vrID)
+					r.paused.Store(false)
+					if r.priority == 255 {
+						// 地址拥有者立即恢复为主节点
+						r.becomeMaster()
+					} else {
+						r.makeMasterDownTimer()
+					}
+				} else if event == LINKDOWN {
+					r.handleLinkDown()
 				}
 
+			case <-r.macChanged:
+				r.debugf("interface MAC changed to %v", r.interfaceMAC())
+				r.handleMACChange()
 			case packet := <-r.packetQueue:
-				//logg.Printf("VRID [%d] received a packet from %s priority %d", r.vrID, packet.Pshdr.Saddr.String(), packet.GetPriority())
+				if r.paused.Load() {
+					// 暂停期间忽略收到的心跳包
+					continue
+				}
+				r.debugf("advertisement from %s priority %d processed", packet.Pshdr.Saddr, packet.GetPriority())
 				// 收到心跳包
 				if packet.GetPriority() == 0 {
 					// 若心跳包优先级为 0，那么认为主节点让渡，设置主节点下线倒计时为 Skew_Time，进入选举状态
-					logg.Printf("VRID [%d] received an advertisement with priority 0, transit into MASTER state", r.vrID)
+					r.logger().Printf("VRID [%d] received an advertisement with priority 0, transit into MASTER state", r.vrID)
 					// 设置 Master_Down_Timer 为 Skew_Time 进入选举状态
+					r.electionCandidate = ""
 					r.resetMasterDownTimerToSkewTime()
 				} else {
 					// 若为非抢占模式，无论收到的心跳包优先级如何，都认为是来自主节点的心跳包
 					// 继续保持 BACKUP 状态
 					//
-					// 若收到的心跳包优先级比备份节点优先级高；
-					// 若优先级相同但是源IP比备份节点的优先源IP大；
+					// 若选举策略判定让渡（默认策略下：收到的心跳包优先级比备份节点优先级高，或优先级相同但是源IP比备份节点的优先源IP大）；
+					// 若优先级相同但不允许相同优先级抢占；
 					// 那么 认为是来自主节点的心跳包。
 					// 继续保持 BACKUP 状态
-					if r.preempt == false ||
-						packet.GetPriority() > r.priority ||
-						(packet.GetPriority() == r.priority && largerThan(packet.Pshdr.Saddr, r.preferredSourceIP)) {
+					//
+					// 若处于抢占延迟期间，同样暂不抢占，继续保持 BACKUP 状态
+					accept := r.preempt == false ||
+						r.election().ShouldYield(r, packet) ||
+						(packet.GetPriority() == r.effectivePriority() && !r.preemptEqualPriority)
+					if accept {
+						r.preemptDeadline.Store(0)
+					} else {
+						accept = r.delayPreempt()
+					}
+					if accept {
+						r.electionCandidate = ""
+						r.observeMaster(packet)
 						// 重置主节点下线倒计时器
+						r.observeRemoteAdvInterval(packet.GetAdvertisementInterval())
 						r.setMasterAdvInterval(packet.GetAdvertisementInterval())
 						r.resetMasterDownTimer()
+					} else {
+						// 不再重置主节点下线倒计时，到期后以该原因接管
+						r.electionCandidate = r.electionReason(packet)
 					}
 				}
 
 			case <-r.masterDownTimer.C:
-				logg.Printf("VRID [%d] enter MASTER state", r.vrID)
-				// 主节点下线倒计时到期，进入选举状态
-				// 组播当前节点的心跳消息，表示当前节点想要成为主节点
-				r.sendAdvertMessage()
-				// 发送ARP消息告知广播域内的主机当前主机接管了虚拟路由器的IP地址
-				if err := r.addrAnnouncer.AnnounceAll(r); err != nil {
-					logg.Printf("ERROR BACKUP to MASTER sending gratuitous arp: %v", err)
+				r.debugf("master down timer expired")
+				r.stats.masterDownExpired.Add(1)
+				if r.inFault() {
+					// 关键跟踪对象失败，保持 BACKUP 状态
+					r.logger().Printf("VRID [%d] critical track failed, stay in BACKUP state", r.vrID)
+					r.resetMasterDownTimer()
+					continue
+				}
+				if !r.election().ShouldBecomeMaster(r) {
+					// 选举策略不允许成为主节点，保持 BACKUP 状态
+					r.debugf("promotion rejected by election policy")
+					r.resetMasterDownTimer()
+					continue
 				}
-				// Set the Advertisement Timer to Advertisement interval
-				r.makeAdvertTicker()
-				// 进入主节点状态
-				atomic.StoreUint32(&r.state, MASTER)
-				r.stateChanged(Backup2Master)
+				// 主节点下线倒计时到期，进入选举状态
+				r.becomeMaster()
 			}
 		}
 
 	}
 }
 
+// becomeMaster 由 BACKUP 状态切换至 MASTER 状态
+func (r *VirtualRouter) becomeMaster() {
+	r.logger().Printf("VRID [%d] enter MASTER state", r.vrID)
+	r.currentMaster.Store(nil)
+	r.preemptDeadline.Store(0)
+	switch {
+	case r.priority == 255:
+		r.setElectionReason(ElectionReasonOwner)
+	case r.electionCandidate != "":
+		r.setElectionReason(r.electionCandidate)
+	default:
+		r.setElectionReason(ElectionReasonMasterDown)
+	}
+	r.electionCandidate = ""
+	r.masterSince = r.now()
+	// 组播当前节点的心跳消息，表示当前节点想要成为主节点
+	r.sendImmediateAdvertMessage()
+	// 发送ARP消息告知广播域内的主机当前主机接管了虚拟路由器的IP地址
+	if err := r.addrAnnouncer.AnnounceAll(r); err != nil {
+		r.logger().Printf("ERROR BACKUP to MASTER sending gratuitous arp: %v", err)
+		r.reportError(ErrorOpAnnounce, err)
+	}
+	// Set the Advertisement Timer to Advertisement interval
+	r.makeAdvertTicker()
+	// 进入主节点状态
+	atomic.StoreUint32(&r.state, MASTER)
+	r.stateChanged(Backup2Master)
+}
+
+// 失去主节点身份的原因
+const (
+	MastershipLostPreempted   = "preempted"    // 收到更高优先级的心跳消息，被其他路由器抢占（非计划内切换）
+	MastershipLostShutdown    = "shutdown"     // 虚拟路由器停止，主动让渡主节点（计划内切换）
+	MastershipLostSendFailure = "send failure" // 连续发送心跳消息失败次数超过阈值（非计划内切换）
+	MastershipLostFault       = "fault"        // 关键跟踪对象失败（非计划内切换）
+	MastershipLostPaused      = "paused"       // 虚拟路由器暂停，主动让渡主节点（计划内切换）
+	MastershipLostLinkDown    = "link down"    // 工作网口链路断开（非计划内切换），见 SetLinkMonitorInterval
+)
+
+// 成为或保持主节点的原因
+const (
+	ElectionReasonOwner      = "owner"       // 地址拥有者（优先级 255）直接成为主节点
+	ElectionReasonPriority   = "priority"    // 优先级高于对端
+	ElectionReasonIPTiebreak = "ip-tiebreak" // 优先级与对端相同，源IP地址较大
+	ElectionReasonMasterDown = "master-down" // 主节点失效或让渡，未与对端比较
+	ElectionReasonDwell      = "dwell"       // 源IP地址比较落败，但处于最短主节点保持时间内，见 SetMinMasterDwell
+)
+
+// LastElectionReason 获取 最近一次成为或保持主节点的原因，取值见 ElectionReasonPriority 等常量，
+// 用于诊断优先级相同的路由器之间出乎意料的选举结果。从未成为主节点时返回空。
+func (r *VirtualRouter) LastElectionReason() string {
+	if reason := r.lastElection.Load(); reason != nil {
+		return *reason
+	}
+	return ""
+}
+
+// electionReason 与对端心跳消息比较后胜出的原因
+func (r *VirtualRouter) electionReason(packet *VRRPPacket) string {
+	if packet.GetPriority() == r.effectivePriority() {
+		return ElectionReasonIPTiebreak
+	}
+	return ElectionReasonPriority
+}
+
+// setElectionReason 记录成为或保持主节点的原因
+func (r *VirtualRouter) setElectionReason(reason string) {
+	r.lastElection.Store(&reason)
+}
+
+// mastershipLossInfo 失去主节点身份的原因与对端
+type mastershipLossInfo struct {
+	reason string
+	peer   net.IP
+}
+
+// OnMastershipLost 设置 失去主节点身份时的回调函数，用于区分计划内与非计划内的主备切换
+// reason: 失去主节点身份的原因，取值见 MastershipLostPreempted 等常量
+// peer: 抢占主节点的路由器源地址，非抢占原因时为 nil
+//
+// 回调函数在状态机协程中同步调用，请勿在其中执行耗时操作。
+func (r *VirtualRouter) OnMastershipLost(handler func(reason string, peer net.IP)) *VirtualRouter {
+	r.mastershipLostHandler = handler
+	return r
+}
+
+// GetMastershipLost 获取 最近一次失去主节点身份的原因与抢占主节点的路由器源地址，从未失去时 reason 为空
+func (r *VirtualRouter) GetMastershipLost() (reason string, peer net.IP) {
+	if info := r.lastMastershipLost.Load(); info != nil {
+		return info.reason, info.peer
+	}
+	return "", nil
+}
+
+// mastershipLost 记录失去主节点身份的原因并调用回调函数
+func (r *VirtualRouter) mastershipLost(reason string, peer net.IP) {
+	r.lastMastershipLost.Store(&mastershipLossInfo{reason: reason, peer: peer})
+	if peer != nil {
+		r.logger().Printf("VRID [%d] lost mastership: %s by %s", r.vrID, reason, peer)
+	} else {
+		r.logger().Printf("VRID [%d] lost mastership: %s", r.vrID, reason)
+	}
+	if r.mastershipLostHandler != nil {
+		r.mastershipLostHandler(reason, peer)
+	}
+}
+
 // AddEventListener 添加状态机事件监听器
 // typ: 状态变更类型
 // handler: 状态变更时的回调函数
 //
 // return: 如果已经存在该类型的监听器，那么返回 true，否则返回 false
+//
+// 注意：该方法将替换该类型已注册的全部监听器，若需注册多个监听器请使用 AppendEventListener
 func (r *VirtualRouter) AddEventListener(typ transition, handler func(*VirtualRouter)) bool {
 	_, exist := r.transitionHandler[typ]
-	if exist {
-		r.transitionHandler[typ] = handler
-		return true
-	} else {
-		r.transitionHandler[typ] = handler
-	}
+	r.transitionHandler[typ] = []func(*VirtualRouter){handler}
 	return exist
 }
 
+// AppendEventListener 追加状态机事件监听器，同一状态变更类型可注册多个监听器，按注册顺序依次调用
+// typ: 状态变更类型
+// handler: 状态变更时的回调函数
+func (r *VirtualRouter) AppendEventListener(typ transition, handler func(*VirtualRouter)) *VirtualRouter {
+	r.transitionHandler[typ] = append(r.transitionHandler[typ], handler)
+	return r
+}
+
+// HandlerCount 获取 指定状态变更类型已注册的监听器数量，可用于启动时检查监听器是否被意外覆盖
+func (r *VirtualRouter) HandlerCount(typ transition) int {
+	return len(r.transitionHandler[typ])
+}
+
+// ErrStopped 虚拟路由器已停止且无法重新打开连接
+var ErrStopped = errors.New("virtual router stopped")
+
+// connOptions 已应用到连接上的设置
+type connOptions struct {
+	rejoinInterval time.Duration    // 周期性重新加入组播组的时间间隔
+	group          net.IP           // 组播地址，为空表示默认组播地址
+	strict         bool             // 是否丢弃非工作网口收到的消息
+	vrf            string           // 绑定的 VRF 设备名称
+	l2             bool             // 是否通过二层发送心跳消息
+	mark           *uint32          // 套接字标记，为空表示未设置
+	ttl            int              // 组播TTL（IPv6 为 Hop Limit），0 表示默认值 255
+	missingTTL     MissingTTLPolicy // 控制消息中没有TTL数据时的处理策略
+	vmac           bool             // 是否使用虚拟MAC地址发送 ARP/NDP 广播
+}
+
+// reopen 停止后重新打开连接与虚拟IP地址广播器，并恢复连接上的设置
+func (r *VirtualRouter) reopen() error {
+	if r.dial == nil {
+		return ErrStopped
+	}
+	conn, announcer, err := r.dial()
+	if err != nil {
+		return err
+	}
+	r.vrrpConn, r.addrAnnouncer = conn, announcer
+	r.closeOnce = sync.Once{}
+	r.closed = make(chan struct{})
+	r.exited = make(chan struct{})
+
+	opts := r.connOpts
+	if opts.rejoinInterval > 0 {
+		r.SetMulticastRejoinInterval(opts.rejoinInterval)
+	}
+	if opts.group != nil {
+		if err = r.SetMulticastGroup(opts.group); err != nil {
+			r.close()
+			return err
+		}
+	}
+	if opts.strict {
+		r.SetRejectForeignInterface(true)
+	}
+	if opts.missingTTL != MissingTTLReject {
+		r.SetMissingTTLPolicy(opts.missingTTL)
+	}
+	if opts.vrf != "" {
+		if err = r.SetVRF(opts.vrf); err != nil {
+			r.close()
+			return err
+		}
+	}
+	if r.version != VRRPv3 {
+		if err = r.SetProtocolVersion(r.version); err != nil {
+			r.close()
+			return err
+		}
+	}
+	if opts.mark != nil {
+		if err = r.SetSocketMark(*opts.mark); err != nil {
+			r.close()
+			return err
+		}
+	}
+	if opts.ttl != 0 {
+		if err = r.SetMulticastTTL(opts.ttl); err != nil {
+			r.close()
+			return err
+		}
+	}
+	if opts.vmac {
+		if err = r.SetUseVirtualMAC(true); err != nil {
+			r.close()
+			return err
+		}
+	}
+	if opts.l2 {
+		if err = r.SetL2Advertisement(true); err != nil {
+			r.close()
+			return err
+		}
+	}
+	r.attachTap()
+	// 重新打开连接时网口MAC地址可能已变化
+	if r.macCheckInterval > 0 {
+		r.refreshMAC()
+	}
+	r.logger().Printf("VRID [%d] connection reopened", r.vrID)
+	return nil
+}
+
 // Start 启动虚拟路由器
 // 虚拟路由器启动后，将开始监听VRRP消息，根据状态机的状态，切换至不同的状态。
-func (r *VirtualRouter) Start() {
-	// 发送启动命令
-	go func() {
-		r.eventChannel <- START
-	}()
+// 该方法将阻塞直至虚拟路由器停止，若开启了实例锁且获取失败则立即返回错误。
+// 停止后可再次调用 Start 重新启动，此时将重新打开连接，无法重新打开时返回错误。
+func (r *VirtualRouter) Start() error {
+	return r.StartContext(context.Background())
+}
+
+// StartContext 启动虚拟路由器，与 Start 相同，但在 ctx 取消时停止虚拟路由器。
+// ctx 取消后执行与 Stop 相同的停止流程：主节点发送优先级为 0 的心跳消息、停止定时器、回收连接等资源，
+// 连接关闭后接收VRRP消息的协程随之退出。因 ctx 取消而停止时返回 ctx.Err()，通过 Stop 正常停止时返回 nil。
+func (r *VirtualRouter) StartContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case <-r.closed:
+		// 已停止，重新打开连接
+		if err := r.reopen(); err != nil {
+			r.logger().Printf("ERROR VRID [%d] restart: %v", r.vrID, err)
+			return err
+		}
+	default:
+	}
+	if err := r.checkMTU(); err != nil {
+		r.logger().Printf("ERROR %v", err)
+		return err
+	}
+	if err := r.acquireInstanceLock(); err != nil {
+		r.logger().Printf("ERROR %v", err)
+		return err
+	}
+	r.running.Store(true)
+	r.clock.start(r.now())
+	r.lastProgress.Store(r.now().UnixNano())
+	if r.watchdogStall > 0 {
+		go r.watchdog(r.exited)
+	}
+	if r.macCheckInterval > 0 {
+		go r.macMonitor(r.exited)
+	}
+	r.linkDown.Store(false)
+	r.sendRetry.Store(false)
+	r.sendRetryTimer = nil
+	if r.linkMonitorInterval > 0 {
+		go r.linkMonitor(r.exited)
+	}
+	// 在状态机运行前同步处理启动事件，
+	// 确保状态切换完成且已开始接收VRRP消息，避免启动期间到达的消息因状态机尚处于 INIT 状态而丢失
+	r.logStartupConfig()
+	if atomic.LoadUint32(&r.state) == INIT {
+		r.logger().Printf("VRID [%d] event %v received", r.vrID, START)
+		r.startup()
+	}
+	if done := ctx.Done(); done != nil {
+		go r.stopOnDone(done, r.exited)
+	}
 	// 启动状态机
 	r.stateMachine()
+	return ctx.Err()
+}
+
+// stopOnDone 在 done 关闭时停止虚拟路由器，状态机已退出时直接返回
+// exited: 本次运行的状态机退出通知，重新启动时会被替换，因此由启动方传入
+func (r *VirtualRouter) stopOnDone(done <-chan struct{}, exited chan struct{}) {
+	select {
+	case <-done:
+	case <-exited:
+		return
+	}
+	r.logger().Printf("VRID [%d] context done, stopping", r.vrID)
+	r.stopping.Store(true)
+	// 发送停止事件，状态机已退出时无需发送
+	select {
+	case r.eventChannel <- SHUTDOWN:
+	case <-exited:
+	}
+}
+
+// logStartupConfig 记录虚拟路由器启动时实际生效的配置，便于通过日志确认配置
+func (r *VirtualRouter) logStartupConfig() {
+	info := r.GetConnectionInfo()
+	virtualMAC := r.virtualRouterMACAddressIPv4
+	if r.ipvX == IPv6 {
+		virtualMAC = r.virtualRouterMACAddressIPv6
+	}
+	r.logger().Printf("VRID [%d] source IP %v, joined multicast group %v on %s", r.vrID, r.preferredSourceIP, info.Group, r.ift.Name)
+	r.logger().Printf("VRID [%d] virtual MAC %v, effective MAC %v", r.vrID, virtualMAC, r.GetEffectiveMAC())
+	r.logger().Printf("VRID [%d] priority %d, advertisement interval %v, master down interval %v",
+		r.vrID, r.priority, r.GetAdvInterval(), r.ExpectedFailoverTime())
 }
 
 // Stop 停止虚拟路由器
+// 虚拟路由器正在运行时，等待状态机退出后返回：主节点让渡的优先级为 0 的心跳消息已发出，
+// 状态变更处理函数（如 Master2Init）均已执行完成，连接等资源已回收。
+// 在 Start 之前或与 Start 并发调用时，Start 处理停止事件后返回。
+//
+// 主节点停止时不发送 gratuitous ARP/NDP：链路层转发表的收敛由接管的备份路由器完成。
+// 备份路由器收到优先级为 0 的心跳消息后，在 Skew_Time 后成为主节点并广播虚拟IP地址，交换机与主机据此更新MAC地址表项；
+// 停止的主节点若再次广播，反而可能在新主节点之后将表项指回自身。请在 Master2Init 处理函数中移除虚拟IP地址，以停止应答ARP/NDP请求。
+// 请勿在状态变更处理函数中调用该方法。
 func (r *VirtualRouter) Stop() {
-	// 若不为 INIT 状态，
-	// 向发送停止命令，使状态机进入 INIT 状态。
-	if atomic.LoadUint32(&r.state) != INIT {
-		r.eventChannel <- SHUTDOWN
+	running := r.running.Load()
+	var exited chan struct{}
+	if running {
+		exited = r.exited
 	}
-	// 终止并退出状态机
+	// 发送停止命令，状态机由 MASTER 或 BACKUP 状态进入 INIT 状态后随即退出，
+	// 无论状态机是否已启动、处于何种状态均只需一个停止事件，避免与启动过程竞争时状态机停留在 INIT 状态
+	r.stopping.Store(true)
 	r.eventChannel <- SHUTDOWN
+	if running {
+		<-exited
+	}
+}
+
+// Pause 暂停虚拟路由器，用于短暂的本地维护。
+// 暂停期间不发送心跳消息、忽略收到的心跳消息且不参与选举，但不关闭连接，可通过 Resume 快速恢复。
+// 主节点暂停前发送优先级为 0 的心跳消息，使备份路由器立即接管。虚拟路由器未运行时不做任何操作。
+func (r *VirtualRouter) Pause() {
+	if atomic.LoadUint32(&r.state) == INIT {
+		return
+	}
+	r.eventChannel <- PAUSE
+}
+
+// Resume 恢复已暂停的虚拟路由器，恢复后以 BACKUP 状态重新参与选举，地址拥有者直接恢复为主节点
+func (r *VirtualRouter) Resume() {
+	if atomic.LoadUint32(&r.state) == INIT {
+		return
+	}
+	r.eventChannel <- RESUME
+}
+
+// IsPaused 虚拟路由器是否已暂停
+func (r *VirtualRouter) IsPaused() bool {
+	return r.paused.Load()
+}
+
+// ErrStopTimeout 在指定时间内虚拟路由器未能停止
+var ErrStopTimeout = errors.New("stop virtual router timeout")
+
+// StopWithTimeout 停止虚拟路由器，最多等待 timeout 时间
+// 若事件通道已满或状态机阻塞（如状态变更处理函数未返回），导致状态机未能在指定时间内退出，
+// 那么强制关闭连接回收资源，并返回 ErrStopTimeout。
+func (r *VirtualRouter) StopWithTimeout(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	r.stopping.Store(true)
+	// 发送停止事件，状态机已退出时无需发送
+	sent := true
+	select {
+	case r.eventChannel <- SHUTDOWN:
+	case <-r.exited:
+	case <-timer.C:
+		sent = false
+	}
+	if sent {
+		select {
+		case <-r.exited:
+			return nil
+		case <-timer.C:
+		}
+	}
+	r.logger().Printf("ERROR VRID [%d] state machine did not stop in %v, force closing", r.vrID, timeout)
+	r.close()
+	return ErrStopTimeout
 }
 
 // 关闭连接回收资源
@@ -618,38 +2110,163 @@ func (r *VirtualRouter) close() {
 	if r == nil {
 		return
 	}
-	if r.addrAnnouncer != nil {
-		_ = r.addrAnnouncer.Close()
+	r.closeOnce.Do(func() {
+		if r.addrAnnouncer != nil {
+			_ = r.addrAnnouncer.Close()
+		}
+		if r.vrrpConn != nil {
+			_ = r.vrrpConn.Close()
+		}
+		if r.vmacFilter != nil {
+			_ = r.vmacFilter.Close()
+			r.vmacFilter = nil
+		}
+		if r.l2Writer != nil {
+			_ = r.l2Writer.Close()
+		}
+		r.releaseInstanceLock()
+		close(r.closed)
+	})
+}
+
+// SourceSelector 源IP地址选择函数，从网口上所有符合协议族的候选地址中选择一个作为VRRP消息的源地址
+type SourceSelector func(candidates []net.IP) net.IP
+
+// SetSourceSelector 设置 源IP地址选择函数，仅对通过网口名称创建（NewVirtualRouter、NewVirtualRouterFromConfig）的虚拟路由器生效，
+// 用于网口存在多个同协议族地址时自定义选择策略（如选择最小/最大的地址、指定子网内的地址）。
+// selector 为 nil 时恢复默认策略，即选择第一个符合条件的地址；
+// 若 selector 返回的地址不在候选地址中，同样使用默认策略。
+// 需在 Start 前调用，选择的源地址发生变化时将重新打开连接。
+func (r *VirtualRouter) SetSourceSelector(selector SourceSelector) *VirtualRouter {
+	r.sourceSelector = selector
+	if !r.autoSource || r.running.Load() {
+		return r
+	}
+	addrs, err := r.interfaceAddrs()
+	if err != nil {
+		r.logger().Printf("WARN VRID [%d] source selector: %v", r.vrID, err)
+		return r
+	}
+	candidates := sourceCandidates(addrs, r.ipvX)
+	if len(candidates) == 0 {
+		return r
 	}
-	if r.vrrpConn != nil {
-		_ = r.vrrpConn.Close()
+	preferred := r.selectSourceIP(candidates)
+	if r.ipvX == IPv4 {
+		preferred = preferred.To4()
+	} else {
+		preferred = preferred.To16()
+	}
+	if preferred.Equal(r.preferredSourceIP) {
+		return r
+	}
+	r.logger().Printf("VRID [%d] source selector chose %v instead of %v", r.vrID, preferred, r.preferredSourceIP)
+	r.preferredSourceIP = preferred
+	// 连接已使用原源地址创建，关闭后重新打开，重新打开失败时 Start 将再次尝试
+	r.close()
+	if err = r.reopen(); err != nil {
+		r.logger().Printf("ERROR VRID [%d] reopen with source %v: %v", r.vrID, preferred, err)
 	}
+	return r
 }
 
-// interfacePreferIP 获取网口上第一个IPv4或IPv6地址
+// interfacePreferIP 获取网口上用作VRRP消息源地址的IPv4或IPv6地址，即第一个符合条件的地址，见 SetSourceSelector
 func interfacePreferIP(itf *net.Interface, IPvX byte) (net.IP, error) {
 	addrs, err := itf.Addrs()
 	if err != nil {
 		return nil, fmt.Errorf("interfacePreferIP: %v", err)
 	}
+	candidates := sourceCandidates(addrs, IPvX)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("interfacePreferIP: can not find valid IP addrs on %v", itf.Name)
+	}
+	return candidates[0], nil
+}
+
+// sourceCandidates 筛选 符合协议族的候选源地址，IPv4 使用全局单播地址，IPv6 使用链路本地地址
+func sourceCandidates(addrs []net.Addr, IPvX byte) []net.IP {
+	var candidates []net.IP
 	for _, addr := range addrs {
 		ipaddr, _, _ := net.ParseCIDR(addr.String())
 		if len(ipaddr) == 0 {
 			continue
 		}
 		if IPvX == IPv4 {
-			if ipaddr.To4() != nil {
-				if ipaddr.IsGlobalUnicast() {
-					return ipaddr, nil
-				}
+			if ipaddr.To4() != nil && ipaddr.IsGlobalUnicast() {
+				candidates = append(candidates, ipaddr)
 			}
 		} else {
-			if ipaddr.To4() == nil {
-				if ipaddr.IsLinkLocalUnicast() {
-					return ipaddr, nil
-				}
+			if ipaddr.To4() == nil && ipaddr.IsLinkLocalUnicast() {
+				candidates = append(candidates, ipaddr)
+			}
+		}
+	}
+	return candidates
+}
+
+// selectSourceIP 使用源IP地址选择函数从候选地址中选择源地址
+func (r *VirtualRouter) selectSourceIP(candidates []net.IP) net.IP {
+	if r.sourceSelector != nil {
+		chosen := r.sourceSelector(append([]net.IP(nil), candidates...))
+		for _, candidate := range candidates {
+			if candidate.Equal(chosen) {
+				return candidate
 			}
 		}
+		r.logger().Printf("WARN VRID [%d] source selector returned %v which is not a candidate, fallback to %v", r.vrID, chosen, candidates[0])
+	}
+	return candidates[0]
+}
+
+// ErrStateMachineStalled 状态机在看门狗设定的时间内没有取得进展
+var ErrStateMachineStalled = errors.New("state machine stalled")
+
+// LastProgress 获取 状态机最近一次取得进展（处理事件、心跳消息或定时器）的时间，
+// 监控进程可据此判断状态机是否停滞（如状态变更处理函数阻塞）。
+func (r *VirtualRouter) LastProgress() time.Time {
+	return time.Unix(0, r.lastProgress.Load())
+}
+
+// minWatchdogStall 看门狗停滞判定时间下限，与最小心跳间隔一致
+const minWatchdogStall = 10 * time.Millisecond
+
+// SetWatchdog 设置 状态机看门狗，需在 Start 前调用，stall 小于等于 0 表示关闭（默认关闭），小于 10ms 时按 10ms 处理。
+// 状态机超过 stall 时间没有取得进展时，记录错误日志并向 Errors 通道写入 ErrStateMachineStalled，
+// forceClose 为 true 时同时强制关闭连接回收资源，状态机恢复后随即退出。
+// 正常运行时状态机至少每个心跳间隔（MASTER）或 Master_Down_Interval（BACKUP）取得一次进展，stall 应大于二者。
+func (r *VirtualRouter) SetWatchdog(stall time.Duration, forceClose bool) *VirtualRouter {
+	if stall > 0 && stall < minWatchdogStall {
+		// 检查周期为 stall 的 1/4，过小的 stall 无意义
+		stall = minWatchdogStall
+	}
+	r.watchdogStall = stall
+	r.watchdogForceClose = forceClose
+	return r
+}
+
+// watchdog 看门狗，周期性检查状态机是否停滞，状态机退出后结束
+// exited: 状态机退出通道，重新启动时通道会被替换，因此由启动方传入
+func (r *VirtualRouter) watchdog(exited <-chan struct{}) {
+	ticker := time.NewTicker(r.watchdogStall / 4)
+	defer ticker.Stop()
+	var fired int64
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+		last := r.lastProgress.Load()
+		// 暂停或链路断开（停留在 INIT 等待链路恢复）期间可能长时间没有事件，不视为停滞
+		if r.paused.Load() || r.linkDown.Load() || last == fired || r.now().Sub(time.Unix(0, last)) < r.watchdogStall {
+			continue
+		}
+		// 同一次停滞仅报告一次
+		fired = last
+		r.logger().Printf("ERROR VRID [%d] state machine made no progress since %v", r.vrID, time.Unix(0, last))
+		r.reportError(ErrorOpWatchdog, ErrStateMachineStalled)
+		if r.watchdogForceClose {
+			r.close()
+		}
 	}
-	return nil, fmt.Errorf("interfacePreferIP: can not find valid IP addrs on %v", itf.Name)
 }
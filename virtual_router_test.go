@@ -0,0 +1,2061 @@
+package govrrp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memNetwork 内存中的组播网络，连接到同一网络的连接互相可见
+type memNetwork struct {
+	mu    sync.Mutex
+	conns []*memConn
+
+	// 以下参数用于模拟网络时延与丢包，均为可选
+	delay     time.Duration                               // 每个报文的投递时延
+	dropRate  float64                                     // 丢包概率 [0, 1]，使用 rand 判定
+	rand      *rand.Rand                                  // 丢包判定使用的随机数，固定种子以保证测试可重复
+	drop      func(src net.IP, pkt *VRRPPacket) bool      // 自定义丢包判定，返回 true 时丢弃
+	afterFunc func(d time.Duration, f func()) func() bool // 时钟，用于延迟投递，默认 time.AfterFunc
+}
+
+// dropped 判定报文是否丢弃
+func (n *memNetwork) dropped(src net.IP, pkt *VRRPPacket) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.drop != nil && n.drop(src, pkt) {
+		return true
+	}
+	if n.dropRate > 0 {
+		if n.rand == nil {
+			n.rand = rand.New(rand.NewSource(1))
+		}
+		return n.rand.Float64() < n.dropRate
+	}
+	return false
+}
+
+// schedule 按照网络时延投递报文
+func (n *memNetwork) schedule(f func()) {
+	n.mu.Lock()
+	delay, after := n.delay, n.afterFunc
+	n.mu.Unlock()
+	if delay <= 0 {
+		f()
+		return
+	}
+	if after == nil {
+		after = func(d time.Duration, f func()) func() bool { return time.AfterFunc(d, f).Stop }
+	}
+	after(delay, f)
+}
+
+// memResult 一次 ReadMessage 的结果
+type memResult struct {
+	pkt *VRRPPacket
+	err error
+}
+
+// memConn 基于内存的 VRRPMsgConnection 实现
+type memConn struct {
+	network *memNetwork
+	src     net.IP
+	group   net.IP
+	in      chan memResult
+	done    chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	sent     []*VRRPPacket
+	writeErr error // 不为空时 WriteMessage 返回该错误
+}
+
+// dial 在网络中创建一个源地址为 src 的连接
+func (n *memNetwork) dial(src net.IP) *memConn {
+	c := &memConn{
+		network: n,
+		src:     src,
+		group:   VRRPMultiAddrIPv4,
+		in:      make(chan memResult, 64),
+		done:    make(chan struct{}),
+	}
+	if c.family() == IPv6 {
+		c.group = VRRPMultiAddrIPv6
+	}
+	n.mu.Lock()
+	n.conns = append(n.conns, c)
+	n.mu.Unlock()
+	return c
+}
+
+func (n *memNetwork) peers(self *memConn) []*memConn {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var res []*memConn
+	for _, c := range n.conns {
+		if c != self {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func (c *memConn) family() byte {
+	if c.src.To4() != nil {
+		return IPv4
+	}
+	return IPv6
+}
+
+func (c *memConn) WriteMessage(packet *VRRPPacket) error {
+	select {
+	case <-c.done:
+		return NetErr{errors.New("memConn: use of closed connection")}
+	default:
+	}
+	raw := packet.ToBytes()
+	c.mu.Lock()
+	if c.writeErr != nil {
+		c.mu.Unlock()
+		return c.writeErr
+	}
+	c.sent = append(c.sent, packet)
+	group := c.group
+	c.mu.Unlock()
+	if c.network == nil {
+		return nil
+	}
+	for _, peer := range c.network.peers(c) {
+		if !peer.getGroup().Equal(group) {
+			continue
+		}
+		cp, err := FromBytes(c.family(), raw)
+		if err != nil {
+			return err
+		}
+		cp.Pshdr = &PseudoHeader{Saddr: c.src, Daddr: group, Protocol: VRRPIPProtocolNumber, Len: uint16(len(raw))}
+		if c.network.dropped(c.src, cp) {
+			continue
+		}
+		peer := peer
+		c.network.schedule(func() { peer.deliver(cp, nil) })
+	}
+	return nil
+}
+
+// deliver 向连接投递一个接收结果
+func (c *memConn) deliver(pkt *VRRPPacket, err error) {
+	select {
+	case c.in <- memResult{pkt: pkt, err: err}:
+	case <-c.done:
+	}
+}
+
+func (c *memConn) ReadMessage() (*VRRPPacket, error) {
+	// 优先读取已投递的结果
+	select {
+	case res := <-c.in:
+		return res.pkt, res.err
+	default:
+	}
+	select {
+	case res := <-c.in:
+		return res.pkt, res.err
+	case <-c.done:
+		return nil, NetErr{errors.New("memConn: use of closed connection")}
+	}
+}
+
+func (c *memConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+func (c *memConn) ConnectionInfo() ConnectionInfo {
+	return ConnectionInfo{SourceIP: c.src, Group: c.getGroup(), InterfaceName: "mem0", InterfaceIndex: 1}
+}
+
+func (c *memConn) SetGroup(group net.IP) error {
+	if !group.IsMulticast() || (group.To4() != nil) != (c.family() == IPv4) {
+		return fmt.Errorf("memConn: %v is not a multicast address of the connection family", group)
+	}
+	c.mu.Lock()
+	c.group = group
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *memConn) getGroup() net.IP {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.group
+}
+
+// failWrites 设置 WriteMessage 返回的错误
+func (c *memConn) failWrites(err error) {
+	c.mu.Lock()
+	c.writeErr = err
+	c.mu.Unlock()
+}
+
+// sentPackets 返回已发送的报文
+func (c *memConn) sentPackets() []*VRRPPacket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*VRRPPacket(nil), c.sent...)
+}
+
+// fakeAnnouncer 记录广播次数的 AddrAnnouncer
+type fakeAnnouncer struct {
+	mu    sync.Mutex
+	count int
+	macs  []net.HardwareAddr // 每次广播使用的MAC地址
+}
+
+func (a *fakeAnnouncer) AnnounceAll(vr *VirtualRouter) error {
+	a.mu.Lock()
+	a.count++
+	a.macs = append(a.macs, vr.ownerMAC())
+	a.mu.Unlock()
+	// 与真实的广播器相同，遍历虚拟IP地址集合
+	for range vr.vipAddrs() {
+	}
+	return nil
+}
+
+func (a *fakeAnnouncer) Close() error { return nil }
+
+// newTestRouter 创建一个使用内存连接的虚拟路由器
+func newTestRouter(t *testing.T, network *memNetwork, VRID byte, src string, priority byte) (*VirtualRouter, *memConn) {
+	t.Helper()
+	ip := net.ParseIP(src)
+	ift := &net.Interface{Index: 1, Name: "mem0", HardwareAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 1}}
+	vr, err := newVirtualRouter(VRID, ift, ip, priority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network == nil {
+		network = &memNetwork{}
+	}
+	conn := network.dial(vr.preferredSourceIP)
+	vr.vrrpConn = conn
+	vr.addrAnnouncer = &fakeAnnouncer{}
+	vr.dial = func() (VRRPMsgConnection, AddrAnnouncer, error) {
+		return network.dial(vr.preferredSourceIP), &fakeAnnouncer{}, nil
+	}
+	return vr, conn
+}
+
+// testInterval 测试中使用的心跳间隔
+const testInterval = 20 * time.Millisecond
+
+// startRouter 以较短的心跳间隔启动虚拟路由器，测试结束时停止
+func startRouter(t *testing.T, vr *VirtualRouter) {
+	t.Helper()
+	startRouterWithInterval(t, vr, testInterval)
+}
+
+// startRouterWithInterval 以指定的心跳间隔启动虚拟路由器，测试结束时停止
+func startRouterWithInterval(t *testing.T, vr *VirtualRouter, interval time.Duration) {
+	t.Helper()
+	vr.SetAdvInterval(interval)
+	vr.SetPriorityAndMasterAdvInterval(vr.GetPriority(), interval)
+	done := make(chan struct{})
+	go func() {
+		vr.Start()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		vr.Stop()
+		<-done
+	})
+}
+
+// waitState 等待虚拟路由器进入指定状态
+func waitState(vr *VirtualRouter, state uint32, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if vr.GetState() == state {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return vr.GetState() == state
+}
+
+func TestVirtualRouter_CountTruncatedAdvert(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "fe80::10", 100)
+	vr.state = BACKUP
+
+	// 声明 3 个 IPv6 地址，实际仅收到 1 个半
+	full := newAdvertisement(240, 100, "fe80::20")
+	for _, ip := range []string{"2001:db8::1", "2001:db8::2", "2001:db8::3"} {
+		full.AddIPvXAddr(IPv6, net.ParseIP(ip))
+	}
+	raw := full.ToBytes()
+	_, err := FromBytes(IPv6, raw[:8+16+8])
+	if !errors.Is(err, ErrTruncatedAdvert) {
+		t.Fatalf("expect ErrTruncatedAdvert, got %v", err)
+	}
+	if _, short := FromBytes(IPv6, raw[:4]); errors.Is(short, ErrTruncatedAdvert) {
+		t.Error("a packet shorter than the header should not be reported as truncated")
+	}
+	conn.deliver(nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: %w", err))
+	conn.deliver(newAdvertisement(240, 100, "fe80::20"), nil)
+	_ = conn.Close()
+
+	vr.fetchVRRPDaemon(vr.vrrpConn)
+	if n := vr.GetStatistics().TruncatedAdvert; n != 1 {
+		t.Errorf("expect 1 truncated advertisement, got %d", n)
+	}
+	if n := len(vr.packetQueue); n != 1 {
+		t.Errorf("expect the daemon to keep receiving after a truncated advertisement, got %d packets", n)
+	}
+}
+
+func TestVirtualRouter_CountUnexpectedType(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.state = BACKUP
+
+	raw := []byte{0x32, 240, 100, 0, 0x00, 0x64, 0, 0}
+	_, err := FromBytes(IPv4, raw)
+	conn.deliver(nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %w", err))
+	conn.deliver(nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %w", err))
+	_ = conn.Close()
+
+	vr.fetchVRRPDaemon(vr.vrrpConn)
+	if n := vr.GetStatistics().UnexpectedType; n != 2 {
+		t.Errorf("expect 2 unexpected type packets, got %d", n)
+	}
+}
+
+func TestVirtualRouter_CountForeignInterface(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.state = BACKUP
+
+	conn.deliver(nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %w, interface index 2", ErrForeignInterface))
+	_ = conn.Close()
+
+	vr.fetchVRRPDaemon(vr.vrrpConn)
+	if n := vr.GetStatistics().ForeignIfIndex; n != 1 {
+		t.Errorf("expect 1 foreign interface packet, got %d", n)
+	}
+}
+
+func TestVirtualRouter_SetZeroAddrPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy   ZeroAddrPolicy
+		accepted int
+		dropped  uint64
+	}{
+		{ZeroAddrAccept, 2, 0},
+		{ZeroAddrReject, 1, 1},
+	} {
+		vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+		vr.state = BACKUP
+		vr.SetZeroAddrPolicy(tc.policy)
+
+		withVIP := newAdvertisement(240, 100, "192.168.0.20")
+		withVIP.AddIPvXAddr(IPv4, net.ParseIP("192.168.0.100"))
+		conn.deliver(newAdvertisement(240, 100, "192.168.0.20"), nil)
+		conn.deliver(withVIP, nil)
+		_ = conn.Close()
+
+		vr.fetchVRRPDaemon(vr.vrrpConn)
+		if n := len(vr.packetQueue); n != tc.accepted {
+			t.Errorf("policy %d: expect %d packets accepted, got %d", tc.policy, tc.accepted, n)
+		}
+		if n := vr.GetStatistics().ZeroAddr; n != tc.dropped {
+			t.Errorf("policy %d: expect %d packets dropped, got %d", tc.policy, tc.dropped, n)
+		}
+	}
+}
+
+func TestVirtualRouter_RangeVIPs(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	want := map[string]bool{"192.168.0.200": true, "192.168.0.201": true, "192.168.0.202": true}
+	for ip := range want {
+		vr.AddIPvXAddr(net.ParseIP(ip))
+	}
+
+	visited := map[string]bool{}
+	vr.RangeVIPs(func(ip net.IP) bool {
+		visited[ip.String()] = true
+		return true
+	})
+	if len(visited) != len(want) {
+		t.Fatalf("expect %d VIPs visited, got %d", len(want), len(visited))
+	}
+	for ip := range want {
+		if !visited[ip] {
+			t.Errorf("VIP %s not visited", ip)
+		}
+	}
+
+	count := 0
+	vr.RangeVIPs(func(net.IP) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("expect iteration to stop after first VIP, visited %d", count)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		vr.RangeVIPs(func(net.IP) bool { return true })
+	})
+	if allocs != 0 {
+		t.Errorf("expect no allocation, got %v", allocs)
+	}
+}
+
+func TestVirtualRouter_SetPreemptEqualPriority(t *testing.T) {
+	for _, preemptEqual := range []bool{true, false} {
+		t.Run(fmt.Sprintf("preemptEqual=%v", preemptEqual), func(t *testing.T) {
+			network := &memNetwork{}
+			low, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+			high, _ := newTestRouter(t, network, 240, "192.168.0.20", 100)
+			high.SetPreemptEqualPriority(preemptEqual)
+
+			startRouter(t, low)
+			if !waitState(low, MASTER, time.Second) {
+				t.Fatal("the first router should become master")
+			}
+			startRouter(t, high)
+
+			time.Sleep(20 * testInterval)
+			if preemptEqual {
+				if high.GetState() != MASTER || low.GetState() != BACKUP {
+					t.Errorf("the router with higher IP should preempt, got high=%d low=%d", high.GetState(), low.GetState())
+				}
+			} else {
+				if high.GetState() != BACKUP || low.GetState() != MASTER {
+					t.Errorf("the router with higher IP should not preempt, got high=%d low=%d", high.GetState(), low.GetState())
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualRouter_SetMaxAdvertRate(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	now := time.Unix(1000, 0)
+	vr.now = func() time.Time { return now }
+	vr.SetMaxAdvertRate(3)
+
+	sent := 0
+	for i := 0; i < 10; i++ {
+		if vr.sendImmediateAdvertMessage() {
+			sent++
+		}
+		now = now.Add(10 * time.Millisecond)
+	}
+	if sent != 3 || len(conn.sentPackets()) != 3 {
+		t.Fatalf("expect 3 advertisements within one second, got %d", len(conn.sentPackets()))
+	}
+
+	now = now.Add(time.Second)
+	for i := 0; i < 10; i++ {
+		vr.sendImmediateAdvertMessage()
+	}
+	if n := len(conn.sentPackets()); n != 6 {
+		t.Fatalf("expect 6 advertisements after the next second, got %d", n)
+	}
+
+	// 定时心跳不受限制
+	vr.sendAdvertMessage()
+	if n := len(conn.sentPackets()); n != 7 {
+		t.Fatalf("periodic advertisement should not be rate limited, got %d", n)
+	}
+
+	// 主节点跟踪对象状态变更时的立即通告同样受限速
+	owner, ownerConn := newTestRouter(t, nil, 241, "192.168.0.11", 255)
+	owner.now = func() time.Time { return time.Unix(1000, 0) }
+	owner.SetMaxAdvertRate(3)
+	owner.AddTrackGroup(Track{Name: "uplink", Weight: 10})
+	startRouterWithInterval(t, owner, 5*time.Second)
+	if !waitState(owner, MASTER, time.Second) {
+		t.Fatal("owner should become master")
+	}
+	// 等待成为主节点时发送的心跳消息
+	time.Sleep(testInterval)
+	before := len(ownerConn.sentPackets())
+	for i := 0; i < 10; i++ {
+		_ = owner.SetTrackState("uplink", i%2 == 1)
+		time.Sleep(testInterval / 4)
+	}
+	if n := len(ownerConn.sentPackets()) - before; n > 3 {
+		t.Errorf("track change advertisements should be rate limited, got %d", n)
+	}
+}
+
+func TestVirtualRouter_OwnerNeverYields(t *testing.T) {
+	network := &memNetwork{}
+	low, _ := newTestRouter(t, network, 240, "192.168.0.10", 255)
+	high, _ := newTestRouter(t, network, 240, "192.168.0.20", 255)
+	startRouter(t, low)
+	startRouter(t, high)
+
+	time.Sleep(10 * testInterval)
+	if low.GetState() != MASTER || high.GetState() != MASTER {
+		t.Errorf("owners should never yield, got low=%d high=%d", low.GetState(), high.GetState())
+	}
+	if low.GetStatistics().OwnerConflict == 0 || high.GetStatistics().OwnerConflict == 0 {
+		t.Error("duplicate owner conflict should be reported")
+	}
+}
+
+// newAdvertisement 构造一个来自 src 的心跳消息，用于直接投递给内存连接
+func newAdvertisement(VRID, priority byte, src string) *VRRPPacket {
+	var packet VRRPPacket
+	packet.SetVersion(VRRPv3)
+	packet.SetType()
+	packet.SetVirtualRouterID(VRID)
+	packet.SetPriority(priority)
+	packet.SetAdvertisementInterval(uint16(testInterval / (10 * time.Millisecond)))
+	ip := net.ParseIP(src)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	packet.Pshdr = &PseudoHeader{Saddr: ip, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())}
+	return &packet
+}
+
+func TestVirtualRouter_StartReceivesFirstPacket(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	startRouter(t, vr)
+	conn.deliver(newAdvertisement(240, 200, "192.168.0.20"), nil)
+
+	deadline := time.Now().Add(time.Second)
+	for vr.GetStatistics().Received != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := vr.GetStatistics().Received; n != 1 {
+		t.Fatalf("the advertisement sent at start should be received, got %d", n)
+	}
+	if vr.GetState() != BACKUP {
+		t.Errorf("expect BACKUP state, got %d", vr.GetState())
+	}
+}
+
+func TestVirtualRouter_StopRightAfterStart(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	done := make(chan struct{})
+	go func() {
+		vr.Start()
+		close(done)
+	}()
+	waitState(vr, BACKUP, time.Second)
+	vr.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start should return after Stop")
+	}
+}
+
+func TestSetDefaultLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.New(&buf, "", 0)
+	prev := SetDefaultLogger(l)
+	if prev == nil {
+		t.Fatal("previous logger should be returned")
+	}
+	if SetDefaultLogger(nil) != l {
+		t.Error("nil logger should be ignored")
+	}
+	if got := SetDefaultLogger(prev); got != l {
+		t.Error("the replaced logger should be returned")
+	}
+	if logg() != prev {
+		t.Error("the previous logger should be restored")
+	}
+}
+
+func TestVirtualRouter_SetLogger(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	var buf bytes.Buffer
+	vr.SetLogger(log.New(&buf, "", 0))
+	vr.AddIPvXAddr(net.ParseIP("192.168.0.200"))
+	if !strings.Contains(buf.String(), "VIP 192.168.0.200 added") {
+		t.Errorf("router logger should be used, got %q", buf.String())
+	}
+	vr.SetLogger(nil)
+	if vr.logger() != logg() {
+		t.Error("default logger should be used after reset")
+	}
+}
+
+func TestVirtualRouter_SendOneAdvertisement(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	vr.AddIPvXAddr(net.ParseIP("192.168.0.200"))
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+
+	if err := vr.SendOneAdvertisement(); err != nil {
+		t.Fatal(err)
+	}
+	packet, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.GetVirtualRouterID() != 240 || packet.GetPriority() != 100 || !packet.Pshdr.Saddr.Equal(vr.preferredSourceIP) {
+		t.Errorf("unexpected advertisement %s", packet)
+	}
+	select {
+	case res := <-peer.in:
+		t.Errorf("expect exactly one advertisement, got another %v", res.pkt)
+	default:
+	}
+	if vr.GetState() != INIT {
+		t.Error("sending an advertisement should not change the state")
+	}
+}
+
+func TestVirtualRouter_SendOneAdvertisementPipeline(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.SetPacketMutator(func(p *VRRPPacket) { p.SetPriority(42) })
+	var observed []byte
+	vr.SetSentObserver(func(p *VRRPPacket) { observed = append(observed, p.GetPriority()) })
+	var sendErrs []error
+	vr.OnSendError(func(err error) { sendErrs = append(sendErrs, err) })
+
+	// 与周期性心跳消息相同，应用报文修改函数、调用发送观察函数并计入统计
+	if err := vr.SendOneAdvertisement(); err != nil {
+		t.Fatal(err)
+	}
+	sent := conn.sentPackets()
+	if len(sent) != 1 || sent[0].GetPriority() != 42 {
+		t.Fatalf("expect one mutated advertisement, got %v", sent)
+	}
+	if len(observed) != 1 || observed[0] != 42 {
+		t.Errorf("sent observer should see the mutated advertisement, got %v", observed)
+	}
+	if n := vr.GetStatistics().AdvertsSent; n != 1 {
+		t.Errorf("expect 1 advertisement sent, got %d", n)
+	}
+
+	conn.failWrites(NetErr{errors.New("network is down")})
+	if err := vr.SendOneAdvertisement(); err == nil {
+		t.Fatal("expect send error")
+	}
+	if n := vr.GetStatistics().SendErrors; n != 1 {
+		t.Errorf("expect 1 send error, got %d", n)
+	}
+	if len(sendErrs) != 1 {
+		t.Errorf("send error handler should be called once, got %d", len(sendErrs))
+	}
+	if len(observed) != 1 {
+		t.Error("sent observer should not be called on failure")
+	}
+}
+
+func TestVirtualRouter_OnSendError(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	conn.failWrites(NetErr{errors.New("network is down")})
+
+	var mu sync.Mutex
+	var errs []error
+	vr.OnSendError(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+	vr.SetSendErrorThreshold(3)
+	master2Init := make(chan struct{}, 1)
+	vr.AddEventListener(Master2Init, func(*VirtualRouter) { master2Init <- struct{}{} })
+
+	startRouter(t, vr)
+	select {
+	case <-master2Init:
+	case <-time.After(time.Second):
+		t.Fatal("master should reset to INIT after consecutive send failures")
+	}
+	if vr.GetState() != INIT {
+		t.Errorf("expect INIT state, got %d", vr.GetState())
+	}
+	mu.Lock()
+	n := len(errs)
+	mu.Unlock()
+	// 进入 MASTER 时立即发送一次，随后定时发送两次达到阈值
+	if n != 3 {
+		t.Errorf("expect 3 send error callbacks, got %d", n)
+	}
+	if got := vr.GetStatistics().SendErrors; got != 3 {
+		t.Errorf("expect 3 send errors counted, got %d", got)
+	}
+
+	// 上行链路恢复后，经过 Master_Down_Interval 重新参与选举并恢复发送心跳消息
+	conn.failWrites(nil)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should resume after writes succeed again")
+	}
+	sent := len(conn.sentPackets())
+	time.Sleep(3 * testInterval)
+	if len(conn.sentPackets()) == sent {
+		t.Error("master should keep sending advertisements after recovery")
+	}
+}
+
+// syncBuffer 并发安全的缓冲区，用于捕获日志
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestVirtualRouter_SetDebug(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	var buf syncBuffer
+	vr.SetLogger(log.New(&buf, "", 0))
+	vr.SetDebug(true)
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	time.Sleep(2 * testInterval)
+	_ = peer.WriteMessage(newAdvertisement(240, 200, "192.168.0.20"))
+	if !waitState(vr, BACKUP, time.Second) {
+		t.Fatal("router should yield to higher priority")
+	}
+
+	logs := buf.String()
+	for _, want := range []string{
+		"DEBUG VRID [240] state BACKUP: master down timer expired",
+		"DEBUG VRID [240] state MASTER: advertisement ticker fired",
+		"DEBUG VRID [240] state MASTER: advertisement from 192.168.0.20 priority 200 processed",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("expect debug log %q", want)
+		}
+	}
+
+	vr.SetDebug(false)
+	n := len(buf.String())
+	time.Sleep(2 * testInterval)
+	if strings.Contains(buf.String()[n:], "DEBUG") {
+		t.Error("debug logs should stop after disabled")
+	}
+}
+
+func TestVirtualRouter_HandlerCount(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 241, "192.168.0.10", 255)
+	called := make(chan int, 2)
+	vr.AppendEventListener(Init2Master, func(*VirtualRouter) { called <- 1 }).
+		AppendEventListener(Init2Master, func(*VirtualRouter) { called <- 2 })
+
+	if n := vr.HandlerCount(Init2Master); n != 2 {
+		t.Fatalf("expect 2 handlers, got %d", n)
+	}
+	if n := vr.HandlerCount(Backup2Master); n != 0 {
+		t.Fatalf("expect 0 handlers for unregistered transition, got %d", n)
+	}
+
+	startRouter(t, vr)
+	for _, want := range []int{1, 2} {
+		select {
+		case got := <-called:
+			if got != want {
+				t.Fatalf("expect handler %d called, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("handler %d not called", want)
+		}
+	}
+
+	if !vr.AddEventListener(Init2Master, func(*VirtualRouter) {}) {
+		t.Error("AddEventListener should report existing handlers")
+	}
+	if n := vr.HandlerCount(Init2Master); n != 1 {
+		t.Errorf("AddEventListener should replace handlers, got %d", n)
+	}
+}
+
+func TestVirtualRouter_SetSourceSelector(t *testing.T) {
+	var addrs []net.Addr
+	for _, cidr := range []string{"10.0.0.5/24", "fe80::1/64", "10.0.0.20/24", "127.0.0.1/8", "10.0.0.9/24"} {
+		ip, ipNet, _ := net.ParseCIDR(cidr)
+		ipNet.IP = ip
+		addrs = append(addrs, ipNet)
+	}
+	candidates := sourceCandidates(addrs, IPv4)
+	if len(candidates) != 3 {
+		t.Fatalf("expect 3 IPv4 candidates, got %v", candidates)
+	}
+	largest := func(candidates []net.IP) net.IP {
+		largest := candidates[0]
+		for _, ip := range candidates[1:] {
+			if bytes.Compare(ip.To16(), largest.To16()) > 0 {
+				largest = ip
+			}
+		}
+		return largest
+	}
+
+	network := &memNetwork{}
+	vr, conn := newTestRouter(t, network, 240, "10.0.0.5", 100)
+	other, _ := newTestRouter(t, network, 241, "10.0.0.5", 100)
+	vr.interfaceAddrs = func() ([]net.Addr, error) { return addrs, nil }
+	other.interfaceAddrs = vr.interfaceAddrs
+	vr.autoSource, other.autoSource = true, true
+	if got := vr.selectSourceIP(candidates); !got.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("default selector should choose first match, got %v", got)
+	}
+
+	// 选择函数仅作用于所属的虚拟路由器，源地址变化时重新打开连接
+	if vr.SetSourceSelector(largest) != vr {
+		t.Error("SetSourceSelector should return the router")
+	}
+	if got := vr.GetPreferredSourceIP(); !got.Equal(net.ParseIP("10.0.0.20")) {
+		t.Errorf("selector should choose largest, got %v", got)
+	}
+	select {
+	case <-conn.done:
+	default:
+		t.Error("connection with the old source should be closed")
+	}
+	if got := other.selectSourceIP(candidates); !got.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("selector should not affect other routers, got %v", got)
+	}
+	if got := other.GetPreferredSourceIP(); !got.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("other router source should not change, got %v", got)
+	}
+
+	vr.SetSourceSelector(func([]net.IP) net.IP { return net.ParseIP("192.168.1.1") })
+	if got := vr.GetPreferredSourceIP(); !got.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("non-candidate selection should fallback to first match, got %v", got)
+	}
+	vr.SetSourceSelector(largest).SetSourceSelector(nil)
+	if got := vr.GetPreferredSourceIP(); !got.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("nil selector should restore the default, got %v", got)
+	}
+
+	// 显式指定源地址的虚拟路由器不受选择函数影响
+	explicit, _ := newTestRouter(t, network, 242, "10.0.0.9", 100)
+	explicit.interfaceAddrs = vr.interfaceAddrs
+	explicit.SetSourceSelector(largest)
+	if got := explicit.GetPreferredSourceIP(); !got.Equal(net.ParseIP("10.0.0.9")) {
+		t.Errorf("explicit source should be kept, got %v", got)
+	}
+
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master after reopening")
+	}
+}
+
+func TestVirtualRouter_RemoveIPvXAddr(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 243, "192.168.0.10", 100)
+	vr.AddIPvXAddr(net.ParseIP("192.168.0.100").To4())
+	vr.RemoveIPvXAddr(net.ParseIP("192.168.0.100"))
+	if vips := vr.GetVIPs(); len(vips) != 0 {
+		t.Errorf("VIP should be removed, got %v", vips)
+	}
+}
+
+func TestVirtualRouter_SetMulticastGroup(t *testing.T) {
+	network := &memNetwork{}
+	vr, conn := newTestRouter(t, network, 244, "192.168.0.10", 255)
+	group := net.ParseIP("224.0.0.100")
+	if err := vr.SetMulticastGroup(net.ParseIP("192.168.0.1")); err == nil {
+		t.Error("expect error for unicast group")
+	}
+	if err := vr.SetMulticastGroup(net.ParseIP("ff02::12")); err == nil {
+		t.Error("expect error for group of other family")
+	}
+	if err := vr.SetMulticastGroup(group); err != nil {
+		t.Fatal(err)
+	}
+	if got := vr.GetConnectionInfo().Group; !got.Equal(group) {
+		t.Errorf("expect group %v, got %v", group, got)
+	}
+
+	def := network.dial(net.ParseIP("192.168.0.20").To4())
+	peer := network.dial(net.ParseIP("192.168.0.30").To4())
+	_ = peer.SetGroup(group)
+
+	_ = peer.WriteMessage(newAdvertisement(244, 100, "192.168.0.30"))
+	select {
+	case res := <-conn.in:
+		if !res.pkt.Pshdr.Daddr.Equal(group) {
+			t.Errorf("expect received on %v, got %v", group, res.pkt.Pshdr.Daddr)
+		}
+	default:
+		t.Error("router should receive on configured group")
+	}
+
+	if err := vr.SendOneAdvertisement(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case res := <-peer.in:
+		if !res.pkt.Pshdr.Daddr.Equal(group) {
+			t.Errorf("expect sent to %v, got %v", group, res.pkt.Pshdr.Daddr)
+		}
+	default:
+		t.Error("peer on configured group should receive advertisement")
+	}
+	select {
+	case <-def.in:
+		t.Error("peer on default group should not receive advertisement")
+	default:
+	}
+}
+
+func TestVirtualRouter_IntervalMismatch(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 245, "192.168.0.10", 100)
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+	startRouter(t, vr)
+	if !waitState(vr, BACKUP, time.Second) {
+		t.Fatal("router should be backup")
+	}
+
+	packet := newAdvertisement(245, 200, "192.168.0.20")
+	_ = peer.WriteMessage(packet)
+	deadline := time.Now().Add(time.Second)
+	for vr.GetRemoteAdvInterval() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if vr.IntervalMismatch() {
+		t.Errorf("interval %v should match local", vr.GetRemoteAdvInterval())
+	}
+
+	packet = newAdvertisement(245, 200, "192.168.0.20")
+	packet.SetAdvertisementInterval(100)
+	_ = peer.WriteMessage(packet)
+	deadline = time.Now().Add(time.Second)
+	for vr.GetRemoteAdvInterval() != time.Second && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !vr.IntervalMismatch() {
+		t.Error("expect interval mismatch")
+	}
+	if got := vr.GetRemoteAdvInterval(); got != time.Second {
+		t.Errorf("expect remote interval 1s, got %v", got)
+	}
+}
+
+func TestVirtualRouter_StopWithTimeout(t *testing.T) {
+	network := &memNetwork{}
+	vr, conn := newTestRouter(t, network, 246, "192.168.0.10", 255)
+	vr.SetAdvInterval(testInterval)
+	vr.SetPriorityAndMasterAdvInterval(255, testInterval)
+	// 阻塞的状态变更处理函数使状态机无法处理停止事件
+	wedged := make(chan struct{})
+	vr.AddEventListener(Init2Master, func(*VirtualRouter) { <-wedged })
+	done := make(chan struct{})
+	go func() {
+		_ = vr.Start()
+		close(done)
+	}()
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	if err := vr.StopWithTimeout(50 * time.Millisecond); !errors.Is(err, ErrStopTimeout) {
+		t.Fatalf("expect ErrStopTimeout, got %v", err)
+	}
+	select {
+	case <-conn.done:
+	default:
+		t.Error("connection should be closed after timeout")
+	}
+
+	close(wedged)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("router should exit after handler returns")
+	}
+}
+
+func TestVirtualRouter_StopWithTimeout_Graceful(t *testing.T) {
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 247, "192.168.0.10", 255)
+	vr.SetAdvInterval(testInterval)
+	vr.SetPriorityAndMasterAdvInterval(255, testInterval)
+	go func() { _ = vr.Start() }()
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	if err := vr.StopWithTimeout(time.Second); err != nil {
+		t.Fatalf("expect graceful stop, got %v", err)
+	}
+	if vr.GetState() != INIT {
+		t.Errorf("expect INIT state, got %d", vr.GetState())
+	}
+}
+
+func TestVirtualRouter_OnMastershipLost(t *testing.T) {
+	type loss struct {
+		reason string
+		peer   net.IP
+	}
+	network := &memNetwork{}
+	vr, _ := newTestRouter(t, network, 248, "192.168.0.10", 100)
+	lost := make(chan loss, 4)
+	vr.OnMastershipLost(func(reason string, peer net.IP) { lost <- loss{reason, peer} })
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	_ = peer.WriteMessage(newAdvertisement(248, 200, "192.168.0.20"))
+	select {
+	case l := <-lost:
+		if l.reason != MastershipLostPreempted || !l.peer.Equal(net.ParseIP("192.168.0.20")) {
+			t.Errorf("expect preempted by 192.168.0.20, got %s by %v", l.reason, l.peer)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("mastership lost callback not called")
+	}
+	if reason, peerIP := vr.GetMastershipLost(); reason != MastershipLostPreempted || peerIP == nil {
+		t.Errorf("unexpected last mastership lost %s %v", reason, peerIP)
+	}
+
+	owner, _ := newTestRouter(t, network, 249, "192.168.0.30", 255)
+	owner.OnMastershipLost(func(reason string, peer net.IP) { lost <- loss{reason, peer} })
+	owner.SetAdvInterval(testInterval)
+	owner.SetPriorityAndMasterAdvInterval(255, testInterval)
+	go func() { _ = owner.Start() }()
+	if !waitState(owner, MASTER, time.Second) {
+		t.Fatal("owner should become master")
+	}
+	if err := owner.StopWithTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case l := <-lost:
+		if l.reason != MastershipLostShutdown || l.peer != nil {
+			t.Errorf("expect shutdown, got %s by %v", l.reason, l.peer)
+		}
+	default:
+		t.Fatal("mastership lost callback not called on stop")
+	}
+}
+
+func TestVirtualRouter_MasterDownIntervalAbsorbsLoss(t *testing.T) {
+	// 心跳间隔 100ms，备份节点（优先级 100）的 Master_Down_Interval 为 3*100ms + Skew_Time(60ms)，
+	// 连续丢失 2 个心跳消息（间隔 300ms）不触发切换，连续丢失 4 个（间隔 500ms）触发切换
+	const interval = 100 * time.Millisecond
+	for _, tc := range []struct {
+		name     string
+		lost     int
+		delay    time.Duration
+		failover bool
+	}{
+		{name: "lose2", lost: 2},
+		{name: "lose4", lost: 4, failover: true},
+		{name: "latency", delay: 50 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			network := &memNetwork{delay: tc.delay}
+			master, _ := newTestRouter(t, network, 250, "192.168.0.20", 200)
+			backup, _ := newTestRouter(t, network, 250, "192.168.0.10", 100)
+			promoted := make(chan struct{}, 8)
+			backup.AddEventListener(Backup2Master, func(*VirtualRouter) { promoted <- struct{}{} })
+
+			// 主节点第 5 个心跳消息起连续丢失 lost 个
+			var sent int
+			network.drop = func(src net.IP, pkt *VRRPPacket) bool {
+				if !src.Equal(master.preferredSourceIP) {
+					return false
+				}
+				sent++
+				return sent >= 5 && sent < 5+tc.lost
+			}
+
+			startRouterWithInterval(t, master, interval)
+			if !waitState(master, MASTER, time.Second) {
+				t.Fatal("master should become master")
+			}
+			startRouterWithInterval(t, backup, interval)
+			time.Sleep(12 * interval)
+
+			select {
+			case <-promoted:
+				if !tc.failover {
+					t.Error("backup should absorb the loss and stay backup")
+				}
+			default:
+				if tc.failover {
+					t.Error("backup should promote after losing too many advertisements")
+				}
+			}
+		})
+	}
+}
+
+func TestComputeFailoverTime(t *testing.T) {
+	for _, tc := range []struct {
+		priority byte
+		interval time.Duration
+	}{
+		{100, time.Second},
+		{254, time.Second},
+		{1, time.Second},
+		{100, 100 * time.Millisecond},
+		{200, 3 * time.Second},
+	} {
+		// RFC 5798: Master_Down_Interval = 3 * Master_Adver_Interval + ((256 - Priority) * Master_Adver_Interval) / 256
+		want := 3*tc.interval + time.Duration(256-int(tc.priority))*tc.interval/256
+		got := ComputeFailoverTime(tc.priority, tc.interval)
+		// 计算精度为 10 ms
+		if diff := got - want; diff < 0 || diff >= 10*time.Millisecond {
+			t.Errorf("priority %d interval %v: expect about %v, got %v", tc.priority, tc.interval, want, got)
+		}
+
+		vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", tc.priority)
+		vr.SetPriorityAndMasterAdvInterval(tc.priority, tc.interval)
+		if vr.ExpectedFailoverTime() != got {
+			t.Errorf("priority %d interval %v: expect router failover %v, got %v", tc.priority, tc.interval, got, vr.ExpectedFailoverTime())
+		}
+	}
+}
+
+func TestVirtualRouter_SetMasterDownJitter(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.SetPriorityAndMasterAdvInterval(100, time.Second)
+	base := vr.ExpectedFailoverTime()
+	skew := time.Duration(vr.skewTime) * 10 * time.Millisecond
+
+	if got := vr.masterDownDuration(); got != base {
+		t.Errorf("expect no jitter by default, got %v", got)
+	}
+
+	rng := rand.New(rand.NewSource(42))
+	vr.jitterRand = rng.Float64
+	vr.SetMasterDownJitter(0.5)
+	var jittered bool
+	for i := 0; i < 1000; i++ {
+		got := vr.masterDownDuration()
+		if got < base || got > base+skew/2 {
+			t.Fatalf("jittered timer %v out of bounds [%v, %v]", got, base, base+skew/2)
+		}
+		jittered = jittered || got != base
+	}
+	if !jittered {
+		t.Error("expect jittered timer values")
+	}
+
+	vr.SetMasterDownJitter(3)
+	vr.jitterRand = func() float64 { return 0.999999 }
+	if got := vr.masterDownDuration(); got > base+skew {
+		t.Errorf("fraction should be clamped to 1, got %v", got)
+	}
+}
+
+func TestVirtualRouter_CurrentAdvertisement(t *testing.T) {
+	vr, _ := newTestRouter(t, &memNetwork{}, 251, "192.168.0.10", 150)
+	vr.SetAdvInterval(2 * time.Second)
+	vr.AddIPvXAddr(net.ParseIP("192.168.0.100"))
+	vr.AddIPvXAddr(net.ParseIP("192.168.0.101"))
+
+	validate := func(group net.IP) *VRRPPacket {
+		t.Helper()
+		packet := vr.CurrentAdvertisement()
+		if packet.GetVirtualRouterID() != 251 || packet.GetPriority() != 150 || packet.GetVersion() != byte(VRRPv3) {
+			t.Errorf("unexpected VRID %d priority %d version %d", packet.GetVirtualRouterID(), packet.GetPriority(), packet.GetVersion())
+		}
+		if packet.GetAdvertisementInterval() != 200 {
+			t.Errorf("expect interval 200 centiseconds, got %d", packet.GetAdvertisementInterval())
+		}
+		if addrs := packet.GetIPAddrs(); len(addrs) != 2 {
+			t.Errorf("expect 2 VIPs, got %v", addrs)
+		}
+		pshdr := &PseudoHeader{Saddr: vr.preferredSourceIP, Daddr: group, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())}
+		if !packet.ValidateCheckSum(pshdr) {
+			t.Errorf("checksum should be computed against group %v", group)
+		}
+		return packet
+	}
+	packet := validate(VRRPMultiAddrIPv4)
+
+	// 返回的报文为副本，修改不影响虚拟路由器
+	packet.SetPriority(1)
+	if vr.CurrentAdvertisement().GetPriority() != 150 {
+		t.Error("modifying returned packet should not affect router")
+	}
+
+	group := net.ParseIP("224.0.0.100")
+	if err := vr.SetMulticastGroup(group); err != nil {
+		t.Fatal(err)
+	}
+	validate(group)
+}
+
+func TestVirtualRouter_LogStartupConfig(t *testing.T) {
+	vr, _ := newTestRouter(t, &memNetwork{}, 252, "192.168.0.10", 100)
+	var buf syncBuffer
+	vr.SetLogger(log.New(&buf, "", 0))
+	startRouterWithInterval(t, vr, time.Second)
+	if !waitState(vr, BACKUP, time.Second) {
+		t.Fatal("router should become backup")
+	}
+	logs := buf.String()
+	for _, want := range []string{
+		"VRID [252] source IP 192.168.0.10, joined multicast group 224.0.0.18 on mem0",
+		"VRID [252] virtual MAC 00:00:5e:00:01:fc, effective MAC 02:00:00:00:00:01",
+		"VRID [252] priority 100, advertisement interval 1s, master down interval 3.61s",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("expect startup log %q, got:\n%s", want, logs)
+		}
+	}
+}
+
+func TestVirtualRouter_Restart(t *testing.T) {
+	network := &memNetwork{}
+	vr, first := newTestRouter(t, network, 253, "192.168.0.10", 255)
+	group := net.ParseIP("224.0.0.100")
+	if err := vr.SetMulticastGroup(group); err != nil {
+		t.Fatal(err)
+	}
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+	_ = peer.SetGroup(group)
+
+	run := func() {
+		t.Helper()
+		vr.SetAdvInterval(testInterval)
+		vr.SetPriorityAndMasterAdvInterval(255, testInterval)
+		go func() { _ = vr.Start() }()
+		if !waitState(vr, MASTER, time.Second) {
+			t.Fatal("router should become master")
+		}
+		if err := vr.StopWithTimeout(time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run()
+	select {
+	case <-first.done:
+	default:
+		t.Fatal("connection should be closed after stop")
+	}
+	for len(peer.in) > 0 {
+		<-peer.in
+	}
+
+	run()
+	second, ok := vr.vrrpConn.(*memConn)
+	if !ok || second == first {
+		t.Fatal("connection should be reopened on restart")
+	}
+	if !second.getGroup().Equal(group) {
+		t.Errorf("multicast group should be restored on restart, got %v", second.getGroup())
+	}
+	if len(second.sentPackets()) == 0 || len(peer.in) == 0 {
+		t.Error("restarted router should send advertisements")
+	}
+
+	// 无法重新打开连接时返回 ErrStopped
+	vr.dial = nil
+	if err := vr.Start(); !errors.Is(err, ErrStopped) {
+		t.Errorf("expect ErrStopped, got %v", err)
+	}
+}
+
+func TestVirtualRouter_AdvertisementSize(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "fe80::10", 100)
+	vr.ift.MTU = 1280
+	logs := &syncBuffer{}
+	vr.SetLogger(log.New(logs, "", 0))
+	// 40 字节 IPv6 首部 + 8 字节 VRRP 首部 + 每个地址 16 字节，1280 字节最多容纳 77 个地址
+	for i := 1; i <= 77; i++ {
+		vr.AddIPvXAddr(net.ParseIP(fmt.Sprintf("2001:db8::%x", i)))
+	}
+	if size := vr.AdvertisementSize(); size != 40+8+77*16 {
+		t.Fatalf("unexpected advertisement size %d", size)
+	}
+	if size := 40 + vr.CurrentAdvertisement().PacketSize(); size != vr.AdvertisementSize() {
+		t.Fatalf("advertisement size %d mismatch with packet size %d", vr.AdvertisementSize(), size)
+	}
+	if err := vr.checkMTU(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if strings.Contains(logs.String(), "WARN") {
+		t.Fatalf("unexpected warning: %s", logs.String())
+	}
+
+	vr.AddIPvXAddr(net.ParseIP("2001:db8::ff"))
+	if !strings.Contains(logs.String(), "WARN") || !strings.Contains(logs.String(), "MTU 1280") {
+		t.Errorf("expect MTU warning, got: %s", logs.String())
+	}
+	if err := vr.Start(); !errors.Is(err, ErrAdvertisementTooLarge) {
+		t.Errorf("expect ErrAdvertisementTooLarge, got %v", err)
+	}
+}
+
+func TestVirtualRouter_TimerGetters(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.SetAdvInterval(500 * time.Millisecond)
+	vr.SetPriorityAndMasterAdvInterval(200, 2*time.Second)
+
+	if vr.GetAdvIntervalCentis() != 50 || vr.GetAdvInterval() != 500*time.Millisecond {
+		t.Errorf("unexpected advertisement interval %d %v", vr.GetAdvIntervalCentis(), vr.GetAdvInterval())
+	}
+	if vr.GetMasterAdvIntervalCentis() != 200 || vr.GetMasterAdvInterval() != 2*time.Second {
+		t.Errorf("unexpected master advertisement interval %d %v", vr.GetMasterAdvIntervalCentis(), vr.GetMasterAdvInterval())
+	}
+	// Skew_Time = 200 - 200*200/256 = 200 - 156 = 44 厘秒
+	if vr.GetSkewTimeCentis() != 44 || vr.GetSkewTime() != 440*time.Millisecond {
+		t.Errorf("unexpected skew time %d %v", vr.GetSkewTimeCentis(), vr.GetSkewTime())
+	}
+	// Master_Down_Interval = 3 * 200 + 44
+	if vr.GetMasterDownIntervalCentis() != 644 || vr.GetMasterDownInterval() != 6440*time.Millisecond {
+		t.Errorf("unexpected master down interval %d %v", vr.GetMasterDownIntervalCentis(), vr.GetMasterDownInterval())
+	}
+	if vr.GetMasterDownInterval() != vr.ExpectedFailoverTime() {
+		t.Errorf("master down interval %v mismatch with expected failover time %v", vr.GetMasterDownInterval(), vr.ExpectedFailoverTime())
+	}
+}
+
+func TestVirtualRouter_SetSentObserver(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	vr.AddIPvXAddr(net.IPv4(192, 168, 0, 100))
+	var mu sync.Mutex
+	var observed []*VRRPPacket
+	vr.SetSentObserver(func(pkt *VRRPPacket) {
+		mu.Lock()
+		observed = append(observed, pkt)
+		mu.Unlock()
+	})
+	vr.SetAdvInterval(testInterval)
+	vr.SetPriorityAndMasterAdvInterval(255, testInterval)
+	go func() { _ = vr.Start() }()
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	time.Sleep(10 * testInterval)
+	conn.failWrites(errors.New("link down"))
+	time.Sleep(3 * testInterval)
+	if err := vr.StopWithTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sent := conn.sentPackets()
+	// 每个心跳间隔一个报文，发送失败的报文不通知观察函数
+	if len(observed) != len(sent) || len(sent) < 5 || len(sent) > 15 {
+		t.Fatalf("expect one observed packet per interval, observed %d, sent %d", len(observed), len(sent))
+	}
+	for i := range sent {
+		if observed[i] != sent[i] {
+			t.Fatalf("observed packet %d differs from sent packet", i)
+		}
+	}
+	if observed[0].GetPriority() != 255 || len(observed[0].GetIPAddrs()) != 1 {
+		t.Errorf("unexpected observed advertisement %v", observed[0])
+	}
+}
+
+func TestVirtualRouter_PauseResume(t *testing.T) {
+	network := &memNetwork{}
+	vr, conn := newTestRouter(t, network, 240, "192.168.0.10", 255)
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+	lost := make(chan string, 1)
+	vr.OnMastershipLost(func(reason string, _ net.IP) { lost <- reason })
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	vr.Pause()
+	if !waitState(vr, BACKUP, time.Second) || !vr.IsPaused() {
+		t.Fatal("paused master should leave MASTER state")
+	}
+	if reason := <-lost; reason != MastershipLostPaused {
+		t.Errorf("expect %s, got %s", MastershipLostPaused, reason)
+	}
+	sent := conn.sentPackets()
+	if last := sent[len(sent)-1]; last.GetPriority() != 0 {
+		t.Errorf("expect priority 0 yield before pausing, got %d", last.GetPriority())
+	}
+	// 暂停期间忽略收到的心跳消息，且不发送心跳消息
+	_ = peer.WriteMessage(newAdvertisement(240, 0, "192.168.0.20"))
+	time.Sleep(5 * testInterval)
+	if n := len(conn.sentPackets()); n != len(sent) {
+		t.Errorf("expect no advertisements while paused, got %d more", n-len(sent))
+	}
+	if vr.GetState() != BACKUP {
+		t.Errorf("expect BACKUP while paused, got %s", StateName(vr.GetState()))
+	}
+
+	vr.Resume()
+	if !waitState(vr, MASTER, time.Second) || vr.IsPaused() {
+		t.Fatal("resumed owner should become master")
+	}
+	time.Sleep(3 * testInterval)
+	if n := len(conn.sentPackets()); n <= len(sent) {
+		t.Error("expect advertisements to resume")
+	}
+}
+
+func TestVirtualRouter_SetPacketMutator(t *testing.T) {
+	network := &memNetwork{}
+	vr, conn := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+	vr.AddIPvXAddr(net.IPv4(192, 168, 0, 100))
+	vr.SetPacketMutator(func(pkt *VRRPPacket) { pkt.SetPriority(42) })
+
+	vr.sendAdvertMessage()
+	sent := conn.sentPackets()
+	if len(sent) != 1 || sent[0].GetPriority() != 42 {
+		t.Fatalf("expect mutated priority 42, got %v", sent)
+	}
+	received, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.GetPriority() != 42 || !received.ValidateCheckSum(received.Pshdr) {
+		t.Errorf("expect received priority 42 with valid checksum, got %d", received.GetPriority())
+	}
+
+	vr.SetPacketMutator(nil)
+	vr.sendAdvertMessage()
+	if sent = conn.sentPackets(); sent[1].GetPriority() != 100 {
+		t.Errorf("expect original priority after removing mutator, got %d", sent[1].GetPriority())
+	}
+}
+
+func TestVirtualRouter_WouldPreempt(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.AddTrackGroup(Track{Name: "uplink", Weight: 30}, Track{Name: "critical", Fault: true})
+	for _, tc := range []struct {
+		name         string
+		preempt      bool
+		equal        bool
+		trackDown    string
+		peerPriority byte
+		peerIP       string
+		expect       bool
+	}{
+		{name: "lower peer", preempt: true, equal: true, peerPriority: 90, peerIP: "192.168.0.20", expect: true},
+		{name: "higher peer", preempt: true, equal: true, peerPriority: 110, peerIP: "192.168.0.1", expect: false},
+		{name: "equal peer smaller IP", preempt: true, equal: true, peerPriority: 100, peerIP: "192.168.0.1", expect: true},
+		{name: "equal peer larger IP", preempt: true, equal: true, peerPriority: 100, peerIP: "192.168.0.20", expect: false},
+		{name: "equal priority disabled", preempt: true, equal: false, peerPriority: 100, peerIP: "192.168.0.1", expect: false},
+		{name: "preempt disabled", preempt: false, equal: true, peerPriority: 90, peerIP: "192.168.0.20", expect: false},
+		{name: "peer yields", preempt: false, equal: true, peerPriority: 0, peerIP: "192.168.0.20", expect: true},
+		{name: "tracked below peer", preempt: true, equal: true, trackDown: "uplink", peerPriority: 90, peerIP: "192.168.0.20", expect: false},
+		{name: "tracked above peer", preempt: true, equal: true, trackDown: "uplink", peerPriority: 60, peerIP: "192.168.0.20", expect: true},
+		{name: "critical fault", preempt: true, equal: true, trackDown: "critical", peerPriority: 0, peerIP: "192.168.0.20", expect: false},
+	} {
+		vr.SetPreemptMode(tc.preempt).SetPreemptEqualPriority(tc.equal)
+		if tc.trackDown != "" {
+			_ = vr.SetTrackState(tc.trackDown, false)
+		}
+		if got := vr.WouldPreempt(tc.peerPriority, net.ParseIP(tc.peerIP)); got != tc.expect {
+			t.Errorf("%s: expect %v, got %v", tc.name, tc.expect, got)
+		}
+		if tc.trackDown != "" {
+			_ = vr.SetTrackState(tc.trackDown, true)
+		}
+	}
+}
+
+func TestVirtualRouter_Errors(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	sendErr := NetErr{errors.New("network is unreachable")}
+	conn.failWrites(sendErr)
+	vr.sendAdvertMessage()
+
+	select {
+	case err := <-vr.Errors():
+		var re *RouterError
+		if !errors.As(err, &re) || re.Op != ErrorOpSend || re.VRID != 240 || !errors.Is(err, sendErr) {
+			t.Errorf("unexpected error %v", err)
+		}
+	default:
+		t.Fatal("send error should be reported")
+	}
+
+	// 通道已满时丢弃最早的错误
+	for i := 0; i < ERROR_CHANNEL_SIZE+3; i++ {
+		vr.reportError(ErrorOpReceive, fmt.Errorf("error %d", i))
+	}
+	if n := len(vr.Errors()); n != ERROR_CHANNEL_SIZE {
+		t.Fatalf("expect %d buffered errors, got %d", ERROR_CHANNEL_SIZE, n)
+	}
+	if err := <-vr.Errors(); !strings.HasSuffix(err.Error(), "error 3") {
+		t.Errorf("expect oldest errors dropped, got %v", err)
+	}
+}
+
+func TestVirtualRouter_SetSuppressInitialAdvert(t *testing.T) {
+	const interval = 200 * time.Millisecond
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	vr.SetSuppressInitialAdvert(true)
+	entered := make(chan int, 1)
+	vr.AddEventListener(Init2Master, func(*VirtualRouter) { entered <- len(conn.sentPackets()) })
+	startRouterWithInterval(t, vr, interval)
+	select {
+	case n := <-entered:
+		if n != 0 {
+			t.Errorf("expect no advertisement at Init2Master, got %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("owner should enter MASTER")
+	}
+	if n := len(conn.sentPackets()); n != 0 {
+		t.Errorf("expect no advertisement before the ticker fires, got %d", n)
+	}
+	time.Sleep(interval * 5 / 2)
+	if n := len(conn.sentPackets()); n < 1 || n > 3 {
+		t.Errorf("expect the ticker to drive advertisements, got %d", n)
+	}
+}
+
+func TestVirtualRouter_SetMissedAdvertThreshold(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 200)
+	vr.SetPriorityAndMasterAdvInterval(200, time.Second)
+	// Skew_Time = 100 - 100*200/256 = 22 厘秒
+	if vr.GetSkewTimeCentis() != 22 || vr.GetMasterDownIntervalCentis() != 3*100+22 {
+		t.Fatalf("unexpected default master down interval %d", vr.GetMasterDownIntervalCentis())
+	}
+	for _, n := range []int{1, 5, 3} {
+		if err := vr.SetMissedAdvertThreshold(n); err != nil {
+			t.Fatal(err)
+		}
+		if expect := uint16(n*100 + 22); vr.GetMasterDownIntervalCentis() != expect {
+			t.Errorf("n=%d: expect master down interval %d, got %d", n, expect, vr.GetMasterDownIntervalCentis())
+		}
+	}
+	if err := vr.SetMissedAdvertThreshold(5); err != nil {
+		t.Fatal(err)
+	}
+	// 主节点心跳间隔变化后仍按 n 计算
+	vr.setMasterAdvInterval(200)
+	if expect := uint16(5*200 + vr.GetSkewTimeCentis()); vr.GetMasterDownIntervalCentis() != expect {
+		t.Errorf("expect master down interval %d after interval change, got %d", expect, vr.GetMasterDownIntervalCentis())
+	}
+	if err := vr.SetMissedAdvertThreshold(0); err == nil {
+		t.Error("threshold 0 should be rejected")
+	}
+}
+
+func TestVirtualRouter_SetWatchdog(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	vr.SetAdvInterval(testInterval)
+	vr.SetPriorityAndMasterAdvInterval(255, testInterval)
+	vr.SetWatchdog(5*testInterval, true)
+	// 阻塞的状态变更处理函数使状态机停滞
+	wedged := make(chan struct{})
+	vr.AddEventListener(Init2Master, func(*VirtualRouter) { <-wedged })
+	done := make(chan struct{})
+	go func() {
+		_ = vr.Start()
+		close(done)
+	}()
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	last := vr.LastProgress()
+	select {
+	case err := <-vr.Errors():
+		if !errors.Is(err, ErrStateMachineStalled) {
+			t.Fatalf("expect ErrStateMachineStalled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watchdog should fire")
+	}
+	if !vr.LastProgress().Equal(last) {
+		t.Error("last progress should not advance while stalled")
+	}
+	select {
+	case <-conn.done:
+	case <-time.After(time.Second):
+		t.Error("watchdog should force close the connection")
+	}
+
+	close(wedged)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("router should exit after handler returns")
+	}
+}
+
+func TestVirtualRouter_SetWatchdogMinimum(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	vr.SetWatchdog(0, false)
+	if vr.watchdogStall != 0 {
+		t.Error("stall 0 should disable the watchdog")
+	}
+	vr.SetWatchdog(time.Nanosecond, false)
+	if vr.watchdogStall != minWatchdogStall {
+		t.Errorf("expect stall clamped to %v, got %v", minWatchdogStall, vr.watchdogStall)
+	}
+	// 过小的 stall 不应导致看门狗创建定时器时 panic
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+}
+
+func TestVirtualRouter_LastProgress(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	vr.SetWatchdog(10*testInterval, true)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	first := vr.LastProgress()
+	time.Sleep(3 * testInterval)
+	if !vr.LastProgress().After(first) {
+		t.Error("last progress should advance with each advertisement")
+	}
+	select {
+	case err := <-vr.Errors():
+		t.Errorf("unexpected error %v", err)
+	default:
+	}
+}
+
+func TestNewVirtualRouterWithConn(t *testing.T) {
+	network := &memNetwork{}
+	src := net.ParseIP("192.168.0.10")
+	for _, tc := range []struct {
+		name     string
+		conn     VRRPMsgConnection
+		VRID     byte
+		src      net.IP
+		priority byte
+		family   byte
+	}{
+		{"nil conn", nil, 240, src, 100, IPv4},
+		{"zero VRID", network.dial(src.To4()), 0, src, 100, IPv4},
+		{"zero priority", network.dial(src.To4()), 240, src, 0, IPv4},
+		{"bad family", network.dial(src.To4()), 240, src, 100, 5},
+		{"IPv6 source for IPv4", network.dial(src.To4()), 240, net.ParseIP("fe80::10"), 100, IPv4},
+		{"IPv4 source for IPv6", network.dial(src.To4()), 240, src, 100, IPv6},
+	} {
+		if _, err := NewVirtualRouterWithConn(tc.VRID, tc.conn, nil, tc.src, tc.priority, tc.family); err == nil {
+			t.Errorf("%s: expect error", tc.name)
+		}
+	}
+
+	network = &memNetwork{}
+	conn := network.dial(src.To4())
+	announcer := &fakeAnnouncer{}
+	vr, err := NewVirtualRouterWithConn(240, conn, announcer, src, 255, IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface := vr.GetInterface(); iface.Name != "mem0" || iface.Index != 1 {
+		t.Errorf("unexpected interface %+v", iface)
+	}
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+	vr.AddIPvXAddr(net.IPv4(192, 168, 0, 100))
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("owner should become master")
+	}
+	received, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.GetVirtualRouterID() != 240 || received.GetPriority() != 255 {
+		t.Errorf("unexpected advertisement %v", received)
+	}
+	announcer.mu.Lock()
+	defer announcer.mu.Unlock()
+	if announcer.count == 0 {
+		t.Error("provided announcer should be used")
+	}
+}
+
+// basicConn 仅实现 VRRPMsgConnection 的连接，不提供 ConnectionInfo 等可选方法
+type basicConn struct {
+	VRRPMsgConnection
+}
+
+func TestNewVirtualRouterWithConn_WithoutConnectionInfo(t *testing.T) {
+	network := &memNetwork{}
+	src := net.ParseIP("192.168.0.10").To4()
+	vr, err := NewVirtualRouterWithConn(240, basicConn{network.dial(src)}, nil, src, 255, IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := vr.GetConnectionInfo(); info.InterfaceName != "" || info.Group != nil {
+		t.Errorf("expect zero connection info, got %+v", info)
+	}
+	peer := network.dial(net.ParseIP("192.168.0.20").To4())
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("owner should become master")
+	}
+	// 未提供组播地址时按协议类型的默认组播地址计算校验和
+	received, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !received.ValidateCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv4, Protocol: VRRPIPProtocolNumber, Len: uint16(received.PacketSize())}) {
+		t.Error("advertisement checksum should use the default group")
+	}
+}
+
+func TestVirtualRouter_PreemptDelayRemaining(t *testing.T) {
+	network := &memNetwork{}
+	low, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	high, _ := newTestRouter(t, network, 240, "192.168.0.20", 200)
+	high.SetPreemptDelay(15 * testInterval)
+	if remaining := high.PreemptDelayRemaining(); remaining != 0 {
+		t.Fatalf("expect no preempt delay before start, got %v", remaining)
+	}
+
+	startRouter(t, low)
+	if !waitState(low, MASTER, time.Second) {
+		t.Fatal("the low priority router should become master")
+	}
+	startRouter(t, high)
+
+	time.Sleep(3 * testInterval)
+	first := high.PreemptDelayRemaining()
+	if first <= 0 || high.GetState() != BACKUP {
+		t.Fatalf("expect preempt delay in progress, got remaining=%v state=%d", first, high.GetState())
+	}
+	time.Sleep(3 * testInterval)
+	second := high.PreemptDelayRemaining()
+	if second <= 0 || second >= first {
+		t.Errorf("expect remaining to decrease, got %v then %v", first, second)
+	}
+
+	if !waitState(high, MASTER, time.Second) {
+		t.Fatal("the high priority router should preempt after the delay")
+	}
+	if remaining := high.PreemptDelayRemaining(); remaining != 0 {
+		t.Errorf("expect no remaining delay after preempting, got %v", remaining)
+	}
+}
+
+func TestVirtualRouter_LastElectionReason(t *testing.T) {
+	for _, tc := range []struct {
+		priority byte
+		expect   string
+	}{
+		{priority: 100, expect: ElectionReasonIPTiebreak},
+		{priority: 200, expect: ElectionReasonPriority},
+	} {
+		network := &memNetwork{}
+		first, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+		second, _ := newTestRouter(t, network, 240, "192.168.0.20", tc.priority)
+		if reason := second.LastElectionReason(); reason != "" {
+			t.Fatalf("expect no election reason before start, got %q", reason)
+		}
+
+		startRouter(t, first)
+		if !waitState(first, MASTER, time.Second) {
+			t.Fatal("the first router should become master")
+		}
+		if reason := first.LastElectionReason(); reason != ElectionReasonMasterDown {
+			t.Errorf("expect %q for the first router, got %q", ElectionReasonMasterDown, reason)
+		}
+		startRouter(t, second)
+		if !waitState(second, MASTER, time.Second) {
+			t.Fatal("the second router should preempt")
+		}
+		if reason := second.LastElectionReason(); reason != tc.expect {
+			t.Errorf("priority %d: expect %q, got %q", tc.priority, tc.expect, reason)
+		}
+	}
+}
+
+func TestVirtualRouter_StopAwaitsMaster2InitHandler(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 255)
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	vr.SetSentObserver(func(pkt *VRRPPacket) {
+		if pkt.GetPriority() == 0 {
+			record("yield")
+		}
+	})
+	vr.AddEventListener(Master2Init, func(*VirtualRouter) {
+		time.Sleep(5 * testInterval)
+		select {
+		case <-conn.done:
+			record("closed during handler")
+		default:
+		}
+		record("handler done")
+	})
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("owner should become master")
+	}
+
+	vr.Stop()
+	select {
+	case <-conn.done:
+	default:
+		t.Fatal("expect connection closed when Stop returns")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 || events[0] != "yield" || events[1] != "handler done" {
+		t.Errorf("expect yield sent before the handler completes and the connection closes, got %v", events)
+	}
+}
+
+func TestVirtualRouter_SetMinMasterDwell(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	dwell := 20 * testInterval
+	vr.SetMinMasterDwell(dwell)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("the router should become master")
+	}
+	since := time.Now()
+
+	// 优先级相同、源IP地址较大的对端心跳消息，在保持时间内不让渡
+	for time.Since(since) < dwell/2 {
+		conn.deliver(newAdvertisement(240, 100, "192.168.0.20"), nil)
+		time.Sleep(testInterval)
+		if vr.GetState() != MASTER {
+			t.Fatalf("expect no yield within the dwell time, yielded after %v", time.Since(since))
+		}
+	}
+	if reason := vr.LastElectionReason(); reason != ElectionReasonDwell {
+		t.Errorf("expect election reason %q, got %q", ElectionReasonDwell, reason)
+	}
+
+	// 保持时间结束后让渡
+	deadline := time.Now().Add(2 * dwell)
+	for vr.GetState() == MASTER && time.Now().Before(deadline) {
+		conn.deliver(newAdvertisement(240, 100, "192.168.0.20"), nil)
+		time.Sleep(testInterval)
+	}
+	if vr.GetState() != BACKUP {
+		t.Fatal("expect yield after the dwell time elapsed")
+	}
+	if elapsed := time.Since(since); elapsed < dwell-testInterval {
+		t.Errorf("expect yield after %v, got %v", dwell, elapsed)
+	}
+}
+
+func TestVirtualRouter_MinMasterDwellHigherPriority(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.SetMinMasterDwell(time.Minute)
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("the router should become master")
+	}
+	conn.deliver(newAdvertisement(240, 101, "192.168.0.1"), nil)
+	if !waitState(vr, BACKUP, time.Second) {
+		t.Fatal("expect higher priority to preempt within the dwell time")
+	}
+}
+
+func TestVirtualRouter_GetAcceptMode(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.SetAcceptMode(true)
+	if vr.GetAcceptMode() {
+		t.Error("accept mode should be false before becoming master")
+	}
+	accepted := make(chan bool, 1)
+	vr.AddEventListener(Backup2Master, func(ctx *VirtualRouter) { accepted <- ctx.GetAcceptMode() })
+	startRouter(t, vr)
+	select {
+	case accept := <-accepted:
+		if !accept {
+			t.Error("expect accept mode true in MASTER state")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("router should become master")
+	}
+
+	owner, _ := newTestRouter(t, nil, 241, "192.168.0.10", 255)
+	startRouterWithInterval(t, owner, 5*time.Second)
+	if !waitState(owner, MASTER, time.Second) {
+		t.Fatal("owner should become master")
+	}
+	if !owner.GetAcceptMode() {
+		t.Error("owner should always accept")
+	}
+}
+
+func TestVirtualRouter_ConcurrentVIPs(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.AddIPvXAddr(net.ParseIP("192.168.0.100"))
+	startRouter(t, vr)
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ip := net.IPv4(10, 0, byte(i), byte(j))
+				vr.AddIPvXAddr(ip)
+				_ = vr.GetVIPs()
+				_ = vr.addrAnnouncer.AnnounceAll(vr)
+				vr.RemoveIPvXAddr(ip)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	time.Sleep(2 * testInterval)
+	if vips := vr.GetVIPs(); len(vips) != 1 || !vips[0].Equal(net.ParseIP("192.168.0.100")) {
+		t.Errorf("expect only the initial VIP left, got %v", vips)
+	}
+	if len(conn.sentPackets()) == 0 {
+		t.Error("router should keep advertising while VIPs change")
+	}
+}
+
+func TestVirtualRouter_StartContext(t *testing.T) {
+	vr, conn := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	vr.SetAdvInterval(testInterval)
+	vr.SetPriorityAndMasterAdvInterval(100, testInterval)
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- vr.StartContext(ctx) }()
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+
+	cancel()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expect context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartContext should return after cancellation")
+	}
+	if vr.GetState() != INIT {
+		t.Errorf("expect INIT state, got %s", StateName(vr.GetState()))
+	}
+	sent := conn.sentPackets()
+	if len(sent) == 0 || sent[len(sent)-1].GetPriority() != 0 {
+		t.Error("master should send a priority 0 advertisement on cancellation")
+	}
+	select {
+	case <-vr.closed:
+	default:
+		t.Error("resources should be closed on cancellation")
+	}
+
+	// 通过 Stop 正常停止时返回 nil
+	go func() { result <- vr.StartContext(context.Background()) }()
+	if !waitState(vr, MASTER, time.Second) {
+		t.Fatal("router should restart")
+	}
+	vr.Stop()
+	if err := <-result; err != nil {
+		t.Errorf("expect nil on Stop, got %v", err)
+	}
+
+	if err := vr.StartContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expect context.Canceled for a done context, got %v", err)
+	}
+}
+
+func TestVirtualRouter_StopLeavesAnnounceToBackup(t *testing.T) {
+	network := &memNetwork{}
+	master, _ := newTestRouter(t, network, 240, "192.168.0.20", 200)
+	backup, _ := newTestRouter(t, network, 240, "192.168.0.10", 100)
+	masterAnnouncer := master.addrAnnouncer.(*fakeAnnouncer)
+	backupAnnouncer := backup.addrAnnouncer.(*fakeAnnouncer)
+	count := func(a *fakeAnnouncer) int {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.count
+	}
+
+	startRouter(t, master)
+	if !waitState(master, MASTER, time.Second) {
+		t.Fatal("router should become master")
+	}
+	startRouter(t, backup)
+	if !waitState(backup, BACKUP, time.Second) {
+		t.Fatal("router should stay backup")
+	}
+	time.Sleep(3 * testInterval)
+	announced := count(masterAnnouncer)
+
+	master.Stop()
+	if !waitState(backup, MASTER, time.Second) {
+		t.Fatal("backup should take over after the master stops")
+	}
+	if n := count(backupAnnouncer); n != 1 {
+		t.Errorf("expect the promoted backup to announce once, got %d", n)
+	}
+	time.Sleep(3 * testInterval)
+	if n := count(masterAnnouncer); n != announced {
+		t.Errorf("expect no announcement from the departing master, got %d more", n-announced)
+	}
+}
+
+func TestVirtualRouter_StopBeforeStart(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+		vr.SetAdvInterval(testInterval)
+		vr.SetPriorityAndMasterAdvInterval(100, testInterval)
+		// Stop 先于 Start 执行时，Start 应处理停止事件后返回而非停留在 INIT 状态
+		vr.Stop()
+		done := make(chan error, 1)
+		go func() { done <- vr.Start() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Start should return when Stop was called before it")
+		}
+		if vr.GetState() != INIT {
+			t.Errorf("expect INIT, got %s", StateName(vr.GetState()))
+		}
+	}
+
+	// Stop 与 Start 并发执行时同样不应阻塞
+	for i := 0; i < 20; i++ {
+		vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+		done := make(chan error, 1)
+		go func() { done <- vr.Start() }()
+		vr.Stop()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Start should return when Stop races with it")
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package govrrp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrBindToDeviceUnsupported 当前平台或连接不支持将套接字绑定至网络设备
+var ErrBindToDeviceUnsupported = errors.New("binding socket to device is not supported")
+
+// SetVRF 设置 VRRP消息收发套接字所在的 VRF（Linux），
+// 通过 SO_BINDTODEVICE 将套接字绑定至 VRF 设备，使VRRP消息在该 VRF 的路由域中收发。
+// name: VRF 设备名称，设备不存在时返回错误。需在 Start 前调用，仅支持 Linux 平台。
+func (r *VirtualRouter) SetVRF(name string) error {
+	if _, err := net.InterfaceByName(name); err != nil {
+		return fmt.Errorf("VRID [%d] VRF device %s: %w", r.vrID, name, err)
+	}
+	c, ok := r.vrrpConn.(interface{ BindToDevice(string) error })
+	if !ok {
+		return fmt.Errorf("VRID [%d] %w", r.vrID, ErrBindToDeviceUnsupported)
+	}
+	if err := c.BindToDevice(name); err != nil {
+		return fmt.Errorf("VRID [%d] bind to VRF device %s: %w", r.vrID, name, err)
+	}
+	r.connOpts.vrf = name
+	r.logger().Printf("VRID [%d] socket bound to VRF %s", r.vrID, name)
+	return nil
+}
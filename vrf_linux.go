@@ -0,0 +1,19 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"syscall"
+)
+
+// bindToDevice 通过 SO_BINDTODEVICE 将套接字绑定至指定网络设备
+func bindToDevice(raw syscall.RawConn, name string) error {
+	var opErr error
+	err := raw.Control(func(fd uintptr) {
+		opErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, name)
+	})
+	if err != nil {
+		return err
+	}
+	return opErr
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package govrrp
+
+import (
+	"syscall"
+)
+
+// bindToDevice 当前平台不支持将套接字绑定至网络设备
+func bindToDevice(syscall.RawConn, string) error {
+	return ErrBindToDeviceUnsupported
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package govrrp
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestBindToDevice(t *testing.T) {
+	ift, err := net.InterfaceByIndex(1)
+	if err != nil {
+		t.Skipf("no loopback interface: %v", err)
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("listen udp: %v", err)
+	}
+	defer conn.Close()
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = bindToDevice(raw, ift.Name); err != nil {
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOPROTOOPT) {
+			t.Skipf("SO_BINDTODEVICE unsupported: %v", err)
+		}
+		t.Fatal(err)
+	}
+	var device string
+	_ = raw.Control(func(fd uintptr) {
+		device, err = unix.GetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device != ift.Name {
+		t.Errorf("expect socket bound to %s, got %q", ift.Name, device)
+	}
+}
+
+func TestVirtualRouter_SetVRF(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	if err := vr.SetVRF("govrrp-no-such-vrf"); err == nil {
+		t.Error("expect error for missing VRF device")
+	}
+	ift, err := net.InterfaceByIndex(1)
+	if err != nil {
+		t.Skipf("no loopback interface: %v", err)
+	}
+	if err = vr.SetVRF(ift.Name); !errors.Is(err, ErrBindToDeviceUnsupported) {
+		t.Errorf("expect ErrBindToDeviceUnsupported on in-memory connection, got %v", err)
+	}
+}
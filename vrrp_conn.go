@@ -1,67 +1,412 @@
 package govrrp
 
 import (
+	"errors"
 	"fmt"
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
 	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// ErrForeignInterface 收到的VRRP消息并非来自工作网口
+var ErrForeignInterface = errors.New("advertisement received on unexpected interface")
+
+// ErrForeignGroup 收到的VRRP消息的目的地址不是连接的组播组，见 NewIPv4VRRPMsgConnWildcard
+var ErrForeignGroup = errors.New("advertisement sent to unexpected destination")
+
+// ErrTTLUnavailable 接收数据包时控制消息中没有TTL数据，无法校验TTL
+var ErrTTLUnavailable = errors.New("TTL control data unavailable")
+
+// ErrNoPseudoHeader 连接返回的报文未设置伪首部，无法获取发送方地址
+var ErrNoPseudoHeader = errors.New("advertisement without pseudo header")
+
+// MissingTTLPolicy 接收 IPv4 数据包时控制消息中没有TTL数据的处理策略
+type MissingTTLPolicy int32
+
+const (
+	MissingTTLReject MissingTTLPolicy = iota // 丢弃（默认），与TTL不为255的数据包相同
+	MissingTTLAccept                         // 接受并记录警告日志，用于控制消息不可用的虚拟化回环测试环境
+)
+
+// 原始报文的校验结果
+const (
+	TapAccepted                  = "accepted"                    // 通过校验，交由状态机处理
+	TapDroppedTTL                = "dropped-ttl"                 // TTL/Hop Limit 不为 255
+	TapDroppedInterface          = "dropped-interface"           // 非工作网口收到，见 SetRejectForeignInterface
+	TapDroppedGroup              = "dropped-group"               // 目的地址不是组播组，见 NewIPv4VRRPMsgConnWildcard
+	TapDroppedMalformed          = "dropped-malformed"           // 报文格式错误
+	TapDroppedFamily             = "dropped-family"              // 源地址或地址序列与协议类型不一致，见 SetStrictFamily
+	TapDroppedTruncated          = "dropped-truncated"           // 报文长度小于声明的地址数量所需的长度
+	TapDroppedVersion            = "dropped-version"             // VRRP版本不匹配
+	TapDroppedChecksum           = "dropped-checksum"            // 校验和错误
+	TapDroppedVRID               = "dropped-vrid"                // 虚拟路由ID不匹配
+	TapDroppedZeroAddr           = "dropped-zero-addr"           // 未携带虚拟IP地址，见 SetZeroAddrPolicy
+	TapDroppedSuspiciousPriority = "dropped-suspicious-priority" // 非已知地址拥有者宣告优先级 255，见 SetRejectSuspiciousPriority
+	TapDroppedNoPseudoHeader     = "dropped-no-pseudo-header"    // 自定义连接返回的报文未设置伪首部，见 NewVirtualRouterWithConn
+)
+
+// malformedReason 报文解析失败的校验结果，区分截断的报文与其他格式错误
+func malformedReason(err error) string {
+	if errors.Is(err, ErrTruncatedAdvert) {
+		return TapDroppedTruncated
+	}
+	return TapDroppedMalformed
+}
+
+// RawTap 原始报文监听函数，在状态机处理之前接收每一个收到的报文及其校验结果
+// pkt: 解析后的报文，报文无法解析或在解析前被丢弃时为 nil
+// src: 报文的源地址
+// result: 校验结果，取值见 TapAccepted 等常量
+type RawTap func(pkt *VRRPPacket, src net.IP, result string)
+
+// rawTapHolder 可并发设置的原始报文监听函数
+type rawTapHolder struct {
+	fn atomic.Pointer[RawTap]
+}
+
+func (h *rawTapHolder) set(tap RawTap) {
+	if tap == nil {
+		h.fn.Store(nil)
+		return
+	}
+	h.fn.Store(&tap)
+}
+
+// report 向原始报文监听函数报告校验结果
+func (h *rawTapHolder) report(pkt *VRRPPacket, src net.IP, result string) {
+	if fn := h.fn.Load(); fn != nil {
+		(*fn)(pkt, src, result)
+	}
+}
+
 // NetErr 网络异常
 type NetErr struct {
 	error
 }
 
+// ConnectionInfo VRRP连接的绑定信息，用于诊断连接是否工作在预期的网口与地址上
+type ConnectionInfo struct {
+	LocalAddr      net.Addr // 套接字绑定的本地地址
+	SourceIP       net.IP   // 发送IP数据包的源地址
+	Group          net.IP   // 加入的组播地址
+	InterfaceName  string   // 工作网口名称
+	InterfaceIndex int      // 工作网口索引
+	Loopback       bool     // 是否开启了组播回环
+}
+
+// groupRejoiner 周期性地重新加入组播组
+//
+// 当网络中的IGMP/MLD查询器变更或交换机重新学习组成员关系时，组播成员关系可能被静默丢弃，
+// 周期性地重新加入组播组可以保持成员关系有效。
+type groupRejoiner struct {
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// reset 按照新的时间间隔重启重新加入任务，interval 小于等于 0 时停止任务
+func (g *groupRejoiner) reset(interval time.Duration, rejoin func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stop != nil {
+		close(g.stop)
+		g.stop = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	g.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rejoin()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// joinGroupRetries 加入组播组失败后的重试次数
+const joinGroupRetries = 3
+
+// joinGroupBackoff 加入组播组失败后首次重试前的等待时间，之后每次重试等待时间递增，便于测试替换
+var joinGroupBackoff = 50 * time.Millisecond
+
+// joinGroupWithRetry 加入组播组，失败时以递增的间隔重试。
+// 部分内核上残留的组播成员关系会导致加入失败（EADDRINUSE），仅在该情况下先离开组播组再重试。
+func joinGroupWithRetry(itf *net.Interface, group net.Addr, join, leave func(*net.Interface, net.Addr) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = join(itf, group); err == nil {
+			return nil
+		}
+		if attempt == joinGroupRetries {
+			return fmt.Errorf("join %s failed after %d attempts: %w", group, attempt+1, err)
+		}
+		if errors.Is(err, syscall.EADDRINUSE) {
+			// 已存在组播成员关系，离开后重新加入
+			_ = leave(itf, group)
+		}
+		time.Sleep(joinGroupBackoff * time.Duration(attempt+1))
+	}
+}
+
+// ipv4PacketConn IPv4 组播连接所需的 ipv4.PacketConn 方法集合
+type ipv4PacketConn interface {
+	ReadFrom(b []byte) (int, *ipv4.ControlMessage, net.Addr, error)
+	WriteTo(b []byte, cm *ipv4.ControlMessage, dst net.Addr) (int, error)
+	JoinGroup(ifi *net.Interface, group net.Addr) error
+	LeaveGroup(ifi *net.Interface, group net.Addr) error
+	SetMulticastLoopback(on bool) error
+	SetMulticastTTL(ttl int) error
+	MulticastTTL() (int, error)
+	SetMulticastInterface(ifi *net.Interface) error
+	SetControlMessage(cf ipv4.ControlFlags, on bool) error
+	LocalAddr() net.Addr
+	Close() error
+}
+
 // NewIPv4VRRPMsgConn 创建的IPv4 VRRP虚拟连接
 // ift: 工作网口
 // src: IP数据包中源地址，应该为工作网口的IP地址
 // dst: IP数据包中目的地址，应该为组播地址 VRRPMultiAddrIPv4
 func NewIPv4VRRPMsgConn(itf *net.Interface, src, dst net.IP) (VRRPMsgConnection, error) {
-	multiAddr := &net.IPAddr{IP: dst}
+	return listenIPv4VRRPMsgConn(itf, src, dst, newIPv4VRRPMsgConn)
+}
 
-	conn, err := net.ListenIP("ip4:112", &net.IPAddr{IP: net.IPv4(0, 0, 0, 0)})
+// NewIPv4VRRPMsgConnWildcard 创建IPv4 VRRP消息连接的兼容模式，用于容器等无法加入指定组播组、但允许监听原始套接字的受限环境。
+// 加入组播组失败时不返回错误，而是广泛监听所有VRRP数据包，并根据控制消息中的目的地址在用户空间过滤，
+// 仅接收目的地址为 dst 的数据包，其余数据包以 TapDroppedGroup 丢弃。可通过 NewVirtualRouterWithConn 创建虚拟路由器。
+func NewIPv4VRRPMsgConnWildcard(itf *net.Interface, src, dst net.IP) (VRRPMsgConnection, error) {
+	return listenIPv4VRRPMsgConn(itf, src, dst, newIPv4VRRPMsgConnWildcard)
+}
+
+// listenIPv4VRRPMsgConn 监听IPv4 VRRP协议数据包，并由 newConn 完成连接配置
+func listenIPv4VRRPMsgConn(itf *net.Interface, src, dst net.IP,
+	newConn func(*net.Interface, net.IP, net.IP, ipv4PacketConn) (*IPv4VRRPMsgCon, error)) (VRRPMsgConnection, error) {
+	conn, err := net.ListenIP(VRRPListenNetworkIPv4, &net.IPAddr{IP: net.IPv4(0, 0, 0, 0)})
 	if err != nil {
 		return nil, fmt.Errorf("NewIPv4VRRPMsgConn interface %s ip packet listen err, %v", itf.Name, err)
 	}
+	_ = conn.SetReadBuffer(2048)
+	_ = conn.SetWriteBuffer(2048)
 
-	pc := ipv4.NewPacketConn(conn)
-	_ = pc.LeaveGroup(itf, multiAddr)
-	if err = pc.JoinGroup(itf, multiAddr); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("NewIPv4VRRPMsgConn interface %s join multicast group err, %v", itf.Name, err)
+	c, err := newConn(itf, src, dst, ipv4.NewPacketConn(conn))
+	if err != nil {
+		return nil, err
 	}
+	c.raw, _ = conn.SyscallConn()
+	return c, nil
+}
+
+// newIPv4VRRPMsgConn 在已有的数据包连接上加入组播并完成连接配置
+func newIPv4VRRPMsgConn(itf *net.Interface, src, dst net.IP, pc ipv4PacketConn) (*IPv4VRRPMsgCon, error) {
+	multiAddr := &net.IPAddr{IP: dst}
+	if err := joinGroupWithRetry(itf, multiAddr, pc.JoinGroup, pc.LeaveGroup); err != nil {
+		_ = pc.Close()
+		return nil, fmt.Errorf("NewIPv4VRRPMsgConn interface %s join multicast group err, %w", itf.Name, err)
+	}
+	return configureIPv4VRRPMsgConn(itf, src, multiAddr, pc), nil
+}
+
+// newIPv4VRRPMsgConnWildcard 在已有的数据包连接上尝试加入组播，失败时仅记录警告，并开启按目的地址过滤
+func newIPv4VRRPMsgConnWildcard(itf *net.Interface, src, dst net.IP, pc ipv4PacketConn) (*IPv4VRRPMsgCon, error) {
+	multiAddr := &net.IPAddr{IP: dst}
+	if err := pc.JoinGroup(itf, multiAddr); err != nil {
+		logg().Printf("WARN NewIPv4VRRPMsgConnWildcard interface %s join multicast group %s: %v, filter by destination instead", itf.Name, dst, err)
+	}
+	conn := configureIPv4VRRPMsgConn(itf, src, multiAddr, pc)
+	conn.groupFilter = true
+	return conn, nil
+}
+
+// configureIPv4VRRPMsgConn 完成连接的组播发送与控制消息配置
+func configureIPv4VRRPMsgConn(itf *net.Interface, src net.IP, multiAddr *net.IPAddr, pc ipv4PacketConn) *IPv4VRRPMsgCon {
 	// 设置组播回环
-	_ = pc.SetMulticastLoopback(true)
+	loopback := pc.SetMulticastLoopback(true) == nil
 	// 设置消息的TTL为255
 	_ = pc.SetMulticastTTL(255)
 	_ = pc.SetMulticastInterface(itf)
-	_ = pc.SetControlMessage(ipv4.FlagTTL|ipv4.FlagSrc|ipv4.FlagDst|ipv4.FlagInterface, true)
-
-	_ = conn.SetReadBuffer(2048)
-	_ = conn.SetWriteBuffer(2048)
+	_ = pc.SetControlMessage(DefaultIPv4ControlFlags, true)
 
 	return &IPv4VRRPMsgCon{
-		itf:    itf,
-		local:  src,
-		remote: multiAddr,
-		pc:     pc,
-		buffer: make([]byte, 2048),
-	}, nil
+		itf:      itf,
+		local:    src,
+		remote:   multiAddr,
+		pc:       pc,
+		loopback: loopback,
+		flags:    DefaultIPv4ControlFlags,
+		buffer:   make([]byte, 2048),
+	}
+}
+
+// IPv4 接收数据包时获取的控制消息
+const (
+	// RequiredIPv4ControlFlags 校验数据包必需的控制消息：TTL 校验（RFC 5798 5.1.1.3）以及伪首部中的源地址与目的地址
+	RequiredIPv4ControlFlags = ipv4.FlagTTL | ipv4.FlagSrc | ipv4.FlagDst
+	// DefaultIPv4ControlFlags 默认的控制消息，额外获取接收网口用于 SetStrictInterface
+	DefaultIPv4ControlFlags = RequiredIPv4ControlFlags | ipv4.FlagInterface
+)
+
+// SetControlFlags 设置 接收数据包时获取的控制消息，默认为 DefaultIPv4ControlFlags。
+// 可去掉不需要的控制消息以降低每个数据包的开销，或增加控制消息用于诊断。
+// 不包含 RequiredIPv4ControlFlags 时返回错误；开启 SetStrictInterface 时必须包含 ipv4.FlagInterface。
+// 需在开始收发消息前调用。
+func (conn *IPv4VRRPMsgCon) SetControlFlags(flags ipv4.ControlFlags) error {
+	if flags&RequiredIPv4ControlFlags != RequiredIPv4ControlFlags {
+		return fmt.Errorf("IPv4VRRPMsgCon.SetControlFlags: flags %v missing required %v", flags, RequiredIPv4ControlFlags&^flags)
+	}
+	if conn.strict.Load() && flags&ipv4.FlagInterface == 0 {
+		return fmt.Errorf("IPv4VRRPMsgCon.SetControlFlags: interface flag is required by strict interface mode")
+	}
+	if removed := conn.flags &^ flags; removed != 0 {
+		if err := conn.pc.SetControlMessage(removed, false); err != nil {
+			return fmt.Errorf("IPv4VRRPMsgCon.SetControlFlags: %v", err)
+		}
+	}
+	if err := conn.pc.SetControlMessage(flags, true); err != nil {
+		return fmt.Errorf("IPv4VRRPMsgCon.SetControlFlags: %v", err)
+	}
+	conn.flags = flags
+	return nil
 }
 
 // IPv4VRRPMsgCon IPv4的VRRP消息组播连接
 type IPv4VRRPMsgCon struct {
-	itf    *net.Interface   // 工作网口
-	local  net.IP           // 发送IP数据包的源地址
-	remote *net.IPAddr      // 发送IP数据包的目的地址
-	pc     *ipv4.PacketConn // VRRP数据包 发送连接
-	buffer []byte           // 接收数据包的缓冲区
+	itf      *net.Interface    // 工作网口
+	local    net.IP            // 发送IP数据包的源地址
+	remote   *net.IPAddr       // 发送IP数据包的目的地址
+	pc       ipv4PacketConn    // VRRP数据包 发送连接
+	raw      syscall.RawConn   // 底层套接字，用于设置套接字选项
+	loopback bool              // 是否开启了组播回环
+	flags    ipv4.ControlFlags // 接收数据包时获取的控制消息
+	strict   atomic.Bool       // 是否丢弃非工作网口收到的数据包
+	version  atomic.Uint32     // 接受的VRRP协议版本，0 表示 VRRPv3
+	tap      rawTapHolder      // 原始报文监听函数
+	buffer   []byte            // 接收数据包的缓冲区
+	rejoiner groupRejoiner     // 组播组周期性重新加入任务
+
+	groupFilter bool // 是否丢弃目的地址不是组播组的数据包，见 NewIPv4VRRPMsgConnWildcard
+
+	missingTTL atomic.Int32 // 控制消息中没有TTL数据时的处理策略，见 MissingTTLPolicy
+	ttlWarned  atomic.Bool  // 是否已记录缺少TTL数据的警告日志
+
+	closeOnce sync.Once // 确保连接仅关闭一次
+	closeErr  error     // 关闭连接的结果
+}
+
+// SetMissingTTLPolicy 设置 控制消息中没有TTL数据时的处理策略，默认为 MissingTTLReject
+func (conn *IPv4VRRPMsgCon) SetMissingTTLPolicy(policy MissingTTLPolicy) {
+	conn.missingTTL.Store(int32(policy))
+}
+
+// SetRawTap 设置 原始报文监听函数，连接校验未通过的报文均会连同原因报告给该函数
+func (conn *IPv4VRRPMsgCon) SetRawTap(tap RawTap) {
+	conn.tap.set(tap)
+}
+
+// SetVersion 设置 接受的VRRP协议版本，其他版本的数据包将被丢弃，默认为 VRRPv3。
+// IPv4 支持 VRRPv2 与 VRRPv3。
+func (conn *IPv4VRRPMsgCon) SetVersion(version VRRPVersion) error {
+	if version != VRRPv2 && version != VRRPv3 {
+		return fmt.Errorf("IPv4VRRPMsgCon.SetVersion: unsupported %s", version)
+	}
+	conn.version.Store(uint32(version))
+	return nil
+}
+
+// acceptVersion 获取 接受的VRRP协议版本
+func (conn *IPv4VRRPMsgCon) acceptVersion() VRRPVersion {
+	if v := conn.version.Load(); v != 0 {
+		return VRRPVersion(v)
+	}
+	return VRRPv3
+}
+
+// SetStrictInterface 设置 是否丢弃非工作网口收到的数据包，默认关闭
+func (conn *IPv4VRRPMsgCon) SetStrictInterface(flag bool) {
+	conn.strict.Store(flag)
+}
+
+// SetRejoinInterval 设置 周期性重新加入组播组的时间间隔，小于等于 0 表示关闭（默认关闭）
+func (conn *IPv4VRRPMsgCon) SetRejoinInterval(interval time.Duration) {
+	conn.rejoiner.reset(interval, func() {
+		_ = conn.pc.LeaveGroup(conn.itf, conn.remote)
+		if err := conn.pc.JoinGroup(conn.itf, conn.remote); err != nil {
+			logg().Printf("ERROR IPv4VRRPMsgCon rejoin multicast group %s: %v", conn.remote, err)
+		}
+	})
+}
+
+// ConnectionInfo 获取 连接的绑定信息
+func (conn *IPv4VRRPMsgCon) ConnectionInfo() ConnectionInfo {
+	return connectionInfo(conn.itf, conn.pc.LocalAddr(), conn.local, conn.remote.IP, conn.loopback)
+}
+
+// BindToDevice 将连接的套接字绑定至指定网络设备（如 Linux VRF 设备）
+func (conn *IPv4VRRPMsgCon) BindToDevice(name string) error {
+	if conn.raw == nil {
+		return fmt.Errorf("IPv4VRRPMsgCon.BindToDevice: %w", ErrBindToDeviceUnsupported)
+	}
+	return bindToDevice(conn.raw, name)
+}
+
+// SetMulticastTTL 设置 发送组播消息使用的TTL，取值范围 1~255
+func (conn *IPv4VRRPMsgCon) SetMulticastTTL(ttl int) error {
+	if ttl < 1 || ttl > 255 {
+		return fmt.Errorf("IPv4VRRPMsgCon.SetMulticastTTL: invalid TTL %d", ttl)
+	}
+	return conn.pc.SetMulticastTTL(ttl)
+}
+
+// MulticastTTL 获取 套接字当前发送组播消息使用的TTL
+func (conn *IPv4VRRPMsgCon) MulticastTTL() (int, error) {
+	return conn.pc.MulticastTTL()
+}
+
+// SetSocketMark 设置 连接套接字的标记（Linux SO_MARK），其他平台为空操作
+func (conn *IPv4VRRPMsgCon) SetSocketMark(mark uint32) error {
+	if conn.raw == nil {
+		return fmt.Errorf("IPv4VRRPMsgCon.SetSocketMark: %w", ErrSocketMarkUnsupported)
+	}
+	return setSocketMark(conn.raw, mark)
+}
+
+// SetGroup 设置 发送与接收VRRP消息使用的组播组，加入新的组播组后离开原组播组
+// 需在开始收发消息前调用。
+func (conn *IPv4VRRPMsgCon) SetGroup(group net.IP) error {
+	if group.To4() == nil || !group.IsMulticast() {
+		return fmt.Errorf("IPv4VRRPMsgCon.SetGroup: %v is not an IPv4 multicast address", group)
+	}
+	if group.Equal(conn.remote.IP) {
+		return nil
+	}
+	multiAddr := &net.IPAddr{IP: group.To4()}
+	if err := conn.pc.JoinGroup(conn.itf, multiAddr); err != nil {
+		if !conn.groupFilter {
+			return fmt.Errorf("IPv4VRRPMsgCon.SetGroup: join multicast group %s err, %v", group, err)
+		}
+		// 兼容模式下按目的地址过滤，无需加入组播组
+		logg().Printf("WARN IPv4VRRPMsgCon.SetGroup: join multicast group %s: %v, filter by destination instead", group, err)
+	}
+	_ = conn.pc.LeaveGroup(conn.itf, conn.remote)
+	conn.remote = multiAddr
+	return nil
 }
 
 // WriteMessage 发送VRRP数据包
 func (conn *IPv4VRRPMsgCon) WriteMessage(packet *VRRPPacket) error {
-	//cm := &ipv4.ControlMessage{TTL: 255, Src: conn.local, IfIndex: conn.itf.Index}
-	if _, err := conn.pc.WriteTo(packet.ToBytes(), nil, conn.remote); err != nil {
+	// 指定发送网口与源地址，避免多网口主机上内核按路由选择其他网口发出心跳消息
+	cm := &ipv4.ControlMessage{Src: conn.local, IfIndex: conn.itf.Index}
+	if _, err := conn.pc.WriteTo(packet.ToBytes(), cm, conn.remote); err != nil {
 		return NetErr{fmt.Errorf("IPv4VRRPMsgCon.WriteMessage: %v", err)}
 	}
 	return nil
@@ -70,87 +415,270 @@ func (conn *IPv4VRRPMsgCon) WriteMessage(packet *VRRPPacket) error {
 // ReadMessage 读取VRRP数据包
 func (conn *IPv4VRRPMsgCon) ReadMessage() (*VRRPPacket, error) {
 	// 此处读取到的数据为 IP数据包
-	var n, cm, _, err = conn.pc.ReadFrom(conn.buffer)
+	var n, cm, src, err = conn.pc.ReadFrom(conn.buffer)
 	if err != nil {
 		return nil, NetErr{fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %v", err)}
 	}
+	if cm == nil {
+		// 协议栈未返回控制消息，源地址取自数据包的来源，目的地址视为组播地址
+		cm = &ipv4.ControlMessage{Dst: conn.remote.IP}
+		if addr, ok := src.(*net.IPAddr); ok {
+			cm.Src = addr.IP
+		}
+	}
 	// 检查 TTL 应该为 255 (see RFC5798 5.1.1.3. TTL)
-	if cm.TTL != 255 {
+	if cm.TTL == 0 {
+		// TTL 为 0 的数据包不会投递至本机，说明控制消息中没有TTL数据（如部分虚拟化回环环境），区别于TTL不为255的数据包
+		if MissingTTLPolicy(conn.missingTTL.Load()) != MissingTTLAccept {
+			conn.tap.report(nil, cm.Src, TapDroppedTTL)
+			return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %w, source %v", ErrTTLUnavailable, cm.Src)
+		}
+		if !conn.ttlWarned.Swap(true) {
+			logg().Printf("WARN IPv4VRRPMsgCon TTL control data unavailable on %s, accept advertisements without TTL check", conn.itf.Name)
+		}
+	} else if cm.TTL != 255 {
+		conn.tap.report(nil, cm.Src, TapDroppedTTL)
 		return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: the TTL of IP datagram carring VRRP advertisment must equal to 255")
 	}
-	// 解析VRRP报文
-	advertisement, err := FromBytes(IPv4, conn.buffer[:n])
-	if err != nil {
-		return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %v", err)
+	if conn.strict.Load() && cm.IfIndex != conn.itf.Index {
+		conn.tap.report(nil, cm.Src, TapDroppedInterface)
+		return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %w, interface index %d", ErrForeignInterface, cm.IfIndex)
+	}
+	if conn.groupFilter && !cm.Dst.Equal(conn.remote.IP) {
+		// 兼容模式下广泛监听，丢弃目的地址不是组播组的数据包
+		conn.tap.report(nil, cm.Src, TapDroppedGroup)
+		return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %w, destination %v", ErrForeignGroup, cm.Dst)
+	}
+	// 解析VRRP报文，报文与伪首部在同一次内存分配中创建
+	var received = new(receivedPacket)
+	var advertisement = &received.packet
+	if err = advertisement.parse(IPv4, conn.buffer[:n]); err != nil {
+		conn.tap.report(nil, cm.Src, malformedReason(err))
+		return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: %w", err)
 	}
 
-	if advertisement.GetVersion() != byte(VRRPv3) {
+	if VRRPVersion(advertisement.GetVersion()) != conn.acceptVersion() {
+		conn.tap.report(advertisement, cm.Src, TapDroppedVersion)
 		return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: received an advertisement with %s", VRRPVersion(advertisement.GetVersion()))
 	}
 
 	// 构造伪首部
-	var pshdr PseudoHeader
+	var pshdr = &received.pshdr
 	pshdr.Saddr = cm.Src
 	pshdr.Daddr = cm.Dst
 	pshdr.Protocol = VRRPIPProtocolNumber
-	pshdr.Len = uint16(n)
+	// 部分协议栈会在报文末尾填充至最小帧长，校验和按声明的报文长度计算，不包含填充
+	pshdr.Len = uint16(advertisement.PacketSize())
 	// 校验校验码
-	if !advertisement.ValidateCheckSum(&pshdr) {
+	if !advertisement.ValidateCheckSum(pshdr) {
+		conn.tap.report(advertisement, cm.Src, TapDroppedChecksum)
 		return nil, fmt.Errorf("IPv4VRRPMsgCon.ReadMessage: validate the check sum of advertisement failed, Src: %s, Dst: %s, TTL: %d", cm.Src, cm.Dst, cm.TTL)
 	}
 
-	advertisement.Pshdr = &pshdr
+	advertisement.Pshdr = pshdr
 	return advertisement, nil
 }
 
 func (conn *IPv4VRRPMsgCon) Close() error {
-	if conn.pc != nil {
-		_ = conn.pc.LeaveGroup(conn.itf, conn.remote)
-		return conn.pc.Close()
+	if conn == nil {
+		return nil
 	}
-	return nil
+	conn.closeOnce.Do(func() {
+		conn.rejoiner.reset(0, nil)
+		if conn.pc != nil {
+			// 关闭套接字时内核会自动退出组播组，退出失败不影响关闭
+			_ = conn.pc.LeaveGroup(conn.itf, conn.remote)
+			conn.closeErr = conn.pc.Close()
+		}
+	})
+	return conn.closeErr
+}
+
+// ipv6PacketConn IPv6 组播连接所需的 ipv6.PacketConn 方法集合
+type ipv6PacketConn interface {
+	ReadFrom(b []byte) (int, *ipv6.ControlMessage, net.Addr, error)
+	WriteTo(b []byte, cm *ipv6.ControlMessage, dst net.Addr) (int, error)
+	JoinGroup(ifi *net.Interface, group net.Addr) error
+	LeaveGroup(ifi *net.Interface, group net.Addr) error
+	SetMulticastLoopback(on bool) error
+	SetMulticastHopLimit(hoplim int) error
+	MulticastHopLimit() (int, error)
+	SetMulticastInterface(ifi *net.Interface) error
+	SetControlMessage(cf ipv6.ControlFlags, on bool) error
+	LocalAddr() net.Addr
+	Close() error
 }
 
 // NewIPv6VRRPMsgCon 创建的IPv6 VRRP虚拟连接
 func NewIPv6VRRPMsgCon(itf *net.Interface, src, dst net.IP) (VRRPMsgConnection, error) {
-	multiAddr := &net.IPAddr{IP: dst}
-	conn, err := net.ListenIP("ip6:112", &net.IPAddr{})
+	conn, err := net.ListenIP(VRRPListenNetworkIPv6, &net.IPAddr{})
 	if err != nil {
 		return nil, fmt.Errorf("NewIPv6VRRPMsgCon interface %s ip packet listen err, %v", itf.Name, err)
 	}
+	_ = conn.SetReadBuffer(2048)
+	_ = conn.SetWriteBuffer(2048)
 
-	pc := ipv6.NewPacketConn(conn)
-	_ = pc.LeaveGroup(itf, multiAddr)
-	if err = pc.JoinGroup(itf, multiAddr); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("NewIPv6VRRPMsgCon interface %s join multicast group err, %v", itf.Name, err)
+	c, err := newIPv6VRRPMsgCon(itf, src, dst, ipv6.NewPacketConn(conn))
+	if err != nil {
+		return nil, err
+	}
+	c.raw, _ = conn.SyscallConn()
+	return c, nil
+}
+
+// newIPv6VRRPMsgCon 在已有的数据包连接上加入组播并完成连接配置
+func newIPv6VRRPMsgCon(itf *net.Interface, src, dst net.IP, pc ipv6PacketConn) (*IPv6VRRPMsgCon, error) {
+	multiAddr := &net.IPAddr{IP: dst}
+	if err := joinGroupWithRetry(itf, multiAddr, pc.JoinGroup, pc.LeaveGroup); err != nil {
+		_ = pc.Close()
+		return nil, fmt.Errorf("NewIPv6VRRPMsgCon interface %s join multicast group err, %w", itf.Name, err)
 	}
 
 	// 设置组播回环
-	_ = pc.SetMulticastLoopback(true)
+	loopback := pc.SetMulticastLoopback(true) == nil
 	// 设置消息的TTL为255 RFC 5798 5.1.2.3.  Hop Limit
 	_ = pc.SetMulticastHopLimit(255)
 	_ = pc.SetMulticastInterface(itf)
-	_ = pc.SetControlMessage(ipv6.FlagHopLimit|ipv6.FlagSrc|ipv6.FlagDst|ipv6.FlagInterface, true)
-
-	_ = conn.SetReadBuffer(2048)
-	_ = conn.SetWriteBuffer(2048)
+	_ = pc.SetControlMessage(DefaultIPv6ControlFlags, true)
 
 	return &IPv6VRRPMsgCon{
-		buffer: make([]byte, 4096),
-		local:  src,
-		remote: multiAddr,
-		pc:     pc,
+		itf:      itf,
+		buffer:   make([]byte, 4096),
+		local:    src,
+		remote:   multiAddr,
+		pc:       pc,
+		loopback: loopback,
+		flags:    DefaultIPv6ControlFlags,
 	}, nil
 }
 
+// IPv6 接收数据包时获取的控制消息
+const (
+	// RequiredIPv6ControlFlags 校验数据包必需的控制消息：Hop Limit 校验（RFC 5798 5.1.2.3）以及伪首部中的源地址与目的地址
+	RequiredIPv6ControlFlags = ipv6.FlagHopLimit | ipv6.FlagSrc | ipv6.FlagDst
+	// DefaultIPv6ControlFlags 默认的控制消息，额外获取接收网口用于 SetStrictInterface
+	DefaultIPv6ControlFlags = RequiredIPv6ControlFlags | ipv6.FlagInterface
+)
+
+// SetControlFlags 设置 接收数据包时获取的控制消息，默认为 DefaultIPv6ControlFlags。
+// 可去掉不需要的控制消息以降低每个数据包的开销，或增加控制消息用于诊断。
+// 不包含 RequiredIPv6ControlFlags 时返回错误；开启 SetStrictInterface 时必须包含 ipv6.FlagInterface。
+// 需在开始收发消息前调用。
+func (con *IPv6VRRPMsgCon) SetControlFlags(flags ipv6.ControlFlags) error {
+	if flags&RequiredIPv6ControlFlags != RequiredIPv6ControlFlags {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetControlFlags: flags %v missing required %v", flags, RequiredIPv6ControlFlags&^flags)
+	}
+	if con.strict.Load() && flags&ipv6.FlagInterface == 0 {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetControlFlags: interface flag is required by strict interface mode")
+	}
+	if removed := con.flags &^ flags; removed != 0 {
+		if err := con.pc.SetControlMessage(removed, false); err != nil {
+			return fmt.Errorf("IPv6VRRPMsgCon.SetControlFlags: %v", err)
+		}
+	}
+	if err := con.pc.SetControlMessage(flags, true); err != nil {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetControlFlags: %v", err)
+	}
+	con.flags = flags
+	return nil
+}
+
 // IPv6VRRPMsgCon IPv6的VRRP消息组播连接
 type IPv6VRRPMsgCon struct {
-	itf    *net.Interface   // 组播接口
-	buffer []byte           // 接收数据包的缓冲区
-	local  net.IP           // 发送IP数据包的源地址
-	remote *net.IPAddr      // 组播地址
-	pc     *ipv6.PacketConn // 组播连接
+	itf      *net.Interface    // 组播接口
+	buffer   []byte            // 接收数据包的缓冲区
+	local    net.IP            // 发送IP数据包的源地址
+	remote   *net.IPAddr       // 组播地址
+	pc       ipv6PacketConn    // 组播连接
+	raw      syscall.RawConn   // 底层套接字，用于设置套接字选项
+	loopback bool              // 是否开启了组播回环
+	flags    ipv6.ControlFlags // 接收数据包时获取的控制消息
+	strict   atomic.Bool       // 是否丢弃非工作网口收到的数据包
+	tap      rawTapHolder      // 原始报文监听函数
+	rejoiner groupRejoiner     // 组播组周期性重新加入任务
+
+	closeOnce sync.Once // 确保连接仅关闭一次
+	closeErr  error     // 关闭连接的结果
+}
+
+// SetRawTap 设置 原始报文监听函数，连接校验未通过的报文均会连同原因报告给该函数
+func (con *IPv6VRRPMsgCon) SetRawTap(tap RawTap) {
+	con.tap.set(tap)
+}
+
+// SetVersion 设置 接受的VRRP协议版本，IPv6 仅支持 VRRPv3（VRRPv2 不支持 IPv6）
+func (con *IPv6VRRPMsgCon) SetVersion(version VRRPVersion) error {
+	if version != VRRPv3 {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetVersion: unsupported %s", version)
+	}
+	return nil
+}
+
+// SetStrictInterface 设置 是否丢弃非工作网口收到的数据包，默认关闭
+func (con *IPv6VRRPMsgCon) SetStrictInterface(flag bool) {
+	con.strict.Store(flag)
+}
+
+// SetRejoinInterval 设置 周期性重新加入组播组的时间间隔，小于等于 0 表示关闭（默认关闭）
+func (con *IPv6VRRPMsgCon) SetRejoinInterval(interval time.Duration) {
+	con.rejoiner.reset(interval, func() {
+		_ = con.pc.LeaveGroup(con.itf, con.remote)
+		if err := con.pc.JoinGroup(con.itf, con.remote); err != nil {
+			logg().Printf("ERROR IPv6VRRPMsgCon rejoin multicast group %s: %v", con.remote, err)
+		}
+	})
+}
+
+// ConnectionInfo 获取 连接的绑定信息
+func (con *IPv6VRRPMsgCon) ConnectionInfo() ConnectionInfo {
+	return connectionInfo(con.itf, con.pc.LocalAddr(), con.local, con.remote.IP, con.loopback)
+}
+
+// BindToDevice 将连接的套接字绑定至指定网络设备（如 Linux VRF 设备）
+func (con *IPv6VRRPMsgCon) BindToDevice(name string) error {
+	if con.raw == nil {
+		return fmt.Errorf("IPv6VRRPMsgCon.BindToDevice: %w", ErrBindToDeviceUnsupported)
+	}
+	return bindToDevice(con.raw, name)
+}
+
+// SetMulticastTTL 设置 发送组播消息使用的跳数限制（Hop Limit），取值范围 1~255
+func (con *IPv6VRRPMsgCon) SetMulticastTTL(ttl int) error {
+	if ttl < 1 || ttl > 255 {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetMulticastTTL: invalid hop limit %d", ttl)
+	}
+	return con.pc.SetMulticastHopLimit(ttl)
+}
+
+// MulticastTTL 获取 套接字当前发送组播消息使用的跳数限制（Hop Limit）
+func (con *IPv6VRRPMsgCon) MulticastTTL() (int, error) {
+	return con.pc.MulticastHopLimit()
+}
+
+// SetSocketMark 设置 连接套接字的标记（Linux SO_MARK），其他平台为空操作
+func (con *IPv6VRRPMsgCon) SetSocketMark(mark uint32) error {
+	if con.raw == nil {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetSocketMark: %w", ErrSocketMarkUnsupported)
+	}
+	return setSocketMark(con.raw, mark)
+}
+
+// SetGroup 设置 发送与接收VRRP消息使用的组播组，加入新的组播组后离开原组播组
+// 需在开始收发消息前调用。
+func (con *IPv6VRRPMsgCon) SetGroup(group net.IP) error {
+	if group.To4() != nil || group.To16() == nil || !group.IsMulticast() {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetGroup: %v is not an IPv6 multicast address", group)
+	}
+	if group.Equal(con.remote.IP) {
+		return nil
+	}
+	multiAddr := &net.IPAddr{IP: group.To16()}
+	if err := con.pc.JoinGroup(con.itf, multiAddr); err != nil {
+		return fmt.Errorf("IPv6VRRPMsgCon.SetGroup: join multicast group %s err, %v", group, err)
+	}
+	_ = con.pc.LeaveGroup(con.itf, con.remote)
+	con.remote = multiAddr
+	return nil
 }
 
 // WriteMessage 发送VRRP数据包
@@ -164,40 +692,92 @@ func (con *IPv6VRRPMsgCon) WriteMessage(packet *VRRPPacket) error {
 
 // ReadMessage 读取VRRP数据包
 func (con *IPv6VRRPMsgCon) ReadMessage() (*VRRPPacket, error) {
-	n, cm, _, err := con.pc.ReadFrom(con.buffer)
+	n, cm, src, err := con.pc.ReadFrom(con.buffer)
 	if err != nil {
 		return nil, NetErr{fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: %v", err)}
 	}
+	if cm == nil {
+		// 协议栈未返回控制消息，源地址取自数据包的来源，目的地址视为组播地址
+		cm = &ipv6.ControlMessage{Dst: con.remote.IP}
+		if addr, ok := src.(*net.IPAddr); ok {
+			cm.Src = addr.IP
+		}
+	}
 	// 检查 TTL 应该为 255 (see RFC5798
+	if cm.HopLimit == 0 {
+		// 控制消息中没有跳数限制数据，无法校验，区别于跳数限制不为255的数据包
+		con.tap.report(nil, cm.Src, TapDroppedTTL)
+		return nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: %w, source %v", ErrTTLUnavailable, cm.Src)
+	}
 	if cm.HopLimit != 255 {
+		con.tap.report(nil, cm.Src, TapDroppedTTL)
 		return nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: the TTL of IP datagram carring VRRP advertisment must equal to 255")
 	}
-
-	var pshdr = PseudoHeader{
-		Daddr:    cm.Src,
-		Saddr:    cm.Dst,
-		Protocol: VRRPIPProtocolNumber,
-		Len:      uint16(n),
+	if con.strict.Load() && cm.IfIndex != con.itf.Index {
+		con.tap.report(nil, cm.Src, TapDroppedInterface)
+		return nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: %w, interface index %d", ErrForeignInterface, cm.IfIndex)
 	}
-	advertisement, err := FromBytes(IPv6, con.buffer)
-	if err != nil {
-		return nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: %v", err)
+
+	// 解析VRRP报文，报文与伪首部在同一次内存分配中创建
+	var received = new(receivedPacket)
+	var advertisement = &received.packet
+	if err = advertisement.parse(IPv6, con.buffer[:n]); err != nil {
+		con.tap.report(nil, cm.Src, malformedReason(err))
+		return nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: %w", err)
 	}
 
 	if VRRPVersion(advertisement.GetVersion()) != VRRPv3 {
+		con.tap.report(advertisement, cm.Src, TapDroppedVersion)
 		return nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: invalid VRRP version %v", advertisement.GetVersion())
 	}
-	if !advertisement.ValidateCheckSum(&pshdr) {
+
+	// 伪首部的源地址为发送方地址，选举中的源IP地址比较与对端记录均依赖该地址
+	var pshdr = &received.pshdr
+	pshdr.Saddr = cm.Src
+	pshdr.Daddr = cm.Dst
+	pshdr.Protocol = VRRPIPProtocolNumber
+	// 校验和按声明的报文长度计算，不包含末尾的填充
+	pshdr.Len = uint16(advertisement.PacketSize())
+	if !advertisement.ValidateCheckSum(pshdr) {
+		con.tap.report(advertisement, cm.Src, TapDroppedChecksum)
 		return nil, fmt.Errorf("IPv6VRRPMsgCon.ReadMessage: invalid check sum")
 	}
-	advertisement.Pshdr = &pshdr
+	advertisement.Pshdr = pshdr
 	return advertisement, nil
 }
 
 func (con *IPv6VRRPMsgCon) Close() error {
-	if con.pc != nil {
-		_ = con.pc.LeaveGroup(con.itf, con.remote)
-		return con.pc.Close()
+	if con == nil {
+		return nil
 	}
-	return nil
+	con.closeOnce.Do(func() {
+		con.rejoiner.reset(0, nil)
+		if con.pc != nil {
+			// 关闭套接字时内核会自动退出组播组，退出失败不影响关闭
+			_ = con.pc.LeaveGroup(con.itf, con.remote)
+			con.closeErr = con.pc.Close()
+		}
+	})
+	return con.closeErr
+}
+
+// receivedPacket 接收到的VRRP报文以及其伪头部，用于减少接收路径上的内存分配
+type receivedPacket struct {
+	packet VRRPPacket
+	pshdr  PseudoHeader
+}
+
+// connectionInfo 根据连接参数构造绑定信息
+func connectionInfo(itf *net.Interface, laddr net.Addr, src, group net.IP, loopback bool) ConnectionInfo {
+	info := ConnectionInfo{
+		LocalAddr: laddr,
+		SourceIP:  src,
+		Group:     group,
+		Loopback:  loopback,
+	}
+	if itf != nil {
+		info.InterfaceName = itf.Name
+		info.InterfaceIndex = itf.Index
+	}
+	return info
 }
@@ -0,0 +1,946 @@
+package govrrp
+
+import (
+	"errors"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"net"
+	"net/netip"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeIPv4PacketConn 模拟的 ipv4.PacketConn
+type fakeIPv4PacketConn struct {
+	mu       sync.Mutex
+	joined   []net.Addr
+	left     []net.Addr
+	written  [][]byte
+	dsts     []net.Addr
+	cms      []*ipv4.ControlMessage
+	flags    ipv4.ControlFlags
+	loopback bool
+	ttl      int
+	closed   bool
+	joinErrs []error // 依次作为 JoinGroup 的返回值，用尽后返回 nil
+
+	reads chan fakeIPv4Read
+}
+
+// fakeIPv4Read 一次 ReadFrom 的结果
+type fakeIPv4Read struct {
+	b   []byte
+	cm  *ipv4.ControlMessage
+	src net.IP // 没有控制消息时数据包的来源地址
+}
+
+func newFakeIPv4PacketConn() *fakeIPv4PacketConn {
+	return &fakeIPv4PacketConn{reads: make(chan fakeIPv4Read, 16)}
+}
+
+func (c *fakeIPv4PacketConn) ReadFrom(b []byte) (int, *ipv4.ControlMessage, net.Addr, error) {
+	r, ok := <-c.reads
+	if !ok {
+		return 0, nil, nil, errors.New("fakeIPv4PacketConn: closed")
+	}
+	n := copy(b, r.b)
+	var src net.Addr
+	if r.cm != nil {
+		src = &net.IPAddr{IP: r.cm.Src}
+	} else if r.src != nil {
+		src = &net.IPAddr{IP: r.src}
+	}
+	return n, r.cm, src, nil
+}
+
+func (c *fakeIPv4PacketConn) WriteTo(b []byte, cm *ipv4.ControlMessage, dst net.Addr) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, append([]byte(nil), b...))
+	c.dsts = append(c.dsts, dst)
+	c.cms = append(c.cms, cm)
+	return len(b), nil
+}
+
+func (c *fakeIPv4PacketConn) JoinGroup(_ *net.Interface, group net.Addr) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.joined = append(c.joined, group)
+	if len(c.joinErrs) > 0 {
+		err := c.joinErrs[0]
+		c.joinErrs = c.joinErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (c *fakeIPv4PacketConn) LeaveGroup(_ *net.Interface, group net.Addr) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.left = append(c.left, group)
+	return nil
+}
+
+func (c *fakeIPv4PacketConn) SetMulticastLoopback(on bool) error {
+	c.loopback = on
+	return nil
+}
+
+func (c *fakeIPv4PacketConn) SetMulticastTTL(ttl int) error {
+	c.ttl = ttl
+	return nil
+}
+
+func (c *fakeIPv4PacketConn) MulticastTTL() (int, error) { return c.ttl, nil }
+
+func (c *fakeIPv4PacketConn) SetMulticastInterface(*net.Interface) error { return nil }
+
+func (c *fakeIPv4PacketConn) SetControlMessage(cf ipv4.ControlFlags, on bool) error {
+	if on {
+		c.flags |= cf
+	} else {
+		c.flags &^= cf
+	}
+	return nil
+}
+
+func (c *fakeIPv4PacketConn) LocalAddr() net.Addr {
+	return &net.IPAddr{IP: net.IPv4zero}
+}
+
+func (c *fakeIPv4PacketConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errors.New("fakeIPv4PacketConn: already closed")
+	}
+	c.closed = true
+	close(c.reads)
+	return nil
+}
+
+// fakeIPv6PacketConn 模拟的 ipv6.PacketConn，依次返回投递的数据包
+type fakeIPv6PacketConn struct {
+	hopLimit int
+	reads    chan fakeIPv6Read
+	once     sync.Once
+}
+
+// fakeIPv6Read 一次 ReadFrom 的结果
+type fakeIPv6Read struct {
+	b   []byte
+	cm  *ipv6.ControlMessage
+	src net.IP // 没有控制消息时数据包的来源地址
+}
+
+func newFakeIPv6PacketConn() *fakeIPv6PacketConn {
+	return &fakeIPv6PacketConn{reads: make(chan fakeIPv6Read, 16)}
+}
+
+func (c *fakeIPv6PacketConn) ReadFrom(b []byte) (int, *ipv6.ControlMessage, net.Addr, error) {
+	r, ok := <-c.reads
+	if !ok {
+		return 0, nil, nil, errors.New("fakeIPv6PacketConn: closed")
+	}
+	n := copy(b, r.b)
+	var src net.Addr
+	if r.cm != nil {
+		src = &net.IPAddr{IP: r.cm.Src}
+	} else if r.src != nil {
+		src = &net.IPAddr{IP: r.src}
+	}
+	return n, r.cm, src, nil
+}
+
+func (c *fakeIPv6PacketConn) WriteTo(b []byte, _ *ipv6.ControlMessage, _ net.Addr) (int, error) {
+	return len(b), nil
+}
+
+func (c *fakeIPv6PacketConn) JoinGroup(*net.Interface, net.Addr) error  { return nil }
+func (c *fakeIPv6PacketConn) LeaveGroup(*net.Interface, net.Addr) error { return nil }
+func (c *fakeIPv6PacketConn) SetMulticastLoopback(bool) error           { return nil }
+func (c *fakeIPv6PacketConn) SetMulticastInterface(*net.Interface) error {
+	return nil
+}
+func (c *fakeIPv6PacketConn) SetControlMessage(ipv6.ControlFlags, bool) error { return nil }
+func (c *fakeIPv6PacketConn) LocalAddr() net.Addr                             { return &net.IPAddr{IP: net.IPv6unspecified} }
+
+func (c *fakeIPv6PacketConn) SetMulticastHopLimit(hoplim int) error {
+	c.hopLimit = hoplim
+	return nil
+}
+
+func (c *fakeIPv6PacketConn) MulticastHopLimit() (int, error) { return c.hopLimit, nil }
+
+func (c *fakeIPv6PacketConn) Close() error {
+	c.once.Do(func() { close(c.reads) })
+	return nil
+}
+
+func TestIPv6VRRPMsgCon_ReadMessage(t *testing.T) {
+	pc := newFakeIPv6PacketConn()
+	local := net.ParseIP("fe80::10")
+	conn, err := newIPv6VRRPMsgCon(&net.Interface{Index: 1, Name: "eth0"}, local, VRRPMultiAddrIPv6, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if ttl, _ := conn.MulticastTTL(); ttl != 255 {
+		t.Errorf("hop limit %d, want 255", ttl)
+	}
+
+	src := net.ParseIP("fe80::20")
+	vip := net.ParseIP("2001:db8::100")
+	var packet VRRPPacket
+	packet.SetVersion(VRRPv3)
+	packet.SetType()
+	packet.SetVirtualRouterID(10)
+	packet.SetPriority(150)
+	packet.SetAdvertisementInterval(100)
+	packet.AddIPvXAddr(IPv6, vip)
+	packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv6, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+
+	pc.reads <- fakeIPv6Read{b: packet.ToBytes(), cm: &ipv6.ControlMessage{HopLimit: 254, Src: src, Dst: VRRPMultiAddrIPv6, IfIndex: 1}}
+	pc.reads <- fakeIPv6Read{b: packet.ToBytes(), cm: &ipv6.ControlMessage{HopLimit: 255, Src: src, Dst: VRRPMultiAddrIPv6, IfIndex: 1}}
+
+	if _, err = conn.ReadMessage(); err == nil {
+		t.Fatal("advertisement with hop limit 254 should be rejected")
+	}
+	got, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Pshdr.Saddr.Equal(src) || !got.Pshdr.Daddr.Equal(VRRPMultiAddrIPv6) {
+		t.Errorf("pseudo header source %v destination %v, want %v -> %v", got.Pshdr.Saddr, got.Pshdr.Daddr, src, VRRPMultiAddrIPv6)
+	}
+	if addrs := got.GetIPvXAddr(IPv6); len(addrs) != 1 || !addrs[0].Equal(vip) {
+		t.Errorf("virtual IP addresses %v, want [%v]", addrs, vip)
+	}
+
+	// 协议栈未返回控制消息时不应 panic，缺少跳数限制数据的数据包被丢弃
+	pc.reads <- fakeIPv6Read{b: packet.ToBytes(), src: src}
+	if _, err = conn.ReadMessage(); !errors.Is(err, ErrTTLUnavailable) {
+		t.Errorf("no control message: got %v, want ErrTTLUnavailable", err)
+	}
+	pc.reads <- fakeIPv6Read{b: packet.ToBytes(), cm: &ipv6.ControlMessage{Src: src, Dst: VRRPMultiAddrIPv6, IfIndex: 1}}
+	if _, err = conn.ReadMessage(); !errors.Is(err, ErrTTLUnavailable) {
+		t.Errorf("hop limit 0: got %v, want ErrTTLUnavailable", err)
+	}
+}
+
+func TestIPv4VRRPMsgCon_ConnectionInfo(t *testing.T) {
+	itf := &net.Interface{Index: 3, Name: "eth1"}
+	src := net.IPv4(192, 168, 0, 10).To4()
+	conn, err := newIPv4VRRPMsgConn(itf, src, VRRPMultiAddrIPv4, newFakeIPv4PacketConn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := conn.ConnectionInfo()
+	if info.InterfaceName != "eth1" || info.InterfaceIndex != 3 {
+		t.Errorf("unexpected interface %s(%d)", info.InterfaceName, info.InterfaceIndex)
+	}
+	if !info.SourceIP.Equal(src) {
+		t.Errorf("unexpected source ip %v", info.SourceIP)
+	}
+	if !info.Group.Equal(VRRPMultiAddrIPv4) {
+		t.Errorf("unexpected group %v", info.Group)
+	}
+	if !info.Loopback {
+		t.Error("multicast loopback should be enabled")
+	}
+	if info.LocalAddr.String() != "0.0.0.0" {
+		t.Errorf("unexpected local addr %v", info.LocalAddr)
+	}
+}
+
+func TestIPv4VRRPMsgCon_WriteMessageControlMessage(t *testing.T) {
+	itf := &net.Interface{Index: 3, Name: "eth1"}
+	src := net.IPv4(192, 168, 0, 10).To4()
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(itf, src, VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = conn.WriteMessage(newAdvertisement(240, 100, "192.168.0.10")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if len(pc.cms) != 3 {
+		t.Fatalf("expect 3 sends, got %d", len(pc.cms))
+	}
+	for i, cm := range pc.cms {
+		if cm == nil {
+			t.Fatalf("send %d: control message should not be nil", i)
+		}
+		if cm.IfIndex != 3 || !cm.Src.Equal(src) {
+			t.Errorf("send %d: expect interface 3 and source %v, got %d and %v", i, src, cm.IfIndex, cm.Src)
+		}
+	}
+}
+
+func (c *fakeIPv4PacketConn) joinCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.joined)
+}
+
+func TestIPv4VRRPMsgCon_SetRejoinInterval(t *testing.T) {
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 默认关闭
+	time.Sleep(50 * time.Millisecond)
+	if n := pc.joinCount(); n != 1 {
+		t.Fatalf("expect only the initial join, got %d", n)
+	}
+
+	conn.SetRejoinInterval(20 * time.Millisecond)
+	time.Sleep(110 * time.Millisecond)
+	conn.SetRejoinInterval(0)
+	n := pc.joinCount() - 1
+	if n < 3 || n > 6 {
+		t.Errorf("expect about 5 rejoins at 20ms interval, got %d", n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if pc.joinCount()-1 != n {
+		t.Error("rejoin should stop after interval reset to 0")
+	}
+}
+
+// replayIPv4PacketConn 每次读取都返回同一个数据包的 ipv4PacketConn，用于基准测试
+type replayIPv4PacketConn struct {
+	fakeIPv4PacketConn
+	raw []byte
+	cm  *ipv4.ControlMessage
+	src net.Addr
+}
+
+func (c *replayIPv4PacketConn) ReadFrom(b []byte) (int, *ipv4.ControlMessage, net.Addr, error) {
+	return copy(b, c.raw), c.cm, c.src, nil
+}
+
+func BenchmarkIPv4VRRPMsgCon_ReadMessage(b *testing.B) {
+	var packet VRRPPacket
+	packet.SetVersion(VRRPv3)
+	packet.SetType()
+	packet.SetVirtualRouterID(240)
+	packet.SetPriority(100)
+	packet.SetAdvertisementInterval(100)
+	for i := 0; i < 8; i++ {
+		packet.AddIPvXAddr(IPv4, net.IPv4(192, 168, 0, byte(200+i)))
+	}
+	src := net.IPv4(192, 168, 0, 10)
+	packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv4, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+
+	pc := &replayIPv4PacketConn{
+		raw: packet.ToBytes(),
+		cm:  &ipv4.ControlMessage{TTL: 255, Src: src, Dst: VRRPMultiAddrIPv4, IfIndex: 1},
+		src: &net.IPAddr{IP: src},
+	}
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, src, VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := conn.ReadMessage(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// replayIPv6PacketConn 每次读取都返回同一个数据包的 ipv6PacketConn，用于基准测试
+type replayIPv6PacketConn struct {
+	fakeIPv6PacketConn
+	raw []byte
+	cm  *ipv6.ControlMessage
+	src net.Addr
+}
+
+func (c *replayIPv6PacketConn) ReadFrom(b []byte) (int, *ipv6.ControlMessage, net.Addr, error) {
+	return copy(b, c.raw), c.cm, c.src, nil
+}
+
+func BenchmarkIPv6VRRPMsgCon_ReadMessage(b *testing.B) {
+	var packet VRRPPacket
+	packet.SetVersion(VRRPv3)
+	packet.SetType()
+	packet.SetVirtualRouterID(240)
+	packet.SetPriority(100)
+	packet.SetAdvertisementInterval(100)
+	for i := 0; i < 8; i++ {
+		vip := net.ParseIP("2001:db8::")
+		vip[15] = byte(200 + i)
+		packet.AddIPvXAddr(IPv6, vip)
+	}
+	src := net.ParseIP("fe80::10")
+	packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv6, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+
+	pc := &replayIPv6PacketConn{
+		fakeIPv6PacketConn: fakeIPv6PacketConn{hopLimit: 255},
+		raw:                packet.ToBytes(),
+		cm:                 &ipv6.ControlMessage{HopLimit: 255, Src: src, Dst: VRRPMultiAddrIPv6, IfIndex: 1},
+		src:                &net.IPAddr{IP: src},
+	}
+	conn, err := newIPv6VRRPMsgCon(&net.Interface{Index: 1, Name: "eth0"}, src, VRRPMultiAddrIPv6, pc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := conn.ReadMessage(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestIPv4VRRPMsgCon_SetGroup(t *testing.T) {
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.SetGroup(net.IPv4(192, 168, 0, 1)); err == nil {
+		t.Error("expect error for unicast group")
+	}
+	group := net.IPv4(224, 0, 0, 100)
+	if err = conn.SetGroup(group); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.WriteMessage(newAdvertisement(1, 100, "192.168.0.10")); err != nil {
+		t.Fatal(err)
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if joined := pc.joined[len(pc.joined)-1].(*net.IPAddr); !joined.IP.Equal(group) {
+		t.Errorf("expect joined %v, got %v", group, joined)
+	}
+	if left := pc.left[len(pc.left)-1].(*net.IPAddr); !left.IP.Equal(VRRPMultiAddrIPv4) {
+		t.Errorf("expect left %v, got %v", VRRPMultiAddrIPv4, left)
+	}
+	if dst := pc.dsts[0].(*net.IPAddr); !dst.IP.Equal(group) {
+		t.Errorf("expect sent to %v, got %v", group, dst)
+	}
+}
+
+func TestIPv4VRRPMsgCon_SetStrictInterface(t *testing.T) {
+	src := net.IPv4(192, 168, 0, 20).To4()
+	packet := newAdvertisement(1, 100, "192.168.0.20")
+	packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv4, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+	raw := packet.ToBytes()
+
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign := &ipv4.ControlMessage{TTL: 255, Src: src, Dst: VRRPMultiAddrIPv4, IfIndex: 2}
+
+	pc.reads <- fakeIPv4Read{b: raw, cm: foreign}
+	if _, err = conn.ReadMessage(); err != nil {
+		t.Errorf("foreign interface should be accepted by default, got %v", err)
+	}
+
+	conn.SetStrictInterface(true)
+	pc.reads <- fakeIPv4Read{b: raw, cm: foreign}
+	if _, err = conn.ReadMessage(); !errors.Is(err, ErrForeignInterface) {
+		t.Errorf("expect ErrForeignInterface, got %v", err)
+	}
+	pc.reads <- fakeIPv4Read{b: raw, cm: &ipv4.ControlMessage{TTL: 255, Src: src, Dst: VRRPMultiAddrIPv4, IfIndex: 1}}
+	if _, err = conn.ReadMessage(); err != nil {
+		t.Errorf("working interface should be accepted, got %v", err)
+	}
+}
+
+func TestVirtualRouter_SetRawTap(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr.vrrpConn = conn
+	vr.state = BACKUP
+
+	type tapped struct {
+		pkt    *VRRPPacket
+		src    string
+		result string
+	}
+	var got []tapped
+	vr.SetRawTap(func(pkt *VRRPPacket, src net.IP, result string) {
+		got = append(got, tapped{pkt, src.String(), result})
+	})
+
+	src := net.IPv4(192, 168, 0, 20).To4()
+	cm := &ipv4.ControlMessage{TTL: 255, Src: src, Dst: VRRPMultiAddrIPv4, IfIndex: 1}
+	advertisement := func(VRID byte) []byte {
+		packet := newAdvertisement(VRID, 100, "192.168.0.20")
+		packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv4, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+		return packet.ToBytes()
+	}
+	bad := advertisement(240)
+	bad[7] ^= 0xff
+	pc.reads <- fakeIPv4Read{b: bad, cm: cm}
+	pc.reads <- fakeIPv4Read{b: advertisement(240), cm: cm}
+	pc.reads <- fakeIPv4Read{b: advertisement(241), cm: cm}
+	pc.reads <- fakeIPv4Read{b: advertisement(240), cm: &ipv4.ControlMessage{TTL: 64, Src: src, Dst: VRRPMultiAddrIPv4}}
+	_ = pc.Close()
+	vr.fetchVRRPDaemon(vr.vrrpConn)
+
+	want := []string{TapDroppedChecksum, TapAccepted, TapDroppedVRID, TapDroppedTTL}
+	if len(got) != len(want) {
+		t.Fatalf("expect %d tapped packets, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].result != want[i] || got[i].src != "192.168.0.20" {
+			t.Errorf("packet %d: expect %s from 192.168.0.20, got %s from %s", i, want[i], got[i].result, got[i].src)
+		}
+	}
+	if got[0].pkt == nil || got[0].pkt.GetVirtualRouterID() != 240 {
+		t.Error("tap should receive the packet with bad checksum")
+	}
+	if len(vr.packetQueue) != 1 {
+		t.Errorf("expect only the accepted packet queued, got %d", len(vr.packetQueue))
+	}
+}
+
+func TestVirtualRouter_SetProtocolVersion(t *testing.T) {
+	v6, _ := newTestRouter(t, nil, 240, "fe80::10", 100)
+	if err := v6.SetProtocolVersion(VRRPv2); err == nil {
+		t.Error("VRRPv2 over IPv6 should be rejected")
+	}
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.20", 100)
+	if err := vr.SetProtocolVersion(VRRPv1); err == nil {
+		t.Error("VRRPv1 should be rejected")
+	}
+	if err := vr.SetProtocolVersion(VRRPv2); err == nil {
+		t.Error("connection without version support should reject VRRPv2")
+	}
+
+	newConn := func() (*IPv4VRRPMsgCon, *fakeIPv4PacketConn) {
+		pc := newFakeIPv4PacketConn()
+		conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 20), VRRPMultiAddrIPv4, pc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, pc
+	}
+	vr.vrrpConn, _ = newConn()
+	vr.SetAdvInterval(time.Second)
+	vr.AddIPvXAddr(net.IPv4(192, 168, 0, 100))
+	if err := vr.SetProtocolVersion(VRRPv2); err != nil {
+		t.Fatal(err)
+	}
+	packet := vr.CurrentAdvertisement()
+	if VRRPVersion(packet.GetVersion()) != VRRPv2 || packet.Header[5] != 1 || len(packet.AuthData) != VRRPv2AuthDataLen {
+		t.Fatalf("unexpected VRRPv2 advertisement %v", packet)
+	}
+	if vr.AdvertisementSize() != 20+packet.PacketSize() {
+		t.Errorf("advertisement size %d mismatch with packet size %d", vr.AdvertisementSize(), packet.PacketSize())
+	}
+	raw := packet.ToBytes()
+	// 接收端的伪首部与发送端不同，VRRPv2 校验和不覆盖伪首部
+	cm := &ipv4.ControlMessage{TTL: 255, Src: net.IPv4(192, 168, 0, 20).To4(), Dst: VRRPMultiAddrIPv4, IfIndex: 1}
+
+	v2Receiver, pc := newConn()
+	if err := v2Receiver.SetVersion(VRRPv2); err != nil {
+		t.Fatal(err)
+	}
+	pc.reads <- fakeIPv4Read{b: raw, cm: cm}
+	received, err := v2Receiver.ReadMessage()
+	if err != nil {
+		t.Fatalf("VRRPv2 receiver should accept the advertisement, got %v", err)
+	}
+	if received.GetAdvertisementInterval() != 100 || received.GetVirtualRouterID() != 240 {
+		t.Errorf("unexpected received advertisement %v", received)
+	}
+
+	v3Receiver, pc := newConn()
+	var result string
+	v3Receiver.SetRawTap(func(_ *VRRPPacket, _ net.IP, r string) { result = r })
+	pc.reads <- fakeIPv4Read{b: raw, cm: cm}
+	if _, err = v3Receiver.ReadMessage(); err == nil || result != TapDroppedVersion {
+		t.Errorf("VRRPv3 receiver should drop the advertisement, got %v (%s)", err, result)
+	}
+}
+
+func TestNewIPv4VRRPMsgConn_JoinGroupRetry(t *testing.T) {
+	backoff := joinGroupBackoff
+	joinGroupBackoff = time.Millisecond
+	defer func() { joinGroupBackoff = backoff }()
+	itf := &net.Interface{Index: 1, Name: "eth0"}
+
+	pc := newFakeIPv4PacketConn()
+	pc.joinErrs = []error{syscall.EADDRINUSE}
+	if _, err := newIPv4VRRPMsgConn(itf, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc); err != nil {
+		t.Fatalf("construction should succeed after retry, got %v", err)
+	}
+	if len(pc.joined) != 2 || len(pc.left) != 1 {
+		t.Errorf("expect 2 joins and 1 leave for stale membership, got %d joins and %d leaves", len(pc.joined), len(pc.left))
+	}
+
+	pc = newFakeIPv4PacketConn()
+	pc.joinErrs = []error{syscall.ENODEV}
+	if _, err := newIPv4VRRPMsgConn(itf, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc); err != nil {
+		t.Fatalf("construction should succeed after retry, got %v", err)
+	}
+	if len(pc.left) != 0 {
+		t.Errorf("leave should only be attempted for stale membership, got %d leaves", len(pc.left))
+	}
+
+	pc = newFakeIPv4PacketConn()
+	pc.joinErrs = []error{syscall.ENODEV, syscall.ENODEV, syscall.ENODEV, syscall.ENODEV}
+	_, err := newIPv4VRRPMsgConn(itf, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if !errors.Is(err, syscall.ENODEV) || !pc.closed {
+		t.Errorf("expect ENODEV after retries exhausted and connection closed, got %v", err)
+	}
+	if len(pc.joined) != joinGroupRetries+1 {
+		t.Errorf("expect %d join attempts, got %d", joinGroupRetries+1, len(pc.joined))
+	}
+}
+
+func TestIPv4VRRPMsgCon_ReadMessagePadding(t *testing.T) {
+	src := net.IPv4(192, 168, 0, 20).To4()
+	packet := newAdvertisement(1, 100, "192.168.0.20")
+	packet.AddIPAddr(netip.MustParseAddr("192.168.0.100"))
+	packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv4, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+	padded := append(packet.ToBytes(), make([]byte, 20)...)
+
+	parsed, err := FromBytes(IPv4, padded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.PacketSize() != packet.PacketSize() || len(parsed.GetIPAddrs()) != int(packet.GetIPvXAddrCount()) {
+		t.Errorf("padding should be ignored, got size %d and addresses %v", parsed.PacketSize(), parsed.GetIPAddrs())
+	}
+
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.reads <- fakeIPv4Read{b: padded, cm: &ipv4.ControlMessage{TTL: 255, Src: src, Dst: VRRPMultiAddrIPv4, IfIndex: 1}}
+	received, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("padded advertisement should validate, got %v", err)
+	}
+	if received.Pshdr.Len != uint16(packet.PacketSize()) {
+		t.Errorf("expect checksum over declared length %d, got %d", packet.PacketSize(), received.Pshdr.Len)
+	}
+}
+
+func TestIPv4VRRPMsgCon_SetControlFlags(t *testing.T) {
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.flags != DefaultIPv4ControlFlags {
+		t.Errorf("expect default flags %v, got %v", DefaultIPv4ControlFlags, pc.flags)
+	}
+	for _, flags := range []ipv4.ControlFlags{
+		ipv4.FlagSrc | ipv4.FlagDst | ipv4.FlagInterface,
+		ipv4.FlagTTL | ipv4.FlagDst | ipv4.FlagInterface,
+		ipv4.FlagTTL | ipv4.FlagSrc,
+		0,
+	} {
+		if err = conn.SetControlFlags(flags); err == nil {
+			t.Errorf("flags %v should be rejected", flags)
+		}
+	}
+	if pc.flags != DefaultIPv4ControlFlags {
+		t.Errorf("rejected flags should not be applied, got %v", pc.flags)
+	}
+
+	if err = conn.SetControlFlags(RequiredIPv4ControlFlags); err != nil {
+		t.Fatal(err)
+	}
+	if pc.flags != RequiredIPv4ControlFlags {
+		t.Errorf("expect flags %v, got %v", RequiredIPv4ControlFlags, pc.flags)
+	}
+	conn.SetStrictInterface(true)
+	if err = conn.SetControlFlags(RequiredIPv4ControlFlags); err == nil {
+		t.Error("strict interface mode requires the interface flag")
+	}
+	if err = conn.SetControlFlags(DefaultIPv4ControlFlags); err != nil || pc.flags != DefaultIPv4ControlFlags {
+		t.Errorf("expect default flags restored, got %v %v", pc.flags, err)
+	}
+}
+
+func TestVRRPMsgCon_CloseIdempotent(t *testing.T) {
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, newFakeIPv4PacketConn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = conn.Close(); err != nil {
+			t.Errorf("close #%d: %v", i+1, err)
+		}
+	}
+	// 未完成构造或为 nil 的连接同样可以安全关闭
+	for _, c := range []VRRPMsgConnection{&IPv4VRRPMsgCon{}, &IPv6VRRPMsgCon{}, (*IPv4VRRPMsgCon)(nil), (*IPv6VRRPMsgCon)(nil)} {
+		for i := 0; i < 2; i++ {
+			if err = c.Close(); err != nil {
+				t.Errorf("%T close #%d: %v", c, i+1, err)
+			}
+		}
+	}
+}
+
+func TestVirtualRouter_GetMulticastTTL(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	if _, err := vr.GetMulticastTTL(); !errors.Is(err, ErrMulticastTTLUnsupported) {
+		t.Fatalf("GetMulticastTTL on unsupported conn = %v, want ErrMulticastTTLUnsupported", err)
+	}
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr.vrrpConn = conn
+
+	if ttl, err := vr.GetMulticastTTL(); err != nil || ttl != 255 {
+		t.Fatalf("default GetMulticastTTL = %d, %v, want 255", ttl, err)
+	}
+	if err = vr.SetMulticastTTL(64); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := vr.GetMulticastTTL(); err != nil || ttl != 64 {
+		t.Fatalf("GetMulticastTTL after override = %d, %v, want 64", ttl, err)
+	}
+	if vr.connOpts.ttl != 64 {
+		t.Errorf("connOpts.ttl = %d, want 64", vr.connOpts.ttl)
+	}
+	for _, ttl := range []int{0, 256} {
+		if err = vr.SetMulticastTTL(ttl); err == nil {
+			t.Errorf("SetMulticastTTL(%d) should fail", ttl)
+		}
+	}
+	if ttl, _ := vr.GetMulticastTTL(); ttl != 64 {
+		t.Errorf("invalid TTL changed the socket to %d", ttl)
+	}
+}
+
+func TestIPv4VRRPMsgCon_MissingTTLPolicy(t *testing.T) {
+	vr, _ := newTestRouter(t, nil, 240, "192.168.0.10", 100)
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr.vrrpConn = conn
+
+	src := net.IPv4(192, 168, 0, 20).To4()
+	peer, _ := newTestRouter(t, nil, 240, "192.168.0.20", 100)
+	raw := peer.CurrentAdvertisement().ToBytes()
+	read := func(ttl int, withCM bool) error {
+		r := fakeIPv4Read{b: raw, src: src}
+		if withCM {
+			r.cm = &ipv4.ControlMessage{TTL: ttl, Src: src, Dst: VRRPMultiAddrIPv4, IfIndex: 1}
+		}
+		pc.reads <- r
+		_, err := conn.ReadMessage()
+		return err
+	}
+
+	// 默认丢弃缺少TTL数据的数据包，且与TTL不为255的数据包区分
+	if err = read(0, true); !errors.Is(err, ErrTTLUnavailable) {
+		t.Errorf("TTL 0: got %v, want ErrTTLUnavailable", err)
+	}
+	if err = read(0, false); !errors.Is(err, ErrTTLUnavailable) {
+		t.Errorf("no control message: got %v, want ErrTTLUnavailable", err)
+	}
+	if err = read(64, true); err == nil || errors.Is(err, ErrTTLUnavailable) {
+		t.Errorf("TTL 64: got %v, want a TTL error other than ErrTTLUnavailable", err)
+	}
+
+	vr.SetMissingTTLPolicy(MissingTTLAccept)
+	if vr.connOpts.missingTTL != MissingTTLAccept {
+		t.Error("policy should be kept for reopened connections")
+	}
+	if err = read(0, true); err != nil {
+		t.Errorf("TTL 0 with MissingTTLAccept: %v", err)
+	}
+	if err = read(0, false); err != nil {
+		t.Errorf("no control message with MissingTTLAccept: %v", err)
+	}
+	if err = read(64, true); err == nil {
+		t.Error("TTL 64 should still be rejected with MissingTTLAccept")
+	}
+	if err = read(255, true); err != nil {
+		t.Errorf("TTL 255: %v", err)
+	}
+}
+
+func TestVirtualRouter_DropReasons(t *testing.T) {
+	pc := newFakeIPv4PacketConn()
+	conn, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr, err := NewVirtualRouterWithConn(240, conn, nil, net.IPv4(192, 168, 0, 10), 100, IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr.state = BACKUP
+
+	src := net.IPv4(192, 168, 0, 20).To4()
+	cm := &ipv4.ControlMessage{TTL: 255, Src: src, Dst: VRRPMultiAddrIPv4, IfIndex: 1}
+	advertisement := func(VRID byte) []byte {
+		packet := newAdvertisement(VRID, 100, "192.168.0.20")
+		packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: VRRPMultiAddrIPv4, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+		return packet.ToBytes()
+	}
+	badChecksum := advertisement(240)
+	badChecksum[7] ^= 0xff
+	badVersion := advertisement(240)
+	badVersion[0] = byte(VRRPv2)<<4 | VRRPTypeAdvertisement
+	truncated := advertisement(240)
+	truncated[3] = 8
+
+	for _, b := range [][]byte{badChecksum, badChecksum, badVersion, truncated, {0x31, 0xf0}, advertisement(241), advertisement(240)} {
+		pc.reads <- fakeIPv4Read{b: b, cm: cm}
+	}
+	pc.reads <- fakeIPv4Read{b: advertisement(240), cm: &ipv4.ControlMessage{TTL: 64, Src: src, Dst: VRRPMultiAddrIPv4}}
+	_ = pc.Close()
+	vr.fetchVRRPDaemon(vr.vrrpConn)
+
+	want := map[string]uint64{
+		TapDroppedChecksum:  2,
+		TapDroppedVersion:   1,
+		TapDroppedTruncated: 1,
+		TapDroppedMalformed: 1,
+		TapDroppedVRID:      1,
+		TapDroppedTTL:       1,
+	}
+	got := vr.DropReasons()
+	if len(got) != len(want) {
+		t.Errorf("expect %d drop reasons, got %v", len(want), got)
+	}
+	for reason, n := range want {
+		if got[reason] != n {
+			t.Errorf("expect %d %s, got %d", n, reason, got[reason])
+		}
+	}
+	// 返回的是快照，修改不影响内部计数
+	got[TapDroppedTTL] = 100
+	if n := vr.DropReasons()[TapDroppedTTL]; n != 1 {
+		t.Errorf("expect snapshot to be independent, got %d", n)
+	}
+	if len(vr.packetQueue) != 1 {
+		t.Errorf("expect only the accepted packet queued, got %d", len(vr.packetQueue))
+	}
+}
+
+func TestNewIPv4VRRPMsgConnWildcard(t *testing.T) {
+	pc := newFakeIPv4PacketConn()
+	pc.joinErrs = []error{syscall.EPERM}
+	conn, err := newIPv4VRRPMsgConnWildcard(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, pc)
+	if err != nil {
+		t.Fatalf("join failure should be tolerated in wildcard mode, got %v", err)
+	}
+	var results []string
+	conn.SetRawTap(func(_ *VRRPPacket, _ net.IP, result string) { results = append(results, result) })
+
+	src := net.IPv4(192, 168, 0, 20).To4()
+	advertisement := func(dst net.IP) fakeIPv4Read {
+		packet := newAdvertisement(240, 100, "192.168.0.20")
+		packet.SetCheckSum(&PseudoHeader{Saddr: src, Daddr: dst, Protocol: VRRPIPProtocolNumber, Len: uint16(packet.PacketSize())})
+		return fakeIPv4Read{b: packet.ToBytes(), cm: &ipv4.ControlMessage{TTL: 255, Src: src, Dst: dst, IfIndex: 1}}
+	}
+	pc.reads <- advertisement(VRRPMultiAddrIPv4)
+	pc.reads <- advertisement(net.IPv4(224, 0, 0, 19).To4())
+	pc.reads <- advertisement(net.IPv4(192, 168, 0, 10).To4())
+	pc.reads <- advertisement(VRRPMultiAddrIPv4)
+
+	var accepted int
+	for i := 0; i < 4; i++ {
+		pkt, err := conn.ReadMessage()
+		if err == nil {
+			accepted++
+			if !pkt.Pshdr.Daddr.Equal(VRRPMultiAddrIPv4) {
+				t.Errorf("unexpected destination %v", pkt.Pshdr.Daddr)
+			}
+		} else if !errors.Is(err, ErrForeignGroup) {
+			t.Errorf("expect ErrForeignGroup, got %v", err)
+		}
+	}
+	if accepted != 2 {
+		t.Errorf("expect 2 advertisements to the group, got %d", accepted)
+	}
+	if len(results) != 2 || results[0] != TapDroppedGroup || results[1] != TapDroppedGroup {
+		t.Errorf("expect 2 %s results, got %v", TapDroppedGroup, results)
+	}
+
+	// 非兼容模式不按目的地址过滤
+	plain, err := newIPv4VRRPMsgConn(&net.Interface{Index: 1, Name: "eth0"}, net.IPv4(192, 168, 0, 10), VRRPMultiAddrIPv4, newFakeIPv4PacketConn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.pc.(*fakeIPv4PacketConn).reads <- advertisement(net.IPv4(224, 0, 0, 19).To4())
+	if _, err = plain.ReadMessage(); errors.Is(err, ErrForeignGroup) {
+		t.Error("destination filter should only apply in wildcard mode")
+	}
+}
+
+// sliceConn 依次返回预置报文的自定义连接，报文取尽后返回网络错误
+type sliceConn struct {
+	packets []*VRRPPacket
+}
+
+func (c *sliceConn) WriteMessage(*VRRPPacket) error { return nil }
+func (c *sliceConn) Close() error                   { return nil }
+
+func (c *sliceConn) ReadMessage() (*VRRPPacket, error) {
+	if len(c.packets) == 0 {
+		return nil, NetErr{errors.New("sliceConn: no more packets")}
+	}
+	packet := c.packets[0]
+	c.packets = c.packets[1:]
+	return packet, nil
+}
+
+func TestVirtualRouter_DropNoPseudoHeader(t *testing.T) {
+	// FromBytes 解析的报文未设置伪首部
+	raw := newAdvertisement(240, 100, "192.168.0.20").ToBytes()
+	packet, err := FromBytes(IPv4, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &sliceConn{packets: []*VRRPPacket{packet, newAdvertisement(240, 100, "192.168.0.20")}}
+	vr, err := NewVirtualRouterWithConn(240, conn, nil, net.IPv4(192, 168, 0, 10), 100, IPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr.state = BACKUP
+
+	// 接收协程不应 panic，未设置伪首部的报文被丢弃并计数
+	vr.fetchVRRPDaemon(vr.vrrpConn)
+	if n := vr.DropReasons()[TapDroppedNoPseudoHeader]; n != 1 {
+		t.Errorf("expect 1 packet dropped without pseudo header, got %d", n)
+	}
+	if n := vr.GetStatistics().Received; n != 1 {
+		t.Errorf("expect 1 advertisement received, got %d", n)
+	}
+	select {
+	case err := <-vr.Errors():
+		if !errors.Is(err, ErrNoPseudoHeader) {
+			t.Errorf("expect ErrNoPseudoHeader, got %v", err)
+		}
+	default:
+		t.Error("missing pseudo header should be reported")
+	}
+}
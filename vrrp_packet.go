@@ -6,7 +6,6 @@ import (
 	"io"
 	"net"
 	"net/netip"
-	"unsafe"
 )
 
 // RFC 5798 5.1. VRRP Packet Format
@@ -35,10 +34,20 @@ import (
 //    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
 //
 
+var (
+	// ErrUnexpectedType 报文类型不是 ADVERTISEMENT
+	ErrUnexpectedType = errors.New("unexpected VRRP packet type")
+	// ErrReservedBits 报文保留位(rsvd)不为0
+	ErrReservedBits = errors.New("nonzero reserved bits in VRRP packet")
+	// ErrTruncatedAdvert 报文长度小于首部声明的地址数量所需的长度，通常由分片丢失或 MTU 问题导致
+	ErrTruncatedAdvert = errors.New("truncated VRRP advertisement")
+)
+
 // VRRPPacket VRRP数据包
 type VRRPPacket struct {
 	Header    [8]byte       // 头部
 	IPAddress [][4]byte     // 报文中IP地址序列
+	AuthData  []byte        // VRRPv2 认证数据（RFC 3768 5.3.10），VRRPv3 中不存在
 	Pshdr     *PseudoHeader // 伪头部，用于记录IP层信息
 }
 
@@ -72,13 +81,56 @@ func (psh *PseudoHeader) ToBytes() []byte {
 }
 
 // FromBytes 解析VRRP数据包
+// 仅解析首部声明的地址数量（以及 VRRPv2 认证数据），忽略其后的填充字节。
 func FromBytes(IPvXVersion byte, octets []byte) (*VRRPPacket, error) {
+	var packet VRRPPacket
+	if err := packet.parse(IPvXVersion, octets); err != nil {
+		return nil, err
+	}
+	return &packet, nil
+}
+
+// FromBytesWithPseudoHeader 解析VRRP数据包，并与 ReadMessage 一样附加由 src、dst 构造的伪头部，
+// 便于离线分析与测试中直接使用 String、ValidateCheckSum 以及选举逻辑。
+// 该函数不校验校验码，可通过 packet.ValidateCheckSum(packet.Pshdr) 检查。
+// src: IP数据包的源地址
+// dst: IP数据包的目的地址
+func FromBytesWithPseudoHeader(IPvXVersion byte, octets []byte, src, dst net.IP) (*VRRPPacket, error) {
+	packet, err := FromBytes(IPvXVersion, octets)
+	if err != nil {
+		return nil, err
+	}
+	var saddr, daddr net.IP
+	if IPvXVersion == IPv4 {
+		saddr, daddr = src.To4(), dst.To4()
+	} else {
+		saddr, daddr = src.To16(), dst.To16()
+	}
+	if saddr == nil || daddr == nil || (IPvXVersion == IPv6 && (src.To4() != nil || dst.To4() != nil)) {
+		return nil, fmt.Errorf("source %v or destination %v does not match IPv%d", src, dst, IPvXVersion)
+	}
+	packet.Pshdr = &PseudoHeader{
+		Saddr:    saddr,
+		Daddr:    daddr,
+		Protocol: VRRPIPProtocolNumber,
+		Len:      uint16(packet.PacketSize()),
+	}
+	return packet, nil
+}
+
+// parse 将字节序列解析至当前报文
+func (packet *VRRPPacket) parse(IPvXVersion byte, octets []byte) error {
 	if len(octets) < 8 {
-		return nil, errors.New("faulty VRRP packet size")
+		return errors.New("faulty VRRP packet size")
 	}
-	var packet VRRPPacket
-	for index := 0; index < 8; index++ {
-		packet.Header[index] = octets[index]
+	copy(packet.Header[:], octets[:8])
+	// RFC 5798 5.2.2. Type 只定义了 ADVERTISEMENT 类型，其他类型的报文必须丢弃
+	if packet.GetType() != VRRPTypeAdvertisement {
+		return fmt.Errorf("%w %d", ErrUnexpectedType, packet.GetType())
+	}
+	// RFC 5798 5.2.6. Rsvd 发送时必须为0，VRRPv2 中该位置为认证类型不做检查
+	if VRRPVersion(packet.GetVersion()) == VRRPv3 && packet.Header[4]&0xF0 != 0 {
+		return fmt.Errorf("%w 0x%X", ErrReservedBits, packet.Header[4]>>4)
 	}
 
 	var countofaddrs = int(packet.GetIPvXAddrCount())
@@ -87,21 +139,46 @@ func FromBytes(IPvXVersion byte, octets []byte) (*VRRPPacket, error) {
 	case 6:
 		countofaddrs = countofaddrs * 4
 	default:
-		return nil, fmt.Errorf("faulty IPvX version %d", IPvXVersion)
+		return fmt.Errorf("faulty IPvX version %d", IPvXVersion)
 	}
-	// to compatible with VRRP v2 packet, ignore the auth info
 	if 8+countofaddrs*4 > len(octets) {
-		return nil, fmt.Errorf("The value of filed IPvXAddrCount doesn't match the length of octets")
+		return fmt.Errorf("%w: %d addresses declared, %d bytes received", ErrTruncatedAdvert, packet.GetIPvXAddrCount(), len(octets))
 	}
-	for index := 0; index < countofaddrs; index++ {
-		var addr [4]byte
-		addr[0] = octets[8+4*index]
-		addr[1] = octets[8+4*index+1]
-		addr[2] = octets[8+4*index+2]
-		addr[3] = octets[8+4*index+3]
-		packet.IPAddress = append(packet.IPAddress, addr)
+	packet.IPAddress = make([][4]byte, countofaddrs)
+	for index := range packet.IPAddress {
+		copy(packet.IPAddress[index][:], octets[8+4*index:])
 	}
-	return &packet, nil
+	packet.AuthData = nil
+	if VRRPVersion(packet.GetVersion()) == VRRPv2 {
+		return packet.parseAuthData(octets[8+countofaddrs*4:])
+	}
+	return nil
+}
+
+// parseAuthData 解析 VRRPv2 地址序列之后的认证数据
+//
+// RFC 3768 中认证数据固定为8字节，简单文本认证时必须存在；
+// 无认证时部分实现不携带认证数据，此时认证数据为空。
+func (packet *VRRPPacket) parseAuthData(trailer []byte) error {
+	if len(trailer) < VRRPv2AuthDataLen {
+		if packet.GetAuthType() == VRRPv2AuthSimpleText {
+			return fmt.Errorf("VRRPv2 simple text authentication data too short, %d bytes", len(trailer))
+		}
+		return nil
+	}
+	packet.AuthData = make([]byte, VRRPv2AuthDataLen)
+	copy(packet.AuthData, trailer)
+	return nil
+}
+
+// GetAuthType 获取 VRRPv2 认证类型，仅对 VRRPv2 报文有效
+func (packet *VRRPPacket) GetAuthType() byte {
+	return packet.Header[4]
+}
+
+// GetAuthData 获取 VRRPv2 认证数据，仅对 VRRPv2 报文有效
+func (packet *VRRPPacket) GetAuthData() []byte {
+	return packet.AuthData
 }
 
 // GetIPvXAddr 获取报文中的IP
@@ -130,6 +207,32 @@ func (packet *VRRPPacket) GetIPvXAddr(version byte) (addrs []net.IP) {
 	}
 }
 
+// GetIPAddrs 获取 报文中的IP地址序列
+// 根据IP地址数量与地址序列长度判断协议类型，地址序列长度与数量不匹配时返回 nil。
+func (packet *VRRPPacket) GetIPAddrs() []netip.Addr {
+	count := int(packet.GetIPvXAddrCount())
+	switch len(packet.IPAddress) {
+	case count:
+		addrs := make([]netip.Addr, 0, count)
+		for index := range packet.IPAddress {
+			addrs = append(addrs, netip.AddrFrom4(packet.IPAddress[index]))
+		}
+		return addrs
+	case count * 4:
+		addrs := make([]netip.Addr, 0, count)
+		for index := 0; index < count; index++ {
+			var a16 [16]byte
+			for i := 0; i < 4; i++ {
+				copy(a16[4*i:], packet.IPAddress[index*4+i][:])
+			}
+			addrs = append(addrs, netip.AddrFrom16(a16))
+		}
+		return addrs
+	default:
+		return nil
+	}
+}
+
 // AddIPvXAddr 向报文中追加IP
 func (packet *VRRPPacket) AddIPvXAddr(version byte, ip net.IP) {
 	switch version {
@@ -179,7 +282,7 @@ func (packet *VRRPPacket) GetType() byte {
 
 // SetType 设置 VRRP数据包的类型，固定值 1 ADVERTISEMENT
 func (packet *VRRPPacket) SetType() {
-	packet.Header[0] = (packet.Header[0] & 0xF0) | 1
+	packet.Header[0] = (packet.Header[0] & 0xF0) | VRRPTypeAdvertisement
 }
 
 // GetVirtualRouterID 获取 虚拟路由ID
@@ -219,12 +322,27 @@ func (packet *VRRPPacket) setIPvXAddrCount(count byte) {
 
 // GetAdvertisementInterval 获取 最大播发间隔
 // 12-bit的字段，用于表示2条VRRP消息发送的间隔时间，单位为 厘秒， 100 厘秒 = 1 秒。
+// VRRPv2 报文中为 8-bit 的字段，单位为秒（RFC 3768 5.3.7），返回值同样换算为厘秒。
 func (packet *VRRPPacket) GetAdvertisementInterval() uint16 {
+	if VRRPVersion(packet.GetVersion()) == VRRPv2 {
+		return uint16(packet.Header[5]) * 100
+	}
 	return uint16(packet.Header[4]&0x0F)<<8 | uint16(packet.Header[5])
 }
 
 // SetAdvertisementInterval 设置 最大播发间隔，单位厘秒， 100 厘秒 = 1 秒。
+// VRRPv2 报文按秒向上取整（1~255 秒），请先调用 SetVersion 设置版本号。
 func (packet *VRRPPacket) SetAdvertisementInterval(interval uint16) {
+	if VRRPVersion(packet.GetVersion()) == VRRPv2 {
+		seconds := (uint32(interval) + 99) / 100
+		if seconds < 1 {
+			seconds = 1
+		} else if seconds > 255 {
+			seconds = 255
+		}
+		packet.Header[5] = byte(seconds)
+		return
+	}
 	packet.Header[4] = (packet.Header[4] & 0xF0) | byte((interval>>8)&0x0F)
 	packet.Header[5] = byte(interval)
 }
@@ -240,71 +358,67 @@ func (packet *VRRPPacket) GetCheckSum() uint16 {
 //
 // pshdr: 伪头部
 func (packet *VRRPPacket) SetCheckSum(pshdr *PseudoHeader) {
-	var PointerAdd = func(ptr unsafe.Pointer, bytes int) unsafe.Pointer {
-		return unsafe.Pointer(uintptr(ptr) + uintptr(bytes))
-	}
-	var octets = pshdr.ToBytes()
-	octets = append(octets, packet.ToBytes()...)
-	var x = len(octets)
-	var ptr = unsafe.Pointer(&octets[0])
-	var sum uint32
-	for x > 1 {
-		sum = sum + uint32(*(*uint16)(ptr))
-		ptr = PointerAdd(ptr, 2)
-		x = x - 2
-	}
-	if x > 0 {
-		sum = sum + uint32(*((*uint8)(ptr)))
-	}
-	for (sum >> 16) > 0 {
-		sum = sum&65535 + sum>>16
-	}
-	sum = ^sum
-	packet.Header[7] = byte(sum >> 8)
-	packet.Header[6] = byte(sum)
+	// 计算校验和时校验和字段应为0
+	packet.Header[6] = 0
+	packet.Header[7] = 0
+	sum := ^packet.checksum(pshdr)
+	packet.Header[6] = byte(sum >> 8)
+	packet.Header[7] = byte(sum)
 }
 
 // ValidateCheckSum 验证 校验和
 func (packet *VRRPPacket) ValidateCheckSum(pshdr *PseudoHeader) bool {
-	var PointerAdd = func(ptr unsafe.Pointer, bytes int) unsafe.Pointer {
-		return unsafe.Pointer(uintptr(ptr) + uintptr(bytes))
-	}
-	var octets = pshdr.ToBytes()
-	octets = append(octets, packet.ToBytes()...)
-	var x = len(octets)
-	var ptr = unsafe.Pointer(&octets[0])
+	return packet.checksum(pshdr) == 65535
+}
+
+// checksum 计算 伪头部 与 报文内容 的16位反码和（未取反）
+// 直接在各字段上累加，避免拼接伪头部与报文带来的内存分配。
+// VRRPv2 的校验和仅覆盖VRRP报文，不包含伪头部（RFC 3768 5.3.8）。
+func (packet *VRRPPacket) checksum(pshdr *PseudoHeader) uint16 {
 	var sum uint32
-	for x > 1 {
-		sum = sum + uint32(*(*uint16)(ptr))
-		ptr = PointerAdd(ptr, 2)
-		x = x - 2
+	if VRRPVersion(packet.GetVersion()) != VRRPv2 {
+		sum = sumWords(sum, pshdr.Saddr)
+		sum = sumWords(sum, pshdr.Daddr)
+		sum += uint32(pshdr.Zero)<<8 | uint32(pshdr.Protocol)
+		sum += uint32(pshdr.Len)
 	}
-	if x > 0 {
-		sum = sum + uint32(*((*uint8)(ptr)))
+	sum = sumWords(sum, packet.Header[:])
+	for index := range packet.IPAddress {
+		sum = sumWords(sum, packet.IPAddress[index][:])
 	}
+	sum = sumWords(sum, packet.AuthData)
 	for (sum >> 16) > 0 {
 		sum = sum&65535 + sum>>16
 	}
-	if uint16(sum) == 65535 {
-		return true
-	} else {
-		return false
+	return uint16(sum)
+}
+
+// sumWords 以网络字节序将 octets 按16位累加至 sum，奇数长度时末尾补0
+func sumWords(sum uint32, octets []byte) uint32 {
+	var x = len(octets)
+	for i := 0; i+1 < x; i += 2 {
+		sum += uint32(octets[i])<<8 | uint32(octets[i+1])
+	}
+	if x%2 == 1 {
+		sum += uint32(octets[x-1]) << 8
 	}
+	return sum
 }
 
 // ToBytes 序列化消息为字节序列
 func (packet *VRRPPacket) ToBytes() []byte {
-	var payload = make([]byte, 8+len(packet.IPAddress)*4)
+	var payload = make([]byte, packet.PacketSize())
 	copy(payload, packet.Header[:])
 	for index := range packet.IPAddress {
 		copy(payload[8+index*4:], packet.IPAddress[index][:])
 	}
+	copy(payload[8+len(packet.IPAddress)*4:], packet.AuthData)
 	return payload
 }
 
 // PacketSize 当前报文的长度
 func (packet *VRRPPacket) PacketSize() int {
-	return 8 + len(packet.IPAddress)*4
+	return 8 + len(packet.IPAddress)*4 + len(packet.AuthData)
 }
 
 // VRRPMsgConnection IP层VRRP协议消息接口
@@ -312,6 +426,6 @@ type VRRPMsgConnection interface {
 	io.Closer
 	// WriteMessage 发送VRRP消息
 	WriteMessage(*VRRPPacket) error
-	// ReadMessage 接收VRRP消息
+	// ReadMessage 接收VRRP消息，返回的报文须设置伪首部 Pshdr，发送方地址（Saddr）用于选举
 	ReadMessage() (*VRRPPacket, error)
 }
@@ -1,7 +1,9 @@
 package govrrp
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
@@ -54,6 +56,73 @@ func TestVRRPPacket_ValidateCheckSum(t *testing.T) {
 	}
 }
 
+func TestFromBytesWithPseudoHeader(t *testing.T) {
+	raw, _ := hex.DecodeString("31f0640100640608c0a800e6")
+	p, err := FromBytesWithPseudoHeader(IPv4, raw, net.ParseIP("192.168.0.220"), VRRPMultiAddrIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Pshdr == nil || !p.Pshdr.Saddr.Equal(net.ParseIP("192.168.0.220")) || len(p.Pshdr.Saddr) != net.IPv4len ||
+		p.Pshdr.Protocol != VRRPIPProtocolNumber || int(p.Pshdr.Len) != len(raw) {
+		t.Fatalf("unexpected pseudo header %+v", p.Pshdr)
+	}
+	if !p.ValidateCheckSum(p.Pshdr) {
+		t.Error("checksum error")
+	}
+	if s := p.String(); s == "" {
+		t.Error("expect printable packet")
+	}
+	if _, err = FromBytesWithPseudoHeader(IPv6, raw, net.ParseIP("192.168.0.220"), VRRPMultiAddrIPv4); err == nil {
+		t.Error("expect error for IPv4 addresses with IPv6 packet")
+	}
+}
+
+func TestVRRPPacket_IPv6RoundTrip(t *testing.T) {
+	var packet VRRPPacket
+	packet.SetPriority(150)
+	packet.SetVersion(VRRPv3)
+	packet.SetVirtualRouterID(10)
+	packet.SetAdvertisementInterval(100)
+	packet.SetType()
+	vips := []net.IP{net.ParseIP("2001:db8::100"), net.ParseIP("fe80::100")}
+	for _, vip := range vips {
+		packet.AddIPvXAddr(IPv6, vip)
+	}
+	pshdr := PseudoHeader{
+		Saddr:    net.ParseIP("fe80::1"),
+		Daddr:    VRRPMultiAddrIPv6,
+		Protocol: VRRPIPProtocolNumber,
+		Len:      uint16(packet.PacketSize()),
+	}
+	packet.SetCheckSum(&pshdr)
+
+	raw := packet.ToBytes()
+	if len(raw) != 8+16*len(vips) {
+		t.Fatalf("IPv6 advertisement size %d, want %d", len(raw), 8+16*len(vips))
+	}
+	parsed, err := FromBytes(IPv6, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := parsed.GetIPvXAddr(IPv6)
+	if len(addrs) != len(vips) {
+		t.Fatalf("parsed %d addresses, want %d", len(addrs), len(vips))
+	}
+	for i, vip := range vips {
+		if !addrs[i].Equal(vip) {
+			t.Errorf("address %d = %v, want %v", i, addrs[i], vip)
+		}
+	}
+	if !parsed.ValidateCheckSum(&pshdr) {
+		t.Error("checksum of the parsed packet should match the IPv6 pseudo header")
+	}
+	other := pshdr
+	other.Saddr = net.ParseIP("fe80::2")
+	if parsed.ValidateCheckSum(&other) {
+		t.Error("checksum should depend on the IPv6 source address")
+	}
+}
+
 func TestVRRPPacket_SetCheckSum(t *testing.T) {
 	var packet VRRPPacket
 	packet.SetPriority(100)
@@ -84,3 +153,72 @@ func TestVRRPPacket_SetCheckSum(t *testing.T) {
 	}
 
 }
+
+func TestVRRPPacket_FromBytes_UnexpectedType(t *testing.T) {
+	// type 2
+	raw, _ := hex.DecodeString("32f0640100640608c0a800e6")
+	_, err := FromBytes(IPv4, raw)
+	if !errors.Is(err, ErrUnexpectedType) {
+		t.Errorf("expect ErrUnexpectedType, got %v", err)
+	}
+}
+
+func TestVRRPPacket_FromBytes_ReservedBits(t *testing.T) {
+	// rsvd = 0x1
+	raw, _ := hex.DecodeString("31f0640110640608c0a800e6")
+	_, err := FromBytes(IPv4, raw)
+	if !errors.Is(err, ErrReservedBits) {
+		t.Errorf("expect ErrReservedBits, got %v", err)
+	}
+}
+
+func TestVRRPPacket_FromBytes_V2SimpleTextAuth(t *testing.T) {
+	// VRRPv2 VRID 10 Priority 100 Count 2 AuthType 1 AdverInt 1
+	raw, _ := hex.DecodeString("210a640201010000" + "c0a800c8" + "c0a800c9" + hex.EncodeToString([]byte("secret\x00\x00")))
+	p, err := FromBytes(IPv4, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetAuthType() != VRRPv2AuthSimpleText {
+		t.Errorf("unexpected auth type %d", p.GetAuthType())
+	}
+	if string(p.GetAuthData()) != "secret\x00\x00" {
+		t.Errorf("unexpected auth data %q", p.GetAuthData())
+	}
+	vips := p.GetIPvXAddr(IPv4)
+	if len(vips) != 2 || !vips[0].Equal(net.IPv4(192, 168, 0, 200)) || !vips[1].Equal(net.IPv4(192, 168, 0, 201)) {
+		t.Errorf("unexpected VIPs %v", vips)
+	}
+	if !bytes.Equal(p.ToBytes(), raw) {
+		t.Errorf("serialized packet should contain the auth data, got %X", p.ToBytes())
+	}
+
+	// 简单文本认证缺少认证数据
+	if _, err = FromBytes(IPv4, raw[:len(raw)-4]); err == nil {
+		t.Error("expect error for truncated auth data")
+	}
+}
+
+func TestVRRPPacket_GetIPAddrs(t *testing.T) {
+	for _, input := range [][]string{
+		{"192.168.0.100", "10.0.0.1"},
+		{"fe80::1", "2001:db8::100"},
+	} {
+		packet := new(VRRPPacket)
+		var want []netip.Addr
+		for _, s := range input {
+			addr := netip.MustParseAddr(s)
+			packet.AddIPAddr(addr)
+			want = append(want, addr)
+		}
+		got := packet.GetIPAddrs()
+		if len(got) != len(want) {
+			t.Fatalf("expect %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("expect %v, got %v", want[i], got[i])
+			}
+		}
+	}
+}